@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/tokens"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+)
+
+type AuthHandler struct {
+	logger       *log.Logger
+	userStore    store.UserStore
+	refreshStore store.RefreshTokenStore
+	tokens       *tokens.Manager
+}
+
+func NewAuthHandler(logger *log.Logger, userStore store.UserStore, refreshStore store.RefreshTokenStore, tokenManager *tokens.Manager) *AuthHandler {
+	return &AuthHandler{
+		logger:       logger,
+		userStore:    userStore,
+		refreshStore: refreshStore,
+		tokens:       tokenManager,
+	}
+}
+
+// missingUser is checked in place of an account when no user has the login
+// email, so an unknown email takes as long to reject as a wrong password and
+// response times do not reveal who is registered. Its hash is of a random
+// password at the cost store.User.SetPassword uses.
+var missingUser = &store.User{PasswordHash: "$2a$12$qD85Dfvw.00Q2cpgau0t0.pgle65uh6JcPKy3ZZnmDLv1aWiZgEiG"}
+
+type loginRequest struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding login request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	v := validate.New()
+	v.Required("email", req.Email)
+	v.Check(req.Password != "", "password", "password is required")
+	if err := v.Err(); err != nil {
+		writeError(w, r, h.logger, err, "validate login")
+		return
+	}
+
+	user, err := h.userStore.GetByEmail(r.Context(), req.Email)
+	if errors.Is(err, store.ErrNotFound) {
+		missingUser.PasswordMatches(req.Password)
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "invalid email or password")
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: GetByEmail: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	matches, err := user.PasswordMatches(req.Password)
+	if err != nil {
+		h.logger.Printf("ERROR: PasswordMatches: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	if !matches {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "invalid email or password")
+		return
+	}
+
+	if !user.IsActive {
+		utils.WriteError(w, r, http.StatusForbidden, utils.CodeForbidden, "account is disabled")
+		return
+	}
+
+	refresh, err := h.tokens.GenerateRefresh()
+	if err != nil {
+		h.logger.Printf("ERROR: generating refresh token: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	err = h.refreshStore.Create(&store.RefreshToken{
+		UserID:     user.ID,
+		Hash:       refresh.Hash,
+		DeviceName: req.DeviceName,
+		ExpiresAt:  refresh.ExpiresAt,
+	})
+	if err != nil {
+		h.logger.Printf("ERROR: storing refresh token: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	accessToken, err := h.tokens.Generate(user.ID, user.Role)
+	if err != nil {
+		h.logger.Printf("ERROR: generating access token: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"token":   accessToken,
+		"refresh": refresh,
+		"user":    user,
+	})
+}
+
+func (h *AuthHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.RefreshToken == "" {
+		writeInvalid(w, r, "refresh_token", "refresh_token is required")
+		return
+	}
+
+	refresh, err := h.tokens.GenerateRefresh()
+	if err != nil {
+		h.logger.Printf("ERROR: generating refresh token: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	next := &store.RefreshToken{
+		Hash:      refresh.Hash,
+		ExpiresAt: refresh.ExpiresAt,
+	}
+	err = h.refreshStore.Rotate(tokens.HashRefresh(req.RefreshToken), next)
+	switch {
+	case errors.Is(err, store.ErrRefreshTokenReused):
+		h.logger.Printf("WARN: refresh token reuse detected, session revoked")
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, err.Error())
+		return
+	case errors.Is(err, store.ErrRefreshTokenNotFound),
+		errors.Is(err, store.ErrRefreshTokenRevoked),
+		errors.Is(err, store.ErrRefreshTokenExpired):
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, err.Error())
+		return
+	case err != nil:
+		h.logger.Printf("ERROR: rotating refresh token: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	user, err := h.userStore.GetById(r.Context(), next.UserID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		h.logger.Printf("ERROR: GetById: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	if user == nil || !user.IsActive {
+		if err := h.refreshStore.RevokeFamilyByHash(next.Hash); err != nil {
+			h.logger.Printf("ERROR: revoking session of inactive user: %v", err)
+		}
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "account is disabled")
+		return
+	}
+
+	accessToken, err := h.tokens.Generate(user.ID, user.Role)
+	if err != nil {
+		h.logger.Printf("ERROR: generating access token: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"token":   accessToken,
+		"refresh": refresh,
+	})
+}
+
+func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.RefreshToken == "" {
+		writeInvalid(w, r, "refresh_token", "refresh_token is required")
+		return
+	}
+
+	err = h.refreshStore.RevokeFamilyByHash(tokens.HashRefresh(req.RefreshToken))
+	if err != nil {
+		h.logger.Printf("ERROR: RevokeFamilyByHash: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "logged out"})
+}
+
+func (h *AuthHandler) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	sessions, err := h.refreshStore.ListSessions(user.ID)
+	if err != nil {
+		h.logger.Printf("ERROR: ListSessions: %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"sessions": sessions})
+}
+
+// HandleRevokeSession signs a device out, e.g. a lost or stolen tablet,
+// without touching the user's password or other sessions.
+func (h *AuthHandler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	user := middleware.GetUser(r)
+
+	err = h.refreshStore.RevokeSession(user.ID, id)
+	if err != nil {
+		writeError(w, r, h.logger, err, "revoke session")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "session revoked"})
+}
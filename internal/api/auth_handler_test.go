@@ -0,0 +1,24 @@
+package api
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestMissingUserHash checks the stand-in hash costs as much to compare as a
+// real user's, which is what keeps unknown emails from answering faster.
+func TestMissingUserHash(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(missingUser.PasswordHash))
+	if err != nil {
+		t.Fatalf("Cost: %v", err)
+	}
+	if cost != 12 {
+		t.Errorf("cost = %d, want 12 as in store.User.SetPassword", cost)
+	}
+
+	matches, err := missingUser.PasswordMatches("password123")
+	if err != nil || matches {
+		t.Errorf("PasswordMatches = %v, %v, want false, nil", matches, err)
+	}
+}
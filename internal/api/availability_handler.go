@@ -0,0 +1,119 @@
+package api
+
+import (
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultSlotInterval   = 15
+	maxAvailabilityWindow = 7 * 24 * time.Hour
+)
+
+type AvailabilityHandler struct {
+	logger          *log.Logger
+	restaurantStore store.RestaurantStore
+	bookingStore    store.BookingStore
+}
+
+func NewAvailabilityHandler(logger *log.Logger, restaurantStore store.RestaurantStore, bookingStore store.BookingStore) *AvailabilityHandler {
+	return &AvailabilityHandler{
+		logger:          logger,
+		restaurantStore: restaurantStore,
+		bookingStore:    bookingStore,
+	}
+}
+
+// HandleGetAvailability serves the public booking page. It lists the slot
+// start times within [from, to) at which a party of party_size can be seated,
+// together with the candidate tables for each slot. Only the start has to
+// fall in the window; the slot runs for duration_minutes and may end after
+// to, as long as the restaurant is still open.
+func (h *AvailabilityHandler) HandleGetAvailability(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	queries := r.URL.Query()
+
+	params := store.AvailabilityParams{
+		RestaurantID:    restaurantID,
+		PartySize:       parseIntOrDefault(queries.Get("party_size"), 0),
+		DurationMinutes: parseIntOrDefault(queries.Get("duration_minutes"), defaultBookingDuration),
+		IntervalMinutes: parseIntOrDefault(queries.Get("interval_minutes"), defaultSlotInterval),
+	}
+
+	if params.PartySize <= 0 {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "party_size must be greater than 0")
+		return
+	}
+	if params.DurationMinutes <= 0 || params.DurationMinutes > maxBookingDuration {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "duration_minutes must be between 1 and 720")
+		return
+	}
+	if params.IntervalMinutes < 5 || params.IntervalMinutes > 240 {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "interval_minutes must be between 5 and 240")
+		return
+	}
+
+	now := time.Now()
+	params.From = now
+	params.To = now.Add(24 * time.Hour)
+	for key, dest := range map[string]*time.Time{"from": &params.From, "to": &params.To} {
+		value := queries.Get(key)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, key+" must be an RFC 3339 timestamp")
+			return
+		}
+		*dest = t
+	}
+
+	// Slots in the past cannot be booked, so the window starts no earlier
+	// than now, rounded up to the next minute.
+	if earliest := now.Truncate(time.Minute).Add(time.Minute); params.From.Before(earliest) {
+		params.From = earliest
+	}
+	if !params.To.After(params.From) {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "to must be after from")
+		return
+	}
+	if params.To.Sub(params.From) > maxAvailabilityWindow {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "the search window must not exceed 7 days")
+		return
+	}
+
+	restaurant, err := h.restaurantStore.GetRestaurantById(r.Context(), restaurantID, store.UnscopedAccess)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get restaurant")
+		return
+	}
+
+	if !restaurant.IsActive {
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "restaurant not found")
+		return
+	}
+
+	slots, err := h.bookingStore.Availability(r.Context(), params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get availability")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"restaurant_id":    restaurantID,
+		"party_size":       params.PartySize,
+		"duration_minutes": params.DurationMinutes,
+		"from":             params.From,
+		"to":               params.To,
+		"slots":            slots,
+	})
+}
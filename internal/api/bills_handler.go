@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"htrr-apis/internal/billing"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"io"
+	"log"
+	"net/http"
+)
+
+type BillHandler struct {
+	logger *log.Logger
+	store  store.BillStore
+}
+
+func NewBillHandler(logger *log.Logger, billStore store.BillStore) *BillHandler {
+	return &BillHandler{
+		logger: logger,
+		store:  billStore,
+	}
+}
+
+func (h *BillHandler) HandleGetSettings(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	settings, err := h.store.GetSettings(restaurantID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get billing settings")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"billing": settings})
+}
+
+// HandleUpdateSettings replaces the tax rates, service charge and rounding
+// of the restaurant.
+func (h *BillHandler) HandleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	settings := billing.Settings{
+		RoundingIncrement: 1,
+		RoundingMode:      billing.RoundHalfUp,
+	}
+	err = json.NewDecoder(r.Body).Decode(&settings)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update billing settings request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if settings.CategoryTaxRates == nil {
+		settings.CategoryTaxRates = []billing.CategoryTaxRate{}
+	}
+
+	if err := settings.Validate(); err != nil {
+		writeError(w, r, h.logger, err, "validate billing settings")
+		return
+	}
+
+	err = h.store.ReplaceSettings(restaurantID, &settings)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update billing settings")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"billing": settings})
+}
+
+// decodeBillRequest reads the discounts and split of a bill. An empty body
+// asks for the whole bill with no discount.
+func (h *BillHandler) decodeBillRequest(w http.ResponseWriter, r *http.Request) (billing.Request, bool) {
+	var req billing.Request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Printf("ERROR: decoding bill request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return req, false
+	}
+
+	if err := req.Validate(); err != nil {
+		writeError(w, r, h.logger, err, "validate bill request")
+		return req, false
+	}
+
+	return req, true
+}
+
+// HandlePreviewBill shows what the bill of an order would be without
+// saving it.
+func (h *BillHandler) HandlePreviewBill(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeBillRequest(w, r)
+	if !ok {
+		return
+	}
+
+	bill, err := h.store.Preview(restaurantID, orderID, req)
+	if err != nil {
+		writeError(w, r, h.logger, err, "preview bill")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"bill": bill})
+}
+
+// HandleFinalizeBill saves the bill of an order and closes the order. The
+// bill cannot change afterwards.
+func (h *BillHandler) HandleFinalizeBill(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeBillRequest(w, r)
+	if !ok {
+		return
+	}
+
+	bill, err := h.store.Finalize(restaurantID, orderID, middleware.GetUser(r).ID, req)
+	if err != nil {
+		writeError(w, r, h.logger, err, "finalize bill")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"bill": bill})
+}
+
+func (h *BillHandler) HandleGetBill(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	bill, err := h.store.GetByOrder(restaurantID, orderID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get bill")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"bill": bill})
+}
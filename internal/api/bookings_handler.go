@@ -0,0 +1,325 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/events"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultBookingDuration = 90
+	maxBookingDuration     = 12 * 60
+)
+
+var bookingStatuses = map[string]bool{
+	store.BookingStatusPending:   true,
+	store.BookingStatusConfirmed: true,
+	store.BookingStatusSeated:    true,
+	store.BookingStatusCompleted: true,
+	store.BookingStatusCancelled: true,
+	store.BookingStatusNoShow:    true,
+}
+
+type BookingHandler struct {
+	logger *log.Logger
+	store  store.BookingStore
+	events *events.Bus
+}
+
+func NewBookingHandler(logger *log.Logger, bookingStore store.BookingStore, bus *events.Bus) *BookingHandler {
+	return &BookingHandler{
+		logger: logger,
+		store:  bookingStore,
+		events: bus,
+	}
+}
+
+type createBookingRequest struct {
+	TableID         string    `json:"table_id"`
+	CustomerID      *string   `json:"customer_id"`
+	CustomerName    string    `json:"customer_name"`
+	CustomerPhone   string    `json:"customer_phone"`
+	CustomerEmail   string    `json:"customer_email"`
+	BookingTime     time.Time `json:"booking_time"`
+	PartySize       int       `json:"party_size"`
+	DurationMinutes int       `json:"duration_minutes"`
+}
+
+type updateBookingRequest struct {
+	TableID         *string        `json:"table_id"`
+	CustomerID      nullableString `json:"customer_id"`
+	CustomerName    *string        `json:"customer_name"`
+	BookingTime     *time.Time     `json:"booking_time"`
+	PartySize       *int           `json:"party_size"`
+	DurationMinutes *int           `json:"duration_minutes"`
+}
+
+func validateBooking(b *store.Booking) error {
+	v := validate.New()
+	v.Required("table_id", b.TableID)
+	v.Required("customer_name", b.CustomerName)
+	v.Check(!b.BookingTime.IsZero(), "booking_time", "booking_time is required")
+	v.Check(b.PartySize > 0, "party_size", "party_size must be greater than 0")
+	v.Range("duration_minutes", b.DurationMinutes, 1, maxBookingDuration)
+	v.Check(bookingStatuses[b.Status], "status", "status is not valid")
+	return v.Err()
+}
+
+func (h *BookingHandler) HandleCreateBooking(w http.ResponseWriter, r *http.Request) {
+	var req createBookingRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create booking request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	booking := &store.Booking{
+		TableID:         req.TableID,
+		CustomerID:      req.CustomerID,
+		CustomerName:    req.CustomerName,
+		ContactPhone:    req.CustomerPhone,
+		ContactEmail:    req.CustomerEmail,
+		BookingTime:     req.BookingTime,
+		PartySize:       req.PartySize,
+		DurationMinutes: req.DurationMinutes,
+		Status:          store.BookingStatusPending,
+		StatusChangedBy: &middleware.GetUser(r).ID,
+	}
+	if booking.DurationMinutes == 0 {
+		booking.DurationMinutes = defaultBookingDuration
+	}
+
+	if err := validateBooking(booking); err != nil {
+		writeError(w, r, h.logger, err, "validate booking")
+		return
+	}
+
+	if booking.BookingTime.Before(time.Now()) {
+		writeInvalid(w, r, "booking_time", "booking_time must be in the future")
+		return
+	}
+
+	err = h.store.Create(r.Context(), booking, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "create booking")
+		return
+	}
+
+	h.events.Publish(booking.RestaurantID, events.BookingCreated, booking)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"booking": booking})
+}
+
+func (h *BookingHandler) HandleListBookings(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	params := store.ListBookingParams{
+		RestaurantID: queries.Get("restaurant_id"),
+		TableID:      queries.Get("table_id"),
+		Status:       queries.Get("status"),
+		Page:         parseIntOrDefault(queries.Get("page"), 1),
+		PageSize:     parseIntOrDefault(queries.Get("page_size"), 10),
+		Scope:        middleware.GetScope(r),
+	}
+
+	for key, dest := range map[string]**time.Time{"from": &params.From, "to": &params.To} {
+		value := queries.Get(key)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, key+" must be an RFC 3339 timestamp")
+			return
+		}
+		*dest = &t
+	}
+
+	list, total, err := h.store.List(r.Context(), params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list bookings")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"bookings": list,
+		"metadata": map[string]any{
+			"current_page":  params.Page,
+			"page_size":     params.PageSize,
+			"total_records": total,
+		}})
+}
+
+func (h *BookingHandler) HandleGetBookingById(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	booking, err := h.store.GetById(r.Context(), id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "get booking")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"booking": booking})
+}
+
+func (h *BookingHandler) HandleUpdateBooking(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req updateBookingRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update booking request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	scope := middleware.GetScope(r)
+
+	booking, err := h.store.GetById(r.Context(), id, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get booking")
+		return
+	}
+
+	if req.TableID != nil {
+		booking.TableID = *req.TableID
+	}
+	if req.CustomerID.Set {
+		booking.CustomerID = req.CustomerID.Value
+	}
+	if req.CustomerName != nil {
+		booking.CustomerName = *req.CustomerName
+	}
+	if req.BookingTime != nil {
+		booking.BookingTime = *req.BookingTime
+	}
+	if req.PartySize != nil {
+		booking.PartySize = *req.PartySize
+	}
+	if req.DurationMinutes != nil {
+		booking.DurationMinutes = *req.DurationMinutes
+	}
+
+	if err := validateBooking(booking); err != nil {
+		writeError(w, r, h.logger, err, "validate booking")
+		return
+	}
+
+	tables, err := h.store.Update(r.Context(), booking, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update booking")
+		return
+	}
+
+	h.events.Publish(booking.RestaurantID, events.BookingUpdated, booking)
+	for _, table := range tables {
+		h.events.Publish(table.RestaurantID, events.TableStatusChanged, table)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"booking": booking})
+}
+
+func (h *BookingHandler) HandleDeleteBooking(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	scope := middleware.GetScope(r)
+
+	booking, err := h.store.GetById(r.Context(), id, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get booking")
+		return
+	}
+
+	table, err := h.store.Delete(r.Context(), id, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete booking")
+		return
+	}
+
+	h.events.Publish(booking.RestaurantID, events.BookingDeleted, map[string]string{"id": id})
+	if table != nil {
+		h.events.Publish(table.RestaurantID, events.TableStatusChanged, table)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
+
+func (h *BookingHandler) transitionBooking(w http.ResponseWriter, r *http.Request, to string) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	booking, table, err := h.store.Transition(r.Context(), id, to, middleware.GetUser(r).ID, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "move booking to "+to)
+		return
+	}
+
+	eventType := events.BookingStatusChanged
+	if to == store.BookingStatusCancelled {
+		eventType = events.BookingCancelled
+	}
+	h.events.Publish(booking.RestaurantID, eventType, booking)
+	if table != nil {
+		h.events.Publish(table.RestaurantID, events.TableStatusChanged, table)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"booking": booking})
+}
+
+func (h *BookingHandler) HandleConfirmBooking(w http.ResponseWriter, r *http.Request) {
+	h.transitionBooking(w, r, store.BookingStatusConfirmed)
+}
+
+func (h *BookingHandler) HandleSeatBooking(w http.ResponseWriter, r *http.Request) {
+	h.transitionBooking(w, r, store.BookingStatusSeated)
+}
+
+func (h *BookingHandler) HandleCompleteBooking(w http.ResponseWriter, r *http.Request) {
+	h.transitionBooking(w, r, store.BookingStatusCompleted)
+}
+
+func (h *BookingHandler) HandleCancelBooking(w http.ResponseWriter, r *http.Request) {
+	h.transitionBooking(w, r, store.BookingStatusCancelled)
+}
+
+func (h *BookingHandler) HandleNoShowBooking(w http.ResponseWriter, r *http.Request) {
+	h.transitionBooking(w, r, store.BookingStatusNoShow)
+}
+
+func (h *BookingHandler) HandleGetBookingHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	history, err := h.store.History(r.Context(), id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "get booking history")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"history": history})
+}
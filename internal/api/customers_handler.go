@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+	"net/mail"
+)
+
+type CustomerHandler struct {
+	logger *log.Logger
+	store  store.CustomerStore
+}
+
+func NewCustomerHandler(logger *log.Logger, customerStore store.CustomerStore) *CustomerHandler {
+	return &CustomerHandler{
+		logger: logger,
+		store:  customerStore,
+	}
+}
+
+type createCustomerRequest struct {
+	RestaurantID string   `json:"restaurant_id"`
+	FullName     string   `json:"full_name"`
+	Phone        string   `json:"phone"`
+	Email        string   `json:"email"`
+	Notes        string   `json:"notes"`
+	Allergies    []string `json:"allergies"`
+	Tags         []string `json:"tags"`
+}
+
+type updateCustomerRequest struct {
+	FullName  *string   `json:"full_name"`
+	Phone     *string   `json:"phone"`
+	Email     *string   `json:"email"`
+	Notes     *string   `json:"notes"`
+	Allergies *[]string `json:"allergies"`
+	Tags      *[]string `json:"tags"`
+}
+
+func validateCustomer(c *store.Customer) error {
+	v := validate.New()
+	v.Check(c.FullName != "", "full_name", "full_name is required")
+	v.Length("full_name", c.FullName, 0, 255)
+	v.Check(len(store.NormalizePhone(c.Phone)) <= 20, "phone", "phone must not be more than 20 characters")
+	if c.Email != "" {
+		_, err := mail.ParseAddress(c.Email)
+		v.Check(err == nil, "email", "email is not valid")
+	}
+	if c.Allergies == nil {
+		c.Allergies = []string{}
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	return v.Err()
+}
+
+func (h *CustomerHandler) HandleCreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var req createCustomerRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create customer request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.RestaurantID == "" {
+		writeInvalid(w, r, "restaurant_id", "restaurant_id is required")
+		return
+	}
+
+	customer := &store.Customer{
+		RestaurantID: req.RestaurantID,
+		FullName:     req.FullName,
+		Phone:        req.Phone,
+		Email:        req.Email,
+		Notes:        req.Notes,
+		Allergies:    req.Allergies,
+		Tags:         req.Tags,
+	}
+
+	if err := validateCustomer(customer); err != nil {
+		writeError(w, r, h.logger, err, "validate customer")
+		return
+	}
+
+	err = h.store.Create(customer, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "create customer")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"customer": customer})
+}
+
+func (h *CustomerHandler) HandleListCustomers(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	params := store.ListCustomerParams{
+		RestaurantID: queries.Get("restaurant_id"),
+		Query:        queries.Get("q"),
+		Tag:          queries.Get("tag"),
+		Page:         parseIntOrDefault(queries.Get("page"), 1),
+		PageSize:     parseIntOrDefault(queries.Get("page_size"), 10),
+		Scope:        middleware.GetScope(r),
+	}
+
+	list, total, err := h.store.List(params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list customers")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"customers": list,
+		"metadata": map[string]any{
+			"current_page":  params.Page,
+			"page_size":     params.PageSize,
+			"total_records": total,
+		}})
+}
+
+func (h *CustomerHandler) HandleGetCustomerById(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	customer, err := h.store.GetById(id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "get customer")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"customer": customer})
+}
+
+func (h *CustomerHandler) HandleUpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req updateCustomerRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update customer request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	scope := middleware.GetScope(r)
+
+	customer, err := h.store.GetById(id, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get customer")
+		return
+	}
+
+	if req.FullName != nil {
+		customer.FullName = *req.FullName
+	}
+	if req.Phone != nil {
+		customer.Phone = *req.Phone
+	}
+	if req.Email != nil {
+		customer.Email = *req.Email
+	}
+	if req.Notes != nil {
+		customer.Notes = *req.Notes
+	}
+	if req.Allergies != nil {
+		customer.Allergies = *req.Allergies
+	}
+	if req.Tags != nil {
+		customer.Tags = *req.Tags
+	}
+
+	if err := validateCustomer(customer); err != nil {
+		writeError(w, r, h.logger, err, "validate customer")
+		return
+	}
+
+	err = h.store.Update(customer, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update customer")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"customer": customer})
+}
+
+func (h *CustomerHandler) HandleDeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	err = h.store.Delete(id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete customer")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
+
+// HandleGetCustomerHistory lists the bookings of a customer together with
+// visit, no-show and cancellation counts.
+func (h *CustomerHandler) HandleGetCustomerHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	history, err := h.store.History(id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "get customer history")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"history": history})
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+)
+
+type EmployeeHandler struct {
+	logger *log.Logger
+	store  store.EmployeeStore
+}
+
+func NewEmployeeHandler(logger *log.Logger, employeeStore store.EmployeeStore) *EmployeeHandler {
+	return &EmployeeHandler{
+		logger: logger,
+		store:  employeeStore,
+	}
+}
+
+// nullableString tells a missing JSON field apart from an explicit null, so
+// PATCH bodies can unlink a user or position with `"user_id": null`.
+type nullableString struct {
+	Set   bool
+	Value *string
+}
+
+func (n *nullableString) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
+
+type createEmployeeRequest struct {
+	RestaurantID string  `json:"restaurant_id"`
+	UserID       *string `json:"user_id"`
+	PositionID   *string `json:"position_id"`
+	FullName     string  `json:"full_name"`
+}
+
+func (r *createEmployeeRequest) validate() error {
+	v := validate.New()
+	v.Required("restaurant_id", r.RestaurantID)
+	v.Check(r.FullName != "", "full_name", "full_name is required")
+	return v.Err()
+}
+
+type updateEmployeeRequest struct {
+	FullName   *string        `json:"full_name"`
+	UserID     nullableString `json:"user_id"`
+	PositionID nullableString `json:"position_id"`
+}
+
+func (h *EmployeeHandler) HandleCreateEmployee(w http.ResponseWriter, r *http.Request) {
+	var req createEmployeeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create employee request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeError(w, r, h.logger, err, "validate employee")
+		return
+	}
+
+	emp := &store.Employee{
+		RestaurantID: req.RestaurantID,
+		UserID:       req.UserID,
+		PositionID:   req.PositionID,
+		FullName:     req.FullName,
+	}
+
+	err = h.store.Create(emp, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "create employee")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"employee": emp})
+}
+
+func (h *EmployeeHandler) HandleListEmployees(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	params := store.ListEmployeeParams{
+		RestaurantID: queries.Get("restaurant_id"),
+		PositionID:   queries.Get("position_id"),
+		Page:         parseIntOrDefault(queries.Get("page"), 1),
+		PageSize:     parseIntOrDefault(queries.Get("page_size"), 10),
+		Scope:        middleware.GetScope(r),
+	}
+
+	list, total, err := h.store.List(params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list employees")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"employees": list,
+		"metadata": map[string]any{
+			"current_page":  params.Page,
+			"page_size":     params.PageSize,
+			"total_records": total,
+		}})
+}
+
+func (h *EmployeeHandler) HandleGetEmployeeById(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	emp, err := h.store.GetById(id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "get employee")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"employee": emp})
+}
+
+func (h *EmployeeHandler) HandleUpdateEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req updateEmployeeRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update employee request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.FullName != nil && *req.FullName == "" {
+		writeInvalid(w, r, "full_name", "full_name cannot be empty")
+		return
+	}
+
+	scope := middleware.GetScope(r)
+
+	emp, err := h.store.GetById(id, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get employee")
+		return
+	}
+
+	if req.FullName != nil {
+		emp.FullName = *req.FullName
+	}
+	if req.UserID.Set {
+		emp.UserID = req.UserID.Value
+	}
+	if req.PositionID.Set {
+		emp.PositionID = req.PositionID.Value
+	}
+
+	err = h.store.Update(emp, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update employee")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"employee": emp})
+}
+
+func (h *EmployeeHandler) HandleDeleteEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	err = h.store.Delete(id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete employee")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"htrr-apis/internal/billing"
+	"htrr-apis/internal/payments"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+)
+
+// problemFor turns an error returned by a store into the problem reported
+// to the client, picking the status and code from the kind of error.
+// Errors of no known kind become internal server errors, so database
+// messages never reach the client; ok is false for those.
+func problemFor(err error) (p utils.Problem, ok bool) {
+	err = store.Classify(err)
+
+	var verr *store.ValidationError
+	var ferrs validate.Errors
+	switch {
+	case errors.Is(err, store.ErrInvalidID):
+		return utils.NewProblem(http.StatusBadRequest, utils.CodeInvalidID, err.Error()), true
+	case errors.Is(err, store.ErrNotFound):
+		return utils.NewProblem(http.StatusNotFound, utils.CodeNotFound, err.Error()), true
+	case errors.Is(err, store.ErrConflict):
+		return utils.NewProblem(http.StatusConflict, utils.CodeConflict, err.Error()), true
+	case errors.As(err, &verr):
+		p := utils.NewProblem(http.StatusUnprocessableEntity, utils.CodeValidation, err.Error())
+		for _, f := range verr.Fields {
+			p.Errors = append(p.Errors, utils.FieldError{Field: f.Field, Message: f.Message})
+		}
+		return p, true
+	case errors.As(err, &ferrs):
+		p := utils.NewProblem(http.StatusUnprocessableEntity, utils.CodeValidation, "request has invalid fields")
+		p.Errors = ferrs
+		return p, true
+	case errors.Is(err, payments.ErrDeclined):
+		return utils.NewProblem(http.StatusPaymentRequired, utils.CodePaymentDeclined, err.Error()), true
+	case errors.Is(err, payments.ErrUnavailable):
+		return utils.NewProblem(http.StatusServiceUnavailable, utils.CodeUnavailable,
+			"payment provider is unavailable, retry with the same Idempotency-Key"), false
+	case errors.Is(err, payments.ErrInvalidAmount),
+		errors.Is(err, payments.ErrUnknownReference),
+		errors.Is(err, billing.ErrEmptyBill),
+		errors.Is(err, billing.ErrNoSeats),
+		errors.Is(err, billing.ErrUnknownLine):
+		return utils.NewProblem(http.StatusUnprocessableEntity, utils.CodeValidation, err.Error()), true
+	}
+	return utils.NewProblem(http.StatusInternalServerError, utils.CodeInternal, "internal server error"), false
+}
+
+// writeError answers a request that failed with err. Errors the client
+// cannot act on are logged with action.
+func writeError(w http.ResponseWriter, r *http.Request, logger *log.Logger, err error, action string) {
+	p, ok := problemFor(err)
+	if !ok {
+		logger.Printf("ERROR: %s: %v", action, err)
+	}
+	utils.WriteProblem(w, r, p)
+}
+
+// writeInvalid answers a request whose body breaks a rule checked outside a
+// validate.Validator, the same way as a failed Validator.
+func writeInvalid(w http.ResponseWriter, r *http.Request, field, msg string) {
+	p, _ := problemFor(validate.Errors{{Field: field, Message: msg}})
+	utils.WriteProblem(w, r, p)
+}
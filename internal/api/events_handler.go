@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"htrr-apis/internal/events"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/tokens"
+	"htrr-apis/internal/utils"
+	"log"
+	"net/http"
+	"time"
+)
+
+const sseHeartbeat = 25 * time.Second
+
+type EventHandler struct {
+	logger  *log.Logger
+	bus     *events.Bus
+	tickets *tokens.TicketStore
+}
+
+func NewEventHandler(logger *log.Logger, bus *events.Bus, tickets *tokens.TicketStore) *EventHandler {
+	return &EventHandler{
+		logger:  logger,
+		bus:     bus,
+		tickets: tickets,
+	}
+}
+
+// HandleIssueStreamTicket hands out a single-use ticket for the restaurant's
+// event stream. Browsers pass it as /events?ticket=... since EventSource
+// cannot send the Authorization header.
+func (h *EventHandler) HandleIssueStreamTicket(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	ticket, err := h.tickets.Issue(middleware.GetUser(r).ID, restaurantID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "issue stream ticket")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"ticket": ticket})
+}
+
+// HandleStream pushes the table and booking events of one restaurant as
+// Server-Sent Events until the client disconnects. A comment line is sent
+// periodically so proxies keep the connection open. Callers authenticate
+// with a bearer token or a stream ticket.
+func (h *EventHandler) HandleStream(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's WriteTimeout would otherwise cut long-lived streams.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream, cancel := h.bus.Subscribe(restaurantID)
+	defer cancel()
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		h.logger.Printf("ERROR: event stream flush: %v", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e, ok := <-stream:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				h.logger.Printf("ERROR: encoding event %s: %v", e.Type, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
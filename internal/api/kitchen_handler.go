@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/events"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"log"
+	"net/http"
+)
+
+type KitchenHandler struct {
+	logger *log.Logger
+	store  store.KitchenStore
+	events *events.Bus
+}
+
+func NewKitchenHandler(logger *log.Logger, kitchenStore store.KitchenStore, bus *events.Bus) *KitchenHandler {
+	return &KitchenHandler{
+		logger: logger,
+		store:  kitchenStore,
+		events: bus,
+	}
+}
+
+func (h *KitchenHandler) HandleGetRouting(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	routing, err := h.store.GetRouting(restaurantID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get kitchen routing")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"routing": routing})
+}
+
+// HandleUpdateRouting replaces the stations of the restaurant and the
+// categories and items routed to them.
+func (h *KitchenHandler) HandleUpdateRouting(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var routing store.KitchenRouting
+	err = json.NewDecoder(r.Body).Decode(&routing)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update kitchen routing request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if routing.Stations == nil {
+		routing.Stations = []store.KitchenStation{}
+	}
+	if routing.Routes == nil {
+		routing.Routes = []store.KitchenRoute{}
+	}
+
+	if err := routing.Validate(); err != nil {
+		writeError(w, r, h.logger, err, "validate kitchen routing")
+		return
+	}
+
+	err = h.store.ReplaceRouting(restaurantID, &routing)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update kitchen routing")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"routing": routing})
+}
+
+// HandleListTickets serves a kitchen screen: the tickets still in the
+// kitchen, oldest first, optionally for one station.
+func (h *KitchenHandler) HandleListTickets(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	params := store.ListTicketParams{
+		RestaurantID: restaurantID,
+		Station:      utils.GetURLQuery("station", r),
+		Status:       utils.GetURLQuery("status", r),
+	}
+
+	if params.Status != "" && !store.IsValidTicketStatus(params.Status) {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "status must be one of queued, preparing, ready, served")
+		return
+	}
+
+	tickets, err := h.store.ListTickets(params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list tickets")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tickets": tickets})
+}
+
+func (h *KitchenHandler) HandleGetTicketById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, ticketID, ok := urlIDs(w, r, "ticketId")
+	if !ok {
+		return
+	}
+
+	ticket, err := h.store.GetTicket(restaurantID, ticketID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get ticket")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"ticket": ticket})
+}
+
+// HandleBumpTicket moves the whole ticket one step along
+// queued, preparing, ready and served.
+func (h *KitchenHandler) HandleBumpTicket(w http.ResponseWriter, r *http.Request) {
+	restaurantID, ticketID, ok := urlIDs(w, r, "ticketId")
+	if !ok {
+		return
+	}
+
+	ticket, err := h.store.BumpTicket(restaurantID, ticketID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "bump ticket")
+		return
+	}
+
+	h.events.Publish(restaurantID, events.KitchenTicketUpdated, ticket)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"ticket": ticket})
+}
+
+func (h *KitchenHandler) HandleBumpTicketItem(w http.ResponseWriter, r *http.Request) {
+	restaurantID, ticketID, ok := urlIDs(w, r, "ticketId")
+	if !ok {
+		return
+	}
+
+	itemID, err := utils.GetUrlParams("itemId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid item id")
+		return
+	}
+
+	ticket, err := h.store.BumpItem(restaurantID, ticketID, itemID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "bump ticket item")
+		return
+	}
+
+	h.events.Publish(restaurantID, events.KitchenTicketUpdated, ticket)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"ticket": ticket})
+}
@@ -0,0 +1,861 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// dietaryTags are the tags an item may carry. The list is closed so that the
+// guest-facing menu can filter on them reliably.
+var dietaryTags = map[string]bool{
+	"vegetarian":  true,
+	"vegan":       true,
+	"gluten_free": true,
+	"dairy_free":  true,
+	"nut_free":    true,
+	"halal":       true,
+	"kosher":      true,
+	"spicy":       true,
+}
+
+type MenuHandler struct {
+	logger          *log.Logger
+	store           store.MenuStore
+	restaurantStore store.RestaurantStore
+}
+
+func NewMenuHandler(logger *log.Logger, menuStore store.MenuStore, restaurantStore store.RestaurantStore) *MenuHandler {
+	return &MenuHandler{
+		logger:          logger,
+		store:           menuStore,
+		restaurantStore: restaurantStore,
+	}
+}
+
+type createMenuRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsActive    *bool  `json:"is_active"`
+	Position    int    `json:"position"`
+}
+
+type updateMenuRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+	Position    *int    `json:"position"`
+}
+
+type createMenuCategoryRequest struct {
+	MenuID      string `json:"menu_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Position    int    `json:"position"`
+}
+
+type updateMenuCategoryRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Position    *int    `json:"position"`
+}
+
+type createMenuItemRequest struct {
+	CategoryID  string   `json:"category_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	PriceMinor  int64    `json:"price_minor"`
+	Currency    string   `json:"currency"`
+	IsAvailable *bool    `json:"is_available"`
+	DietaryTags []string `json:"dietary_tags"`
+	Position    int      `json:"position"`
+}
+
+type updateMenuItemRequest struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	PriceMinor  *int64    `json:"price_minor"`
+	Currency    *string   `json:"currency"`
+	IsAvailable *bool     `json:"is_available"`
+	DietaryTags *[]string `json:"dietary_tags"`
+	Position    *int      `json:"position"`
+}
+
+type createModifierGroupRequest struct {
+	ItemID        string `json:"item_id"`
+	Name          string `json:"name"`
+	MinSelections int    `json:"min_selections"`
+	MaxSelections *int   `json:"max_selections"`
+	Position      int    `json:"position"`
+}
+
+type updateModifierGroupRequest struct {
+	Name          *string `json:"name"`
+	MinSelections *int    `json:"min_selections"`
+	MaxSelections *int    `json:"max_selections"`
+	Position      *int    `json:"position"`
+}
+
+type createModifierRequest struct {
+	GroupID     string `json:"group_id"`
+	Name        string `json:"name"`
+	PriceMinor  int64  `json:"price_minor"`
+	IsAvailable *bool  `json:"is_available"`
+	Position    int    `json:"position"`
+}
+
+type updateModifierRequest struct {
+	Name        *string `json:"name"`
+	PriceMinor  *int64  `json:"price_minor"`
+	IsAvailable *bool   `json:"is_available"`
+	Position    *int    `json:"position"`
+}
+
+// checkMenuName applies the name rules shared by menus, categories, items,
+// modifier groups and modifiers.
+func checkMenuName(v *validate.Validator, name string) {
+	v.Required("name", name)
+	v.Length("name", name, 0, 255)
+}
+
+func validateMenuName(name string) error {
+	v := validate.New()
+	checkMenuName(v, name)
+	return v.Err()
+}
+
+// isCurrencyCode reports whether s looks like an ISO 4217 code such as "USD".
+func isCurrencyCode(s string) bool {
+	return len(s) == 3 && strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+func validateMenuItem(i *store.MenuItem) error {
+	v := validate.New()
+	checkMenuName(v, i.Name)
+	v.Check(i.PriceMinor >= 0, "price_minor", "price_minor must not be negative")
+
+	i.Currency = strings.ToUpper(i.Currency)
+	v.Check(isCurrencyCode(i.Currency), "currency", "currency must be a three-letter ISO 4217 code")
+
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, tag := range i.DietaryTags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !dietaryTags[tag] {
+			known := make([]string, 0, len(dietaryTags))
+			for t := range dietaryTags {
+				known = append(known, t)
+			}
+			sort.Strings(known)
+			v.Add("dietary_tags", "dietary_tags must be among "+strings.Join(known, ", "))
+			continue
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	i.DietaryTags = tags
+
+	return v.Err()
+}
+
+func validateModifierGroup(g *store.ModifierGroup) error {
+	v := validate.New()
+	checkMenuName(v, g.Name)
+	v.Check(g.MinSelections >= 0, "min_selections", "min_selections must not be negative")
+	v.Check(g.MaxSelections > 0, "max_selections", "max_selections must be greater than 0")
+	v.Check(g.MinSelections <= g.MaxSelections, "min_selections", "min_selections must not be greater than max_selections")
+	return v.Err()
+}
+
+func validateModifier(m *store.Modifier) error {
+	v := validate.New()
+	checkMenuName(v, m.Name)
+	v.Check(m.PriceMinor >= 0, "price_minor", "price_minor must not be negative")
+	return v.Err()
+}
+
+// urlIDs returns the restaurant id and the id of the resource below it named
+// by key.
+func urlIDs(w http.ResponseWriter, r *http.Request, key string) (string, string, bool) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return "", "", false
+	}
+
+	id, err := utils.GetUrlParams(key, r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid "+key)
+		return "", "", false
+	}
+
+	return restaurantID, id, true
+}
+
+// HandleGetMenuTree serves the public menu: every active menu of the
+// restaurant with its categories, items, modifier groups and modifiers.
+func (h *MenuHandler) HandleGetMenuTree(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	restaurant, err := h.restaurantStore.GetRestaurantById(r.Context(), restaurantID, store.UnscopedAccess)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get restaurant")
+		return
+	}
+
+	if !restaurant.IsActive {
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "restaurant not found")
+		return
+	}
+
+	menus, err := h.store.Tree(restaurantID, true)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get menu tree")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"menus": menus})
+}
+
+func (h *MenuHandler) HandleCreateMenu(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req createMenuRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create menu request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	menu := &store.Menu{
+		RestaurantID: restaurantID,
+		Name:         req.Name,
+		Description:  req.Description,
+		IsActive:     true,
+		Position:     req.Position,
+	}
+	if req.IsActive != nil {
+		menu.IsActive = *req.IsActive
+	}
+
+	if err := validateMenuName(menu.Name); err != nil {
+		writeError(w, r, h.logger, err, "validate menu")
+		return
+	}
+
+	err = h.store.CreateMenu(menu)
+	if err != nil {
+		writeError(w, r, h.logger, err, "create menu")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"menu": menu})
+}
+
+// HandleListMenus lists every menu of the restaurant, inactive ones
+// included, without their contents.
+func (h *MenuHandler) HandleListMenus(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	menus, err := h.store.ListMenus(restaurantID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list menus")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"menus": menus})
+}
+
+func (h *MenuHandler) HandleGetMenuById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, menuID, ok := urlIDs(w, r, "menuId")
+	if !ok {
+		return
+	}
+
+	menu, err := h.store.GetMenu(restaurantID, menuID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get menu")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"menu": menu})
+}
+
+func (h *MenuHandler) HandleUpdateMenu(w http.ResponseWriter, r *http.Request) {
+	restaurantID, menuID, ok := urlIDs(w, r, "menuId")
+	if !ok {
+		return
+	}
+
+	var req updateMenuRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update menu request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	menu, err := h.store.GetMenu(restaurantID, menuID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get menu")
+		return
+	}
+
+	if req.Name != nil {
+		menu.Name = *req.Name
+	}
+	if req.Description != nil {
+		menu.Description = *req.Description
+	}
+	if req.IsActive != nil {
+		menu.IsActive = *req.IsActive
+	}
+	if req.Position != nil {
+		menu.Position = *req.Position
+	}
+
+	if err := validateMenuName(menu.Name); err != nil {
+		writeError(w, r, h.logger, err, "validate menu")
+		return
+	}
+
+	err = h.store.UpdateMenu(menu)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update menu")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"menu": menu})
+}
+
+func (h *MenuHandler) HandleDeleteMenu(w http.ResponseWriter, r *http.Request) {
+	restaurantID, menuID, ok := urlIDs(w, r, "menuId")
+	if !ok {
+		return
+	}
+
+	err := h.store.DeleteMenu(restaurantID, menuID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete menu")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
+
+func (h *MenuHandler) HandleCreateMenuCategory(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req createMenuCategoryRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create menu category request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.MenuID == "" {
+		writeInvalid(w, r, "menu_id", "menu_id is required")
+		return
+	}
+
+	category := &store.MenuCategory{
+		RestaurantID: restaurantID,
+		MenuID:       req.MenuID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Position:     req.Position,
+	}
+
+	if err := validateMenuName(category.Name); err != nil {
+		writeError(w, r, h.logger, err, "validate menu category")
+		return
+	}
+
+	err = h.store.CreateCategory(category)
+	if err != nil {
+		writeError(w, r, h.logger, err, "create menu category")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"category": category})
+}
+
+func (h *MenuHandler) HandleGetMenuCategoryById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, categoryID, ok := urlIDs(w, r, "categoryId")
+	if !ok {
+		return
+	}
+
+	category, err := h.store.GetCategory(restaurantID, categoryID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get menu category")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"category": category})
+}
+
+func (h *MenuHandler) HandleUpdateMenuCategory(w http.ResponseWriter, r *http.Request) {
+	restaurantID, categoryID, ok := urlIDs(w, r, "categoryId")
+	if !ok {
+		return
+	}
+
+	var req updateMenuCategoryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update menu category request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	category, err := h.store.GetCategory(restaurantID, categoryID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get menu category")
+		return
+	}
+
+	if req.Name != nil {
+		category.Name = *req.Name
+	}
+	if req.Description != nil {
+		category.Description = *req.Description
+	}
+	if req.Position != nil {
+		category.Position = *req.Position
+	}
+
+	if err := validateMenuName(category.Name); err != nil {
+		writeError(w, r, h.logger, err, "validate menu category")
+		return
+	}
+
+	err = h.store.UpdateCategory(category)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update menu category")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"category": category})
+}
+
+func (h *MenuHandler) HandleDeleteMenuCategory(w http.ResponseWriter, r *http.Request) {
+	restaurantID, categoryID, ok := urlIDs(w, r, "categoryId")
+	if !ok {
+		return
+	}
+
+	err := h.store.DeleteCategory(restaurantID, categoryID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete menu category")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
+
+func (h *MenuHandler) HandleCreateMenuItem(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req createMenuItemRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create menu item request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.CategoryID == "" {
+		writeInvalid(w, r, "category_id", "category_id is required")
+		return
+	}
+
+	item := &store.MenuItem{
+		RestaurantID: restaurantID,
+		CategoryID:   req.CategoryID,
+		Name:         req.Name,
+		Description:  req.Description,
+		PriceMinor:   req.PriceMinor,
+		Currency:     req.Currency,
+		IsAvailable:  true,
+		DietaryTags:  req.DietaryTags,
+		Position:     req.Position,
+	}
+	if req.IsAvailable != nil {
+		item.IsAvailable = *req.IsAvailable
+	}
+
+	if err := validateMenuItem(item); err != nil {
+		writeError(w, r, h.logger, err, "validate menu item")
+		return
+	}
+
+	err = h.store.CreateItem(item)
+	if err != nil {
+		writeError(w, r, h.logger, err, "create menu item")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"item": item})
+}
+
+func (h *MenuHandler) HandleGetMenuItemById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, itemID, ok := urlIDs(w, r, "itemId")
+	if !ok {
+		return
+	}
+
+	item, err := h.store.GetItem(restaurantID, itemID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get menu item")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"item": item})
+}
+
+func (h *MenuHandler) HandleUpdateMenuItem(w http.ResponseWriter, r *http.Request) {
+	restaurantID, itemID, ok := urlIDs(w, r, "itemId")
+	if !ok {
+		return
+	}
+
+	var req updateMenuItemRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update menu item request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	item, err := h.store.GetItem(restaurantID, itemID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get menu item")
+		return
+	}
+
+	if req.Name != nil {
+		item.Name = *req.Name
+	}
+	if req.Description != nil {
+		item.Description = *req.Description
+	}
+	if req.PriceMinor != nil {
+		item.PriceMinor = *req.PriceMinor
+	}
+	if req.Currency != nil {
+		item.Currency = *req.Currency
+	}
+	if req.IsAvailable != nil {
+		item.IsAvailable = *req.IsAvailable
+	}
+	if req.DietaryTags != nil {
+		item.DietaryTags = *req.DietaryTags
+	}
+	if req.Position != nil {
+		item.Position = *req.Position
+	}
+
+	if err := validateMenuItem(item); err != nil {
+		writeError(w, r, h.logger, err, "validate menu item")
+		return
+	}
+
+	err = h.store.UpdateItem(item)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update menu item")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"item": item})
+}
+
+func (h *MenuHandler) HandleDeleteMenuItem(w http.ResponseWriter, r *http.Request) {
+	restaurantID, itemID, ok := urlIDs(w, r, "itemId")
+	if !ok {
+		return
+	}
+
+	err := h.store.DeleteItem(restaurantID, itemID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete menu item")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
+
+func (h *MenuHandler) HandleCreateModifierGroup(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req createModifierGroupRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create modifier group request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.ItemID == "" {
+		writeInvalid(w, r, "item_id", "item_id is required")
+		return
+	}
+
+	group := &store.ModifierGroup{
+		RestaurantID:  restaurantID,
+		ItemID:        req.ItemID,
+		Name:          req.Name,
+		MinSelections: req.MinSelections,
+		MaxSelections: 1,
+		Position:      req.Position,
+	}
+	if req.MaxSelections != nil {
+		group.MaxSelections = *req.MaxSelections
+	}
+
+	if err := validateModifierGroup(group); err != nil {
+		writeError(w, r, h.logger, err, "validate modifier group")
+		return
+	}
+
+	err = h.store.CreateModifierGroup(group)
+	if err != nil {
+		writeError(w, r, h.logger, err, "create modifier group")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"modifier_group": group})
+}
+
+func (h *MenuHandler) HandleGetModifierGroupById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, groupID, ok := urlIDs(w, r, "groupId")
+	if !ok {
+		return
+	}
+
+	group, err := h.store.GetModifierGroup(restaurantID, groupID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get modifier group")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"modifier_group": group})
+}
+
+func (h *MenuHandler) HandleUpdateModifierGroup(w http.ResponseWriter, r *http.Request) {
+	restaurantID, groupID, ok := urlIDs(w, r, "groupId")
+	if !ok {
+		return
+	}
+
+	var req updateModifierGroupRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update modifier group request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	group, err := h.store.GetModifierGroup(restaurantID, groupID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get modifier group")
+		return
+	}
+
+	if req.Name != nil {
+		group.Name = *req.Name
+	}
+	if req.MinSelections != nil {
+		group.MinSelections = *req.MinSelections
+	}
+	if req.MaxSelections != nil {
+		group.MaxSelections = *req.MaxSelections
+	}
+	if req.Position != nil {
+		group.Position = *req.Position
+	}
+
+	if err := validateModifierGroup(group); err != nil {
+		writeError(w, r, h.logger, err, "validate modifier group")
+		return
+	}
+
+	err = h.store.UpdateModifierGroup(group)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update modifier group")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"modifier_group": group})
+}
+
+func (h *MenuHandler) HandleDeleteModifierGroup(w http.ResponseWriter, r *http.Request) {
+	restaurantID, groupID, ok := urlIDs(w, r, "groupId")
+	if !ok {
+		return
+	}
+
+	err := h.store.DeleteModifierGroup(restaurantID, groupID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete modifier group")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
+
+func (h *MenuHandler) HandleCreateModifier(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req createModifierRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create modifier request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.GroupID == "" {
+		writeInvalid(w, r, "group_id", "group_id is required")
+		return
+	}
+
+	modifier := &store.Modifier{
+		RestaurantID: restaurantID,
+		GroupID:      req.GroupID,
+		Name:         req.Name,
+		PriceMinor:   req.PriceMinor,
+		IsAvailable:  true,
+		Position:     req.Position,
+	}
+	if req.IsAvailable != nil {
+		modifier.IsAvailable = *req.IsAvailable
+	}
+
+	if err := validateModifier(modifier); err != nil {
+		writeError(w, r, h.logger, err, "validate modifier")
+		return
+	}
+
+	err = h.store.CreateModifier(modifier)
+	if err != nil {
+		writeError(w, r, h.logger, err, "create modifier")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"modifier": modifier})
+}
+
+func (h *MenuHandler) HandleGetModifierById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, modifierID, ok := urlIDs(w, r, "modifierId")
+	if !ok {
+		return
+	}
+
+	modifier, err := h.store.GetModifier(restaurantID, modifierID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get modifier")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"modifier": modifier})
+}
+
+func (h *MenuHandler) HandleUpdateModifier(w http.ResponseWriter, r *http.Request) {
+	restaurantID, modifierID, ok := urlIDs(w, r, "modifierId")
+	if !ok {
+		return
+	}
+
+	var req updateModifierRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update modifier request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	modifier, err := h.store.GetModifier(restaurantID, modifierID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get modifier")
+		return
+	}
+
+	if req.Name != nil {
+		modifier.Name = *req.Name
+	}
+	if req.PriceMinor != nil {
+		modifier.PriceMinor = *req.PriceMinor
+	}
+	if req.IsAvailable != nil {
+		modifier.IsAvailable = *req.IsAvailable
+	}
+	if req.Position != nil {
+		modifier.Position = *req.Position
+	}
+
+	if err := validateModifier(modifier); err != nil {
+		writeError(w, r, h.logger, err, "validate modifier")
+		return
+	}
+
+	err = h.store.UpdateModifier(modifier)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update modifier")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"modifier": modifier})
+}
+
+func (h *MenuHandler) HandleDeleteModifier(w http.ResponseWriter, r *http.Request) {
+	restaurantID, modifierID, ok := urlIDs(w, r, "modifierId")
+	if !ok {
+		return
+	}
+
+	err := h.store.DeleteModifier(restaurantID, modifierID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete modifier")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
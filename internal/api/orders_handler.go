@@ -0,0 +1,322 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/events"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const maxOrderItemQuantity = 100
+
+type OrderHandler struct {
+	logger *log.Logger
+	store  store.OrderStore
+	events *events.Bus
+}
+
+func NewOrderHandler(logger *log.Logger, orderStore store.OrderStore, bus *events.Bus) *OrderHandler {
+	return &OrderHandler{
+		logger: logger,
+		store:  orderStore,
+		events: bus,
+	}
+}
+
+type openOrderRequest struct {
+	TableID   string  `json:"table_id"`
+	BookingID *string `json:"booking_id"`
+	Notes     string  `json:"notes"`
+}
+
+type addOrderItemRequest struct {
+	MenuItemID  string   `json:"menu_item_id"`
+	ModifierIDs []string `json:"modifier_ids"`
+	Name        string   `json:"name"`
+	PriceMinor  *int64   `json:"price_minor"`
+	Currency    string   `json:"currency"`
+	Quantity    *int     `json:"quantity"`
+	Seat        *int     `json:"seat"`
+	Notes       string   `json:"notes"`
+}
+
+type setOrderItemSeatRequest struct {
+	Seat *int `json:"seat"`
+}
+
+func checkSeat(v *validate.Validator, seat *int) {
+	v.Check(seat == nil || *seat > 0, "seat", "seat must be greater than 0")
+}
+
+func validateSeat(seat *int) error {
+	v := validate.New()
+	checkSeat(v, seat)
+	return v.Err()
+}
+
+func validateOrderItem(p *store.AddOrderItemParams) error {
+	v := validate.New()
+	v.Range("quantity", p.Quantity, 1, maxOrderItemQuantity)
+	checkSeat(v, p.Seat)
+	if p.MenuItemID != "" {
+		return v.Err()
+	}
+
+	v.Check(p.Name != "", "name", "name is required when menu_item_id is not given")
+	v.Length("name", p.Name, 0, 255)
+	v.Check(p.PriceMinor >= 0, "price_minor", "price_minor must not be negative")
+	v.Check(len(p.ModifierIDs) == 0, "modifier_ids", "modifier_ids can only be used with menu_item_id")
+	p.Currency = strings.ToUpper(p.Currency)
+	v.Check(p.Currency == "" || isCurrencyCode(p.Currency), "currency", "currency must be a three-letter ISO 4217 code")
+	return v.Err()
+}
+
+func (h *OrderHandler) HandleOpenOrder(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req openOrderRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding open order request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.TableID == "" {
+		writeInvalid(w, r, "table_id", "table_id is required")
+		return
+	}
+
+	openedBy := middleware.GetUser(r).ID
+	order := &store.Order{
+		RestaurantID: restaurantID,
+		TableID:      &req.TableID,
+		BookingID:    req.BookingID,
+		Notes:        req.Notes,
+		OpenedBy:     &openedBy,
+	}
+
+	err = h.store.Open(order)
+	if err != nil {
+		writeError(w, r, h.logger, err, "open order")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"order": order})
+}
+
+func (h *OrderHandler) HandleListOrders(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	queries := r.URL.Query()
+
+	params := store.ListOrderParams{
+		RestaurantID: restaurantID,
+		TableID:      queries.Get("table_id"),
+		Status:       queries.Get("status"),
+		Page:         parseIntOrDefault(queries.Get("page"), 1),
+		PageSize:     parseIntOrDefault(queries.Get("page_size"), 10),
+	}
+
+	if params.Status != "" && params.Status != store.OrderStatusOpen && params.Status != store.OrderStatusClosed {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "status must be open or closed")
+		return
+	}
+
+	orders, total, err := h.store.List(params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list orders")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"orders": orders,
+		"metadata": map[string]any{
+			"current_page":  params.Page,
+			"page_size":     params.PageSize,
+			"total_records": total,
+		}})
+}
+
+func (h *OrderHandler) HandleGetOrderById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	order, err := h.store.GetById(restaurantID, orderID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get order")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"order": order})
+}
+
+// HandleAddOrderItem adds a line to an open order, either from the menu by
+// menu_item_id or as a custom item with its own name and price.
+func (h *OrderHandler) HandleAddOrderItem(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	var req addOrderItemRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding add order item request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	params := store.AddOrderItemParams{
+		MenuItemID:  req.MenuItemID,
+		ModifierIDs: req.ModifierIDs,
+		Name:        req.Name,
+		Currency:    req.Currency,
+		Quantity:    1,
+		Seat:        req.Seat,
+		Notes:       req.Notes,
+	}
+	if req.Quantity != nil {
+		params.Quantity = *req.Quantity
+	}
+	if req.PriceMinor != nil {
+		params.PriceMinor = *req.PriceMinor
+	} else if req.MenuItemID == "" {
+		writeInvalid(w, r, "price_minor", "price_minor is required when menu_item_id is not given")
+		return
+	}
+
+	if err := validateOrderItem(&params); err != nil {
+		writeError(w, r, h.logger, err, "validate order item")
+		return
+	}
+
+	item, err := h.store.AddItem(restaurantID, orderID, params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "add order item")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"item": item})
+}
+
+// HandleRemoveOrderItem removes a line and returns the order as it now
+// stands. Lines already sent to the kitchen stay on the order as voided.
+func (h *OrderHandler) HandleRemoveOrderItem(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	itemID, err := utils.GetUrlParams("itemId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid item id")
+		return
+	}
+
+	ticket, err := h.store.RemoveItem(restaurantID, orderID, itemID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "remove order item")
+		return
+	}
+
+	if ticket != nil {
+		h.events.Publish(restaurantID, events.KitchenTicketUpdated, ticket)
+	}
+
+	order, err := h.store.GetById(restaurantID, orderID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get order")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"order": order})
+}
+
+// HandleSetOrderItemSeat moves a line to another seat, or to no seat when
+// seat is null, for splitting the bill by seat.
+func (h *OrderHandler) HandleSetOrderItemSeat(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	itemID, err := utils.GetUrlParams("itemId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid item id")
+		return
+	}
+
+	var req setOrderItemSeatRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding set order item seat request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if err := validateSeat(req.Seat); err != nil {
+		writeError(w, r, h.logger, err, "validate seat")
+		return
+	}
+
+	item, err := h.store.SetItemSeat(restaurantID, orderID, itemID, req.Seat)
+	if err != nil {
+		writeError(w, r, h.logger, err, "set order item seat")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"item": item})
+}
+
+// HandleSendOrder fires every line that has not been sent yet to the
+// kitchen, as one ticket per station, and pushes the tickets to the kitchen
+// screens.
+func (h *OrderHandler) HandleSendOrder(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	sent, tickets, err := h.store.Send(restaurantID, orderID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "send order")
+		return
+	}
+
+	for _, ticket := range tickets {
+		h.events.Publish(restaurantID, events.KitchenTicketFired, ticket)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"items": sent, "tickets": tickets})
+}
+
+func (h *OrderHandler) HandleCloseOrder(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	order, err := h.store.Close(restaurantID, orderID, middleware.GetUser(r).ID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "close order")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"order": order})
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/payments"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"io"
+	"log"
+	"net/http"
+)
+
+// idempotencyKeyHeader carries the client's key for a payment or refund.
+// Sending a request again with the same key, for instance after a timeout,
+// returns the first result instead of charging twice.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+type PaymentHandler struct {
+	logger *log.Logger
+	store  store.PaymentStore
+}
+
+func NewPaymentHandler(logger *log.Logger, paymentStore store.PaymentStore) *PaymentHandler {
+	return &PaymentHandler{
+		logger: logger,
+		store:  paymentStore,
+	}
+}
+
+type takePaymentRequest struct {
+	ShareID     *string `json:"share_id"`
+	Method      string  `json:"method"`
+	Token       string  `json:"token"`
+	AmountMinor int64   `json:"amount_minor"`
+	TipMinor    int64   `json:"tip_minor"`
+	Capture     *bool   `json:"capture"`
+}
+
+type capturePaymentRequest struct {
+	TipMinor *int64 `json:"tip_minor"`
+}
+
+type refundPaymentRequest struct {
+	AmountMinor int64  `json:"amount_minor"`
+	Reason      string `json:"reason"`
+}
+
+func idempotencyKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" || len(key) > 255 {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidRequest, "Idempotency-Key header of at most 255 characters is required")
+		return "", false
+	}
+	return key, true
+}
+
+func validateTakePayment(p *store.TakePaymentParams) error {
+	v := validate.New()
+	v.Check(payments.IsValidMethod(p.Method), "method", "method must be one of cash, card, ewallet")
+	v.Check(p.AmountMinor > 0, "amount_minor", "amount_minor must be greater than 0")
+	v.Check(p.TipMinor >= 0, "tip_minor", "tip_minor must not be negative")
+	v.Check(p.Method == payments.MethodCash || p.Token != "", "token", "token is required for card and e-wallet payments")
+	return v.Err()
+}
+
+// HandleTakePayment records a payment against the final bill of an order.
+// Card and e-wallet payments are captured straight away unless capture is
+// false. A retry with the same Idempotency-Key answers 200 with the payment
+// made the first time.
+func (h *PaymentHandler) HandleTakePayment(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	key, ok := idempotencyKey(w, r)
+	if !ok {
+		return
+	}
+
+	var req takePaymentRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding take payment request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	params := store.TakePaymentParams{
+		IdempotencyKey: key,
+		ShareID:        req.ShareID,
+		Method:         req.Method,
+		Token:          req.Token,
+		AmountMinor:    req.AmountMinor,
+		TipMinor:       req.TipMinor,
+		Capture:        req.Capture == nil || *req.Capture,
+		CreatedBy:      middleware.GetUser(r).ID,
+	}
+
+	if err := validateTakePayment(&params); err != nil {
+		writeError(w, r, h.logger, err, "validate payment")
+		return
+	}
+
+	payment, created, err := h.store.Take(restaurantID, orderID, params)
+	if errors.Is(err, payments.ErrDeclined) && payment != nil {
+		p := utils.NewProblem(http.StatusPaymentRequired, utils.CodePaymentDeclined, err.Error())
+		p.Extensions = map[string]any{"payment": payment}
+		utils.WriteProblem(w, r, p)
+		return
+	}
+	if err != nil {
+		writeError(w, r, h.logger, err, "take payment")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	utils.WriteJSON(w, status, utils.Envelope{"payment": payment})
+}
+
+// HandleListPayments lists the payments of an order's bill with what is
+// left to pay on the bill and on each of its shares.
+func (h *PaymentHandler) HandleListPayments(w http.ResponseWriter, r *http.Request) {
+	restaurantID, orderID, ok := urlIDs(w, r, "orderId")
+	if !ok {
+		return
+	}
+
+	summary, err := h.store.ListForOrder(restaurantID, orderID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list payments")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"payments": summary})
+}
+
+func (h *PaymentHandler) HandleGetPaymentById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, paymentID, ok := urlIDs(w, r, "paymentId")
+	if !ok {
+		return
+	}
+
+	payment, err := h.store.GetById(restaurantID, paymentID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get payment")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"payment": payment})
+}
+
+// HandleCapturePayment takes an authorized payment, optionally with the tip
+// written on the slip.
+func (h *PaymentHandler) HandleCapturePayment(w http.ResponseWriter, r *http.Request) {
+	restaurantID, paymentID, ok := urlIDs(w, r, "paymentId")
+	if !ok {
+		return
+	}
+
+	var req capturePaymentRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Printf("ERROR: decoding capture payment request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.TipMinor != nil && *req.TipMinor < 0 {
+		writeInvalid(w, r, "tip_minor", "tip_minor must not be negative")
+		return
+	}
+
+	payment, err := h.store.Capture(restaurantID, paymentID, req.TipMinor)
+	if err != nil {
+		writeError(w, r, h.logger, err, "capture payment")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"payment": payment})
+}
+
+func (h *PaymentHandler) HandleVoidPayment(w http.ResponseWriter, r *http.Request) {
+	restaurantID, paymentID, ok := urlIDs(w, r, "paymentId")
+	if !ok {
+		return
+	}
+
+	payment, err := h.store.Void(restaurantID, paymentID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "void payment")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"payment": payment})
+}
+
+// HandleRefundPayment gives back part or all of a captured payment. Like
+// payments, refunds are made once per Idempotency-Key.
+func (h *PaymentHandler) HandleRefundPayment(w http.ResponseWriter, r *http.Request) {
+	restaurantID, paymentID, ok := urlIDs(w, r, "paymentId")
+	if !ok {
+		return
+	}
+
+	key, ok := idempotencyKey(w, r)
+	if !ok {
+		return
+	}
+
+	var req refundPaymentRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding refund payment request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.AmountMinor <= 0 {
+		writeInvalid(w, r, "amount_minor", "amount_minor must be greater than 0")
+		return
+	}
+
+	refund, created, err := h.store.Refund(restaurantID, paymentID, store.RefundPaymentParams{
+		IdempotencyKey: key,
+		AmountMinor:    req.AmountMinor,
+		Reason:         req.Reason,
+		CreatedBy:      middleware.GetUser(r).ID,
+	})
+	if err != nil {
+		writeError(w, r, h.logger, err, "refund payment")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	utils.WriteJSON(w, status, utils.Envelope{"refund": refund})
+}
@@ -10,10 +10,10 @@ import (
 
 type PositionHandler struct {
 	logger *log.Logger
-	store  store.PostgresPosition
+	store  store.PositionStore
 }
 
-func NewPositionHandler(logger *log.Logger, positionStore store.PostgresPosition) *PositionHandler {
+func NewPositionHandler(logger *log.Logger, positionStore store.PositionStore) *PositionHandler {
 	return &PositionHandler{
 		logger: logger,
 		store:  positionStore,
@@ -21,7 +21,11 @@ func NewPositionHandler(logger *log.Logger, positionStore store.PostgresPosition
 }
 
 type createPositionRequest struct {
-	Title string
+	Title string `json:"title"`
+}
+
+type updatePositionRequest struct {
+	Title *string `json:"title"`
 }
 
 func (h *PositionHandler) HandleCreatePosition(w http.ResponseWriter, r *http.Request) {
@@ -29,12 +33,12 @@ func (h *PositionHandler) HandleCreatePosition(w http.ResponseWriter, r *http.Re
 	err := json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
 		h.logger.Printf("ERROR: decode body: %v\n", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	if body.Title == "" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "title is required"})
+		writeInvalid(w, r, "title", "title is required")
 		return
 	}
 
@@ -42,49 +46,74 @@ func (h *PositionHandler) HandleCreatePosition(w http.ResponseWriter, r *http.Re
 		Title: body.Title,
 	}
 
-	err = h.store.Create(pos)
+	err = h.store.Create(r.Context(), pos)
 	if err != nil {
-		h.logger.Printf("Error: Create position failed :%v\n", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "create position")
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"position": pos})
 }
 
+func (h *PositionHandler) HandleListPositions(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	params := store.ListPositionParams{
+		Title:    queries.Get("title"),
+		Page:     parseIntOrDefault(queries.Get("page"), 1),
+		PageSize: parseIntOrDefault(queries.Get("page_size"), 10),
+	}
+
+	list, total, err := h.store.List(r.Context(), params)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list positions")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"positions": list,
+		"metadata": map[string]any{
+			"current_page":  params.Page,
+			"page_size":     params.PageSize,
+			"total_records": total,
+		}})
+}
+
 func (h *PositionHandler) HandleUpdatePosition(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.GetIdUrlParams(r)
 
 	if err != nil {
 		h.logger.Printf("ERROR: parse id via params: %v\n", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "id is not valid"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "id is not valid")
 		return
 	}
 
-	var body createPositionRequest
+	var body updatePositionRequest
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
 		h.logger.Printf("ERROR: decode body failed: %v\n", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
 		return
 	}
 
-	pos, err := h.store.GetById(id)
-	if err != nil {
-		h.logger.Printf("ERROR: get position by id failed %v\n", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+	if body.Title != nil && *body.Title == "" {
+		writeInvalid(w, r, "title", "title cannot be empty")
 		return
 	}
 
-	if pos == nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "position does not exits"})
+	pos, err := h.store.GetById(r.Context(), id)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get position")
 		return
 	}
 
-	err = h.store.Update(*pos)
+	if body.Title != nil {
+		pos.Title = *body.Title
+	}
+
+	err = h.store.Update(r.Context(), pos)
 	if err != nil {
-		h.logger.Printf("ERROR: update position: %v\n", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "update position")
 		return
 	}
 
@@ -95,19 +124,31 @@ func (h *PositionHandler) HandleGetPositionById(w http.ResponseWriter, r *http.R
 	id, err := utils.GetIdUrlParams(r)
 	if err != nil {
 		h.logger.Printf("ERROR: parse id via params: %v\n", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "id is not valid")
 		return
 	}
-	pos, err := h.store.GetById(id)
+	pos, err := h.store.GetById(r.Context(), id)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get position")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"position": pos})
+}
+
+func (h *PositionHandler) HandleDeletePosition(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
 	if err != nil {
-		h.logger.Printf("ERROR: cannot get position by id :%v\n", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		h.logger.Printf("ERROR: parse id via params: %v\n", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "id is not valid")
 		return
 	}
-	if pos == nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "position does not exist!"})
+
+	err = h.store.Delete(r.Context(), id)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete position")
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"position": pos})
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
 }
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"net/http"
+	"time"
+)
+
+// HandleGetHours is public: the booking page shows the schedule next to the
+// availability search.
+func (h *RestaurantHandler) HandleGetHours(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	hours, err := h.store.GetHours(r.Context(), id, store.UnscopedAccess)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get restaurant hours")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"hours":    hours,
+		"open_now": hours.IsOpen(time.Now()),
+	})
+}
+
+// HandleUpdateHours replaces the whole schedule. Omitting timezone keeps the
+// current one.
+func (h *RestaurantHandler) HandleUpdateHours(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	scope := middleware.GetScope(r)
+
+	current, err := h.store.GetHours(r.Context(), id, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get restaurant hours")
+		return
+	}
+
+	var hours store.Hours
+	err = json.NewDecoder(r.Body).Decode(&hours)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update hours request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if hours.Timezone == "" {
+		hours.Timezone = current.Timezone
+	}
+	if hours.Weekly == nil {
+		hours.Weekly = []store.OpeningInterval{}
+	}
+	if hours.Closures == nil {
+		hours.Closures = []store.Closure{}
+	}
+
+	if err := hours.Validate(); err != nil {
+		writeError(w, r, h.logger, err, "validate opening hours")
+		return
+	}
+
+	err = h.store.ReplaceHours(r.Context(), id, &hours, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "replace restaurant hours")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"hours":    hours,
+		"open_now": hours.IsOpen(time.Now()),
+	})
+}
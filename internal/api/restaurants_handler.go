@@ -1,14 +1,16 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"htrr-apis/internal/middleware"
 	"htrr-apis/internal/store"
 	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type RestaurantHandler struct {
@@ -24,16 +26,51 @@ func NewRestaurantHandler(logger *log.Logger, store store.RestaurantStore) *Rest
 }
 
 type registerRestaurantRequest struct {
-	Name    string `json:"name"`
-	Address string `json:"address"`
-	Phone   string `json:"phone"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Phone    string `json:"phone"`
+	Timezone string `json:"timezone"`
 }
 
 func (r *registerRestaurantRequest) Validate() error {
-	if r.Name == "" {
-		return errors.New("name is required")
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
 	}
-	return nil
+
+	v := validate.New()
+	v.Required("name", r.Name)
+	v.Length("name", r.Name, 0, 255)
+	v.Phone("phone", r.Phone)
+	v.Check(validTimezone(r.Timezone), "timezone", "timezone must be a valid IANA timezone")
+	return v.Err()
+}
+
+type updateRestaurantRequest struct {
+	Name     *string `json:"name"`
+	Address  *string `json:"address"`
+	IsActive *bool   `json:"is_active"`
+	Phone    *string `json:"phone"`
+	Timezone *string `json:"timezone"`
+}
+
+func (r *updateRestaurantRequest) Validate() error {
+	v := validate.New()
+	if r.Name != nil {
+		v.Required("name", *r.Name)
+		v.Length("name", *r.Name, 0, 255)
+	}
+	if r.Phone != nil {
+		v.Phone("phone", *r.Phone)
+	}
+	if r.Timezone != nil {
+		v.Check(*r.Timezone != "" && validTimezone(*r.Timezone), "timezone", "timezone must be a valid IANA timezone")
+	}
+	return v.Err()
+}
+
+func validTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil
 }
 
 type bulkDeleteRestaurantRequest struct {
@@ -42,25 +79,22 @@ type bulkDeleteRestaurantRequest struct {
 }
 
 func (r *bulkDeleteRestaurantRequest) Validate() error {
-	if len(r.IDs) == 0 {
-		return errors.New("ids array is required and cannot be empty")
-	}
-
-	validStrategies := map[string]bool{
-		"atomic":      true,
-		"partial":     true,
-		"best_effort": true,
-	}
-
 	if r.Strategy == "" {
 		r.Strategy = "atomic"
 	}
 
-	if !validStrategies[r.Strategy] {
-		return errors.New("invalid strategy: must be 'atomic', 'partial', or 'best_effort'")
+	v := validate.New()
+	v.Check(len(r.IDs) > 0, "ids", "ids array is required and cannot be empty")
+	for i, id := range r.IDs {
+		field := fmt.Sprintf("ids[%d]", i)
+		v.Required(field, id)
+		// partial and best_effort report or skip malformed ids themselves
+		if r.Strategy == "atomic" {
+			v.UUID(field, id)
+		}
 	}
-
-	return nil
+	v.OneOf("strategy", r.Strategy, "atomic", "partial", "best_effort")
+	return v.Err()
 }
 
 func (h *RestaurantHandler) HandleCreateRestaurant(w http.ResponseWriter, r *http.Request) {
@@ -68,13 +102,13 @@ func (h *RestaurantHandler) HandleCreateRestaurant(w http.ResponseWriter, r *htt
 	err := json.NewDecoder(r.Body).Decode(&reqBody)
 	if err != nil {
 		h.logger.Printf("ERROR: decoding HandleCreateRestaurant: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	err = reqBody.Validate()
 	if err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		writeError(w, r, h.logger, err, "validate restaurant")
 		return
 	}
 
@@ -83,12 +117,12 @@ func (h *RestaurantHandler) HandleCreateRestaurant(w http.ResponseWriter, r *htt
 		Address:  reqBody.Address,
 		Phone:    reqBody.Phone,
 		IsActive: false,
+		Timezone: reqBody.Timezone,
 	}
 
-	err = h.store.Create(restaurant)
+	err = h.store.Create(r.Context(), restaurant)
 	if err != nil {
-		h.logger.Printf("ERROR: creating failed: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "create restaurant")
 		return
 	}
 
@@ -103,12 +137,12 @@ func (h *RestaurantHandler) HandleSearchRestaurant(w http.ResponseWriter, r *htt
 		Name:     queries.Get("name"),
 		Page:     parseIntOrDefault(queries.Get("page"), 1),
 		PageSize: parseIntOrDefault(queries.Get("page_size"), 10),
+		Scope:    middleware.GetScope(r),
 	}
 
-	list, total, err := h.store.Search(req)
+	list, total, err := h.store.Search(r.Context(), req)
 	if err != nil {
-		h.logger.Printf("ERROR: search failed: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "search restaurants")
 		return
 	}
 
@@ -133,57 +167,53 @@ func (h *RestaurantHandler) HandleGetRestaurantById(w http.ResponseWriter, r *ht
 	paramsId, err := utils.GetIdUrlParams(r)
 	if err != nil {
 		h.logger.Printf("ERROR: GetIdUrlParams %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
 		return
 	}
 
-	restaurant, err := h.store.GetRestaurantById(paramsId)
+	restaurant, err := h.store.GetRestaurantById(r.Context(), paramsId, middleware.GetScope(r))
 	if err != nil {
-		h.logger.Printf("ERROR: GetRestaurantById: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": err.Error()})
+		writeError(w, r, h.logger, err, "get restaurant")
 		return
 	}
 
-	if restaurant == nil {
-		http.NotFound(w, r)
+	hours, err := h.store.GetHours(r.Context(), paramsId, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "get restaurant hours")
 		return
 	}
 
+	openNow := restaurant.IsActive && hours.IsOpen(time.Now())
+	restaurant.OpenNow = &openNow
+
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"restaurant": restaurant})
 }
 
 func (h *RestaurantHandler) HandleUpdateRestaurant(w http.ResponseWriter, r *http.Request) {
 	rId, err := utils.GetIdUrlParams(r)
 	if err != nil {
-		h.logger.Printf("ERROR: GetIdViaUrl %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
 		return
 	}
 
-	existingRestaurant, err := h.store.GetRestaurantById(rId)
-	if err != nil {
-		h.logger.Printf("ERROR: GetRestaurantById %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": err.Error()})
-		return
-	}
+	scope := middleware.GetScope(r)
 
-	if existingRestaurant == nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "cannot find id"})
+	existingRestaurant, err := h.store.GetRestaurantById(r.Context(), rId, scope)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get restaurant")
 		return
 	}
 
-	type updateRestaurantRequest struct {
-		Name     *string `json:"name"`
-		Address  *string `json:"address"`
-		IsActive *bool   `json:"is_active"`
-		Phone    *string `json:"phone"`
-	}
-
 	var rqBody updateRestaurantRequest
 	err = json.NewDecoder(r.Body).Decode(&rqBody)
 	if err != nil {
 		h.logger.Printf("ERROR: decode json failed %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if err := rqBody.Validate(); err != nil {
+		writeError(w, r, h.logger, err, "validate restaurant")
 		return
 	}
 
@@ -199,11 +229,13 @@ func (h *RestaurantHandler) HandleUpdateRestaurant(w http.ResponseWriter, r *htt
 	if rqBody.Phone != nil {
 		existingRestaurant.Phone = *rqBody.Phone
 	}
+	if rqBody.Timezone != nil {
+		existingRestaurant.Timezone = *rqBody.Timezone
+	}
 
-	err = h.store.Update(existingRestaurant)
+	err = h.store.Update(r.Context(), existingRestaurant, scope)
 	if err != nil {
-		h.logger.Printf("ERROR: updateRestaurant %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "update restaurant")
 		return
 	}
 
@@ -213,14 +245,13 @@ func (h *RestaurantHandler) HandleUpdateRestaurant(w http.ResponseWriter, r *htt
 func (h *RestaurantHandler) HandleDeleteRestaurant(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.GetIdUrlParams(r)
 	if err != nil {
-		h.logger.Printf("ERROR: GetIdUrlParams, %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
 		return
 	}
 
-	err = h.store.Delete(id)
-	if err == sql.ErrNoRows {
-		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "not found id"})
+	err = h.store.Delete(r.Context(), id, middleware.GetScope(r))
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete restaurant")
 		return
 	}
 
@@ -233,50 +264,41 @@ func (h *RestaurantHandler) HandleBulkDeleteRestaurants(w http.ResponseWriter, r
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Printf("ERROR: Failed to decode request body, %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	if err := req.Validate(); err != nil {
-		h.logger.Printf("ERROR: Validation failed, %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		writeError(w, r, h.logger, err, "validate bulk delete")
 		return
 	}
 
+	scope := middleware.GetScope(r)
+
 	switch req.Strategy {
 	case "atomic":
-		h.handleAtomicDelete(w, req.IDs)
+		h.handleAtomicDelete(w, r, req.IDs, scope)
 	case "partial":
-		h.handlePartialDelete(w, req.IDs)
+		h.handlePartialDelete(w, r, req.IDs, scope)
 	case "best_effort":
-		h.handleBestEffortDelete(w, req.IDs)
+		h.handleBestEffortDelete(w, r, req.IDs, scope)
 	}
 }
 
-func (h *RestaurantHandler) handleAtomicDelete(w http.ResponseWriter, ids []string) {
-	_, err := h.store.BulkDeleteAtomic(ids)
+func (h *RestaurantHandler) handleAtomicDelete(w http.ResponseWriter, r *http.Request, ids []string, scope store.Scope) {
+	_, err := h.store.BulkDeleteAtomic(r.Context(), ids, scope)
 	if err != nil {
-		if err.Error() == "Invalid id format" {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
-			return
-		}
-		if err == sql.ErrNoRows {
-			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "one or more ids not found"})
-			return
-		}
-		h.logger.Printf("ERROR: BulkDeleteAtomic failed, %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "bulk delete restaurants")
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
 }
 
-func (h *RestaurantHandler) handlePartialDelete(w http.ResponseWriter, ids []string) {
-	result, err := h.store.BulkDeletePartial(ids)
+func (h *RestaurantHandler) handlePartialDelete(w http.ResponseWriter, r *http.Request, ids []string, scope store.Scope) {
+	result, err := h.store.BulkDeletePartial(r.Context(), ids, scope)
 	if err != nil {
-		h.logger.Printf("ERROR: BulkDeletePartial failed, %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "bulk delete restaurants")
 		return
 	}
 
@@ -296,11 +318,10 @@ func (h *RestaurantHandler) handlePartialDelete(w http.ResponseWriter, ids []str
 	})
 }
 
-func (h *RestaurantHandler) handleBestEffortDelete(w http.ResponseWriter, ids []string) {
-	count, err := h.store.BulkDeleteBestEffort(ids)
+func (h *RestaurantHandler) handleBestEffortDelete(w http.ResponseWriter, r *http.Request, ids []string, scope store.Scope) {
+	count, err := h.store.BulkDeleteBestEffort(r.Context(), ids, scope)
 	if err != nil {
-		h.logger.Printf("ERROR: BulkDeleteBestEffort failed, %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "bulk delete restaurants")
 		return
 	}
 
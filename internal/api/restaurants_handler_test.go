@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"htrr-apis/internal/store"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// bulkDeleteStore counts the bulk deletes it receives and treats
+// every well-formed id as an existing restaurant.
+type bulkDeleteStore struct {
+	store.RestaurantStore
+	calls int
+}
+
+func (s *bulkDeleteStore) BulkDeleteAtomic(ctx context.Context, ids []string, scope store.Scope) (int, error) {
+	s.calls++
+	return len(ids), nil
+}
+
+func (s *bulkDeleteStore) BulkDeletePartial(ctx context.Context, ids []string, scope store.Scope) (*store.BulkDeleteResult, error) {
+	s.calls++
+	result := &store.BulkDeleteResult{DeletedIDs: []string{}, FailedIDs: []string{}}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			result.FailedIDs = append(result.FailedIDs, id)
+			result.FailedCount++
+			continue
+		}
+		result.DeletedIDs = append(result.DeletedIDs, id)
+		result.DeletedCount++
+	}
+	return result, nil
+}
+
+func (s *bulkDeleteStore) BulkDeleteBestEffort(ctx context.Context, ids []string, scope store.Scope) (int, error) {
+	s.calls++
+	n := 0
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestHandleBulkDeleteRestaurants(t *testing.T) {
+	const id = "6f1c1e9a-4b8e-4c1e-9d1a-2b3c4d5e6f70"
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCalls  int
+		wantFailed []string
+	}{
+		{
+			name:       "atomic",
+			body:       `{"ids": ["` + id + `"]}`,
+			wantStatus: http.StatusOK,
+			wantCalls:  1,
+		},
+		{
+			name:       "atomic with a malformed id",
+			body:       `{"ids": ["` + id + `", "abc"], "strategy": "atomic"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "partial",
+			body:       `{"ids": ["` + id + `"], "strategy": "partial"}`,
+			wantStatus: http.StatusOK,
+			wantCalls:  1,
+			wantFailed: []string{},
+		},
+		{
+			name:       "partial with a malformed id",
+			body:       `{"ids": ["` + id + `", "abc"], "strategy": "partial"}`,
+			wantStatus: http.StatusPartialContent,
+			wantCalls:  1,
+			wantFailed: []string{"abc"},
+		},
+		{
+			name:       "best_effort with a malformed id",
+			body:       `{"ids": ["` + id + `", "abc"], "strategy": "best_effort"}`,
+			wantStatus: http.StatusOK,
+			wantCalls:  1,
+		},
+		{
+			name:       "partial with an empty id",
+			body:       `{"ids": ["` + id + `", ""], "strategy": "partial"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "no ids",
+			body:       `{"ids": [], "strategy": "partial"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown strategy",
+			body:       `{"ids": ["` + id + `"], "strategy": "all"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &bulkDeleteStore{}
+			h := NewRestaurantHandler(log.New(io.Discard, "", 0), s)
+
+			req := httptest.NewRequest(http.MethodDelete, "/restaurants", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.HandleBulkDeleteRestaurants(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if s.calls != tt.wantCalls {
+				t.Errorf("store called %d times, want %d", s.calls, tt.wantCalls)
+			}
+			if tt.wantFailed == nil {
+				return
+			}
+
+			var body struct {
+				FailedIDs []string `json:"failed_ids"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !slices.Equal(body.FailedIDs, tt.wantFailed) {
+				t.Errorf("failed_ids = %v, want %v", body.FailedIDs, tt.wantFailed)
+			}
+		})
+	}
+}
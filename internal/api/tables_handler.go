@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/events"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+)
+
+type TableHandler struct {
+	logger *log.Logger
+	store  store.TableStore
+	events *events.Bus
+}
+
+func NewTableHandler(logger *log.Logger, tableStore store.TableStore, bus *events.Bus) *TableHandler {
+	return &TableHandler{
+		logger: logger,
+		store:  tableStore,
+		events: bus,
+	}
+}
+
+type createTableRequest struct {
+	TableNumber  string `json:"table_number"`
+	Capacity     int    `json:"capacity"`
+	MinPartySize *int   `json:"min_party_size"`
+	MaxPartySize *int   `json:"max_party_size"`
+}
+
+type updateTableRequest struct {
+	TableNumber  *string `json:"table_number"`
+	Capacity     *int    `json:"capacity"`
+	MinPartySize *int    `json:"min_party_size"`
+	MaxPartySize *int    `json:"max_party_size"`
+}
+
+func validateTable(t *store.Table) error {
+	v := validate.New()
+	v.Required("table_number", t.TableNumber)
+	v.Length("table_number", t.TableNumber, 0, 20)
+	v.Check(t.Capacity > 0, "capacity", "capacity must be greater than 0")
+	v.Check(t.MinPartySize > 0, "min_party_size", "min_party_size must be greater than 0")
+	v.Check(t.MinPartySize <= t.MaxPartySize, "min_party_size", "min_party_size must not be greater than max_party_size")
+	return v.Err()
+}
+
+func (h *TableHandler) HandleCreateTable(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req createTableRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create table request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	table := &store.Table{
+		RestaurantID: restaurantID,
+		TableNumber:  req.TableNumber,
+		Capacity:     req.Capacity,
+		MinPartySize: 1,
+		MaxPartySize: req.Capacity,
+	}
+	if req.MinPartySize != nil {
+		table.MinPartySize = *req.MinPartySize
+	}
+	if req.MaxPartySize != nil {
+		table.MaxPartySize = *req.MaxPartySize
+	}
+
+	if err := validateTable(table); err != nil {
+		writeError(w, r, h.logger, err, "validate table")
+		return
+	}
+
+	err = h.store.Create(table)
+	if err != nil {
+		writeError(w, r, h.logger, err, "create table")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"table": table})
+}
+
+func (h *TableHandler) HandleListTables(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	partySize := parseIntOrDefault(utils.GetURLQuery("party_size", r), 0)
+
+	tables, err := h.store.ListByRestaurant(restaurantID, partySize)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list tables")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tables": tables})
+}
+
+func (h *TableHandler) HandleGetTableById(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	tableID, err := utils.GetUrlParams("tableId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid table id")
+		return
+	}
+
+	table, err := h.store.GetById(restaurantID, tableID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get table")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"table": table})
+}
+
+func (h *TableHandler) HandleUpdateTable(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	tableID, err := utils.GetUrlParams("tableId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid table id")
+		return
+	}
+
+	var req updateTableRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update table request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	table, err := h.store.GetById(restaurantID, tableID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get table")
+		return
+	}
+
+	if req.TableNumber != nil {
+		table.TableNumber = *req.TableNumber
+	}
+	if req.Capacity != nil {
+		table.Capacity = *req.Capacity
+	}
+	if req.MinPartySize != nil {
+		table.MinPartySize = *req.MinPartySize
+	}
+	if req.MaxPartySize != nil {
+		table.MaxPartySize = *req.MaxPartySize
+	}
+
+	if err := validateTable(table); err != nil {
+		writeError(w, r, h.logger, err, "validate table")
+		return
+	}
+
+	err = h.store.Update(table)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update table")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"table": table})
+}
+
+func (h *TableHandler) HandleDeleteTable(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	tableID, err := utils.GetUrlParams("tableId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid table id")
+		return
+	}
+
+	err = h.store.Delete(restaurantID, tableID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "delete table")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "deleted successfully"})
+}
+
+func (h *TableHandler) HandleSetTableStatus(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	tableID, err := utils.GetUrlParams("tableId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid table id")
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding set table status request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if !store.IsValidTableStatus(req.Status) {
+		writeInvalid(w, r, "status", "status must be one of available, reserved, occupied, cleaning, out_of_service")
+		return
+	}
+
+	table, err := h.store.SetStatus(restaurantID, tableID, req.Status)
+	if err != nil {
+		writeError(w, r, h.logger, err, "set table status")
+		return
+	}
+
+	h.events.Publish(table.RestaurantID, events.TableStatusChanged, table)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"table": table})
+}
+
+func (h *TableHandler) HandleGetFloor(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	floor, err := h.store.Floor(restaurantID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "get floor")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tables": floor})
+}
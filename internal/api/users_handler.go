@@ -3,11 +3,13 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"htrr-apis/internal/permissions"
 	"htrr-apis/internal/store"
 	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
 	"log"
 	"net/http"
-	"regexp"
+	"unicode"
 )
 
 type UserHandler struct {
@@ -27,23 +29,48 @@ type registerUserRequest struct {
 	Phone    string `json:"phone"`
 	Username string `json:"username"`
 	Bio      string `json:"bio"`
+	Password string `json:"password"`
 }
 
 func (r *registerUserRequest) validate() error {
-	if r.Username == "" {
-		return errors.New("username is required")
-	}
-	if r.Email == "" {
-		return errors.New("email is required")
+	v := validate.New()
+	v.Required("username", r.Username)
+	v.Length("username", r.Username, 3, 50)
+	v.Required("email", r.Email)
+	v.Email("email", r.Email)
+	v.Length("email", r.Email, 0, 255)
+	v.Required("phone", r.Phone)
+	v.Phone("phone", r.Phone)
+	if err := validatePassword(r.Password); err != nil {
+		v.Add("password", err.Error())
 	}
+	return v.Err()
+}
 
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	if !emailRegex.MatchString(r.Email) {
-		return errors.New("email is not valid")
+// validatePassword enforces the password policy: 8 to 72 bytes (bcrypt only
+// uses the first 72) containing at least one letter and one digit.
+func validatePassword(password string) error {
+	if password == "" {
+		return errors.New("password is required")
+	}
+	if len(password) < 8 {
+		return errors.New("password must be at least 8 characters long")
+	}
+	if len(password) > 72 {
+		return errors.New("password must not be more than 72 bytes long")
 	}
 
-	if len(r.Phone) < 3 {
-		return errors.New("phone is not valid")
+	var hasLetter, hasDigit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLetter(c):
+			hasLetter = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("password must contain at least one letter and one digit")
 	}
 
 	return nil
@@ -54,28 +81,73 @@ func (h *UserHandler) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		h.logger.Printf("ERROR: decoding register request: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid payload"})
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	err = req.validate()
 	if err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		writeError(w, r, h.logger, err, "validate user")
 		return
 	}
 
 	user := &store.User{
-		Email:        req.Email,
-		PasswordHash: "",
+		Email:    req.Email,
+		Username: req.Username,
+		Phone:    req.Phone,
+		Bio:      req.Bio,
+		Role:     permissions.RoleStaff,
+		IsActive: true,
 	}
 
-	err = h.userStore.Create(user)
+	err = user.SetPassword(req.Password)
+	if err != nil {
+		h.logger.Printf("ERROR: hashing password %v", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
 
+	err = h.userStore.Create(r.Context(), user)
 	if err != nil {
-		h.logger.Printf("ERROR: registering user %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		writeError(w, r, h.logger, err, "register user")
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"user": user})
 }
+
+type updateUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *UserHandler) HandleUpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req updateUserRoleRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding update role request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if !permissions.IsValidRole(req.Role) {
+		writeInvalid(w, r, "role", "role is not valid")
+		return
+	}
+
+	err = h.userStore.UpdateRole(r.Context(), id, req.Role)
+	if err != nil {
+		writeError(w, r, h.logger, err, "update user role")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"role":        req.Role,
+		"permissions": permissions.For(req.Role),
+	})
+}
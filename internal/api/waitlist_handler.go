@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"htrr-apis/internal/events"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/utils"
+	"htrr-apis/internal/validate"
+	"log"
+	"net/http"
+)
+
+type WaitlistHandler struct {
+	logger *log.Logger
+	store  store.WaitlistStore
+	events *events.Bus
+}
+
+func NewWaitlistHandler(logger *log.Logger, waitlistStore store.WaitlistStore, bus *events.Bus) *WaitlistHandler {
+	return &WaitlistHandler{
+		logger: logger,
+		store:  waitlistStore,
+		events: bus,
+	}
+}
+
+type createWaitlistEntryRequest struct {
+	CustomerName string `json:"customer_name"`
+	Phone        string `json:"phone"`
+	PartySize    int    `json:"party_size"`
+}
+
+func (r *createWaitlistEntryRequest) validate() error {
+	v := validate.New()
+	v.Check(r.CustomerName != "", "customer_name", "customer_name is required")
+	v.Length("customer_name", r.CustomerName, 0, 255)
+	v.Check(len(r.Phone) <= 20, "phone", "phone must not be more than 20 characters")
+	v.Check(r.PartySize > 0, "party_size", "party_size must be greater than 0")
+	return v.Err()
+}
+
+type seatWaitlistEntryRequest struct {
+	TableID         string `json:"table_id"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// writeQueue answers with the whole queue, since any change moves the
+// positions and estimated waits of the other parties too.
+func (h *WaitlistHandler) writeQueue(w http.ResponseWriter, r *http.Request, restaurantID string, status int, extra utils.Envelope) {
+	list, err := h.store.List(restaurantID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list waitlist")
+		return
+	}
+
+	h.events.Publish(restaurantID, events.WaitlistChanged, list)
+
+	body := utils.Envelope{"waitlist": list}
+	for k, v := range extra {
+		body[k] = v
+	}
+	utils.WriteJSON(w, status, body)
+}
+
+func (h *WaitlistHandler) HandleListWaitlist(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	list, err := h.store.List(restaurantID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "list waitlist")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"waitlist": list})
+}
+
+func (h *WaitlistHandler) HandleCreateWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	var req createWaitlistEntryRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding create waitlist entry request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeError(w, r, h.logger, err, "validate waitlist entry")
+		return
+	}
+
+	entry := &store.WaitlistEntry{
+		RestaurantID: restaurantID,
+		CustomerName: req.CustomerName,
+		Phone:        req.Phone,
+		PartySize:    req.PartySize,
+	}
+
+	err = h.store.Create(entry)
+	if err != nil {
+		writeError(w, r, h.logger, err, "create waitlist entry")
+		return
+	}
+
+	h.writeQueue(w, r, restaurantID, http.StatusCreated, utils.Envelope{"entry_id": entry.ID})
+}
+
+func (h *WaitlistHandler) HandleMoveWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	entryID, err := utils.GetUrlParams("entryId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid entry id")
+		return
+	}
+
+	var req struct {
+		Position int `json:"position"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding move waitlist entry request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.Position <= 0 {
+		writeInvalid(w, r, "position", "position must be greater than 0")
+		return
+	}
+
+	err = h.store.Move(restaurantID, entryID, req.Position)
+	if err != nil {
+		writeError(w, r, h.logger, err, "move waitlist entry")
+		return
+	}
+
+	h.writeQueue(w, r, restaurantID, http.StatusOK, nil)
+}
+
+func (h *WaitlistHandler) HandleRemoveWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	entryID, err := utils.GetUrlParams("entryId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid entry id")
+		return
+	}
+
+	err = h.store.Remove(restaurantID, entryID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "remove waitlist entry")
+		return
+	}
+
+	h.writeQueue(w, r, restaurantID, http.StatusOK, nil)
+}
+
+// HandleSeatWaitlistEntry converts a waiting party into a seated booking on
+// the chosen table.
+func (h *WaitlistHandler) HandleSeatWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := utils.GetIdUrlParams(r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid id")
+		return
+	}
+
+	entryID, err := utils.GetUrlParams("entryId", r)
+	if err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidID, "invalid entry id")
+		return
+	}
+
+	var req seatWaitlistEntryRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decoding seat waitlist entry request: %v", err)
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.TableID == "" {
+		writeInvalid(w, r, "table_id", "table_id is required")
+		return
+	}
+	if req.DurationMinutes == 0 {
+		req.DurationMinutes = defaultBookingDuration
+	}
+	if req.DurationMinutes < 0 || req.DurationMinutes > maxBookingDuration {
+		writeInvalid(w, r, "duration_minutes", "duration_minutes must be between 1 and 720")
+		return
+	}
+
+	entry, booking, table, err := h.store.Seat(r.Context(), store.SeatWaitlistParams{
+		RestaurantID:    restaurantID,
+		EntryID:         entryID,
+		TableID:         req.TableID,
+		DurationMinutes: req.DurationMinutes,
+		ChangedBy:       middleware.GetUser(r).ID,
+	})
+	if err != nil {
+		writeError(w, r, h.logger, err, "seat waitlist entry")
+		return
+	}
+
+	h.events.Publish(restaurantID, events.BookingCreated, booking)
+	h.events.Publish(restaurantID, events.TableStatusChanged, table)
+
+	h.writeQueue(w, r, restaurantID, http.StatusOK, utils.Envelope{
+		"entry":   entry,
+		"booking": booking,
+		"table":   table,
+	})
+}
@@ -1,24 +1,46 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"htrr-apis/internal/api"
+	"htrr-apis/internal/events"
+	"htrr-apis/internal/middleware"
+	"htrr-apis/internal/payments"
 	"htrr-apis/internal/store"
+	"htrr-apis/internal/tokens"
 	"htrr-apis/internal/utils"
 	"htrr-apis/migrations"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Application struct {
-	Logger            *log.Logger
-	DB                *sql.DB
-	UserHandler       *api.UserHandler
-	RestaurantHandler *api.RestaurantHandler
+	Logger              *log.Logger
+	DB                  *sql.DB
+	Middleware          *middleware.UserMiddleware
+	AuthHandler         *api.AuthHandler
+	UserHandler         *api.UserHandler
+	RestaurantHandler   *api.RestaurantHandler
+	PositionHandler     *api.PositionHandler
+	EmployeeHandler     *api.EmployeeHandler
+	TableHandler        *api.TableHandler
+	BookingHandler      *api.BookingHandler
+	AvailabilityHandler *api.AvailabilityHandler
+	EventHandler        *api.EventHandler
+	WaitlistHandler     *api.WaitlistHandler
+	CustomerHandler     *api.CustomerHandler
+	MenuHandler         *api.MenuHandler
+	OrderHandler        *api.OrderHandler
+	KitchenHandler      *api.KitchenHandler
+	BillHandler         *api.BillHandler
+	PaymentHandler      *api.PaymentHandler
 }
 
 func NewApplication() (*Application, error) {
@@ -57,23 +79,130 @@ func NewApplication() (*Application, error) {
 
 	logger := log.New(os.Stdout, "[APP] ", log.Ldate|log.Ltime)
 
-	userHandler := api.NewUserHandler(
-		logger, store.NewPostgresUserStore(pgDB))
+	accessTokenTTL := 15 * time.Minute
+	if v := os.Getenv("JWT_ACCESS_TTL"); v != "" {
+		accessTokenTTL, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_ACCESS_TTL: %w", err)
+		}
+	}
+
+	refreshTokenTTL := 7 * 24 * time.Hour
+	if v := os.Getenv("JWT_REFRESH_TTL"); v != "" {
+		refreshTokenTTL, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_REFRESH_TTL: %w", err)
+		}
+	}
+
+	tokenManager, err := tokens.NewManager(os.Getenv("JWT_SECRET"), accessTokenTTL, refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	userStore := store.NewPostgresUserStore(pgDB)
+
+	err = bootstrapOwner(context.Background(), logger, userStore, os.Getenv("OWNER_EMAIL"), os.Getenv("OWNER_PASSWORD"))
+	if err != nil {
+		return nil, err
+	}
+
+	restaurantStore := store.NewPostgresRestaurantStore(pgDB)
+
+	// stream tickets only need to outlive the page opening its EventSource
+	streamTickets := tokens.NewTicketStore(30 * time.Second)
+
+	userMiddleware := middleware.NewUserMiddleware(logger, userStore, restaurantStore, tokenManager, streamTickets)
 
-	restaurantHandler := api.NewRestaurantHandler(
+	authHandler := api.NewAuthHandler(
 		logger,
-		store.NewPostgresRestaurantStore(pgDB))
+		userStore,
+		store.NewPostgresRefreshTokenStore(pgDB),
+		tokenManager)
+
+	userHandler := api.NewUserHandler(logger, userStore)
+
+	restaurantHandler := api.NewRestaurantHandler(logger, restaurantStore)
+
+	positionHandler := api.NewPositionHandler(logger, store.NewPosition(pgDB))
+
+	employeeHandler := api.NewEmployeeHandler(logger, store.NewPostgresEmployeeStore(pgDB))
+
+	bus := events.NewBus()
+
+	tableStore := store.NewPostgresTableStore(pgDB)
+
+	tableHandler := api.NewTableHandler(logger, tableStore, bus)
+
+	bookingStore := store.NewPostgresBookingStore(pgDB)
+
+	bookingHandler := api.NewBookingHandler(logger, bookingStore, bus)
+
+	availabilityHandler := api.NewAvailabilityHandler(logger, restaurantStore, bookingStore)
+
+	eventHandler := api.NewEventHandler(logger, bus, streamTickets)
+
+	waitlistHandler := api.NewWaitlistHandler(logger, store.NewPostgresWaitlistStore(pgDB), bus)
+
+	customerHandler := api.NewCustomerHandler(logger, store.NewPostgresCustomerStore(pgDB))
+
+	menuHandler := api.NewMenuHandler(logger, store.NewPostgresMenuStore(pgDB), restaurantStore)
+
+	orderHandler := api.NewOrderHandler(logger, store.NewPostgresOrderStore(pgDB), bus)
+
+	kitchenHandler := api.NewKitchenHandler(logger, store.NewPostgresKitchenStore(pgDB), bus)
+
+	billHandler := api.NewBillHandler(logger, store.NewPostgresBillStore(pgDB))
+
+	paymentProvider, err := newPaymentProvider(os.Getenv("PAYMENT_PROVIDER"), os.Getenv("APP_ENV"))
+	if err != nil {
+		return nil, err
+	}
+
+	paymentHandler := api.NewPaymentHandler(logger, store.NewPostgresPaymentStore(pgDB, paymentProvider))
 
 	app := &Application{
-		Logger:            logger,
-		DB:                pgDB,
-		UserHandler:       userHandler,
-		RestaurantHandler: restaurantHandler,
+		Logger:              logger,
+		DB:                  pgDB,
+		Middleware:          userMiddleware,
+		AuthHandler:         authHandler,
+		UserHandler:         userHandler,
+		RestaurantHandler:   restaurantHandler,
+		PositionHandler:     positionHandler,
+		EmployeeHandler:     employeeHandler,
+		TableHandler:        tableHandler,
+		BookingHandler:      bookingHandler,
+		AvailabilityHandler: availabilityHandler,
+		EventHandler:        eventHandler,
+		WaitlistHandler:     waitlistHandler,
+		CustomerHandler:     customerHandler,
+		MenuHandler:         menuHandler,
+		OrderHandler:        orderHandler,
+		KitchenHandler:      kitchenHandler,
+		BillHandler:         billHandler,
+		PaymentHandler:      paymentHandler,
 	}
 
 	return app, nil
 }
 
+// newPaymentProvider picks the card and e-wallet gateway named by
+// PAYMENT_PROVIDER. The fake gateway approves every card without moving
+// money and forgets its authorizations on restart, so it is refused unless
+// APP_ENV says this is a development or test deployment.
+func newPaymentProvider(name, env string) (payments.PaymentProvider, error) {
+	switch name {
+	case "":
+		return nil, errors.New("PAYMENT_PROVIDER is required")
+	case "fake":
+		if env != "development" && env != "test" {
+			return nil, errors.New("PAYMENT_PROVIDER=fake moves no money and is only allowed with APP_ENV=development or APP_ENV=test")
+		}
+		return payments.NewFakeProvider(), nil
+	}
+	return nil, fmt.Errorf("PAYMENT_PROVIDER: unknown payment provider %q", name)
+}
+
 func (a *Application) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Available!!!"})
 }
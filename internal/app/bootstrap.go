@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"htrr-apis/internal/permissions"
+	"htrr-apis/internal/store"
+	"log"
+)
+
+// bootstrapOwner makes sure a fresh deployment has an owner, since only
+// owners may manage users and roles and registration always creates staff.
+// While no OWNER exists, the account named by OWNER_EMAIL is promoted, or
+// created with OWNER_PASSWORD when it does not exist yet. Once any owner
+// exists the settings are ignored, so they can stay in the environment.
+func bootstrapOwner(ctx context.Context, logger *log.Logger, users store.UserStore, email, password string) error {
+	if email == "" {
+		return nil
+	}
+
+	hasOwner, err := users.HasRole(ctx, permissions.RoleOwner)
+	if err != nil {
+		return fmt.Errorf("bootstrap owner: %w", err)
+	}
+	if hasOwner {
+		return nil
+	}
+
+	user, err := users.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if err := users.UpdateRole(ctx, user.ID, permissions.RoleOwner); err != nil {
+			return fmt.Errorf("bootstrap owner: %w", err)
+		}
+		logger.Printf("promoted %s to %s", email, permissions.RoleOwner)
+		return nil
+	case !errors.Is(err, store.ErrNotFound):
+		return fmt.Errorf("bootstrap owner: %w", err)
+	}
+
+	if len(password) < 8 {
+		return errors.New("bootstrap owner: OWNER_PASSWORD of at least 8 characters is required to create " + email)
+	}
+
+	user = &store.User{
+		Email:    email,
+		Role:     permissions.RoleOwner,
+		IsActive: true,
+	}
+	if err := user.SetPassword(password); err != nil {
+		return fmt.Errorf("bootstrap owner: %w", err)
+	}
+	if err := users.Create(ctx, user); err != nil {
+		return fmt.Errorf("bootstrap owner: %w", err)
+	}
+	logger.Printf("created %s as %s", email, permissions.RoleOwner)
+	return nil
+}
@@ -0,0 +1,572 @@
+// Package billing turns the lines of an order into a bill. All amounts are
+// integers in minor units of the bill's currency and all rates are basis
+// points (1/100 of a percent), so no step goes through floating point.
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"htrr-apis/internal/validate"
+)
+
+const (
+	RoundHalfUp = "half_up"
+	RoundUp     = "up"
+	RoundDown   = "down"
+
+	DiscountPercent = "percent"
+	DiscountAmount  = "amount"
+
+	SplitNone = "none"
+	SplitEven = "even"
+	SplitSeat = "seat"
+	SplitItem = "item"
+)
+
+// basisPoints is 100%.
+const basisPoints = 10000
+
+const maxSplitParts = 50
+
+var (
+	ErrEmptyBill = errors.New("order has no billable items")
+	ErrNoSeats   = errors.New("no item of the order has a seat")
+	// ErrUnknownLine wraps the errors of discounts and split groups that do
+	// not match the lines of the bill.
+	ErrUnknownLine = errors.New("bill request does not match the order")
+)
+
+type CategoryTaxRate struct {
+	CategoryID string `json:"category_id"`
+	TaxRateBP  int    `json:"tax_rate_bp"`
+}
+
+// Settings are the billing rules of a restaurant. Prices exclude tax. A
+// category rate replaces the restaurant rate for items of that category.
+// RoundingIncrement rounds the grand total, e.g. 5 for cash rounding to the
+// nearest 0.05; 1 disables it.
+type Settings struct {
+	TaxRateBP            int               `json:"tax_rate_bp"`
+	CategoryTaxRates     []CategoryTaxRate `json:"category_tax_rates"`
+	ServiceChargeBP      int               `json:"service_charge_bp"`
+	ServiceChargeTaxable bool              `json:"service_charge_taxable"`
+	RoundingIncrement    int64             `json:"rounding_increment"`
+	RoundingMode         string            `json:"rounding_mode"`
+}
+
+func (s *Settings) Validate() error {
+	v := validate.New()
+	v.Range("tax_rate_bp", s.TaxRateBP, 0, basisPoints)
+	seen := map[string]bool{}
+	for i, c := range s.CategoryTaxRates {
+		field := fmt.Sprintf("category_tax_rates[%d]", i)
+		v.Range(field+".tax_rate_bp", c.TaxRateBP, 0, basisPoints)
+		v.Check(!seen[c.CategoryID], field+".category_id", fmt.Sprintf("category %s is listed twice", c.CategoryID))
+		seen[c.CategoryID] = true
+	}
+	v.Range("service_charge_bp", s.ServiceChargeBP, 0, basisPoints)
+	v.Check(s.RoundingIncrement >= 1 && s.RoundingIncrement <= 100, "rounding_increment",
+		"rounding_increment must be between 1 and 100")
+	switch s.RoundingMode {
+	case RoundHalfUp, RoundUp, RoundDown:
+	default:
+		v.Add("rounding_mode", "rounding_mode must be one of half_up, up, down")
+	}
+	return v.Err()
+}
+
+func (s *Settings) taxRate(categoryID *string) int {
+	if categoryID != nil {
+		for _, c := range s.CategoryTaxRates {
+			if c.CategoryID == *categoryID {
+				return c.TaxRateBP
+			}
+		}
+	}
+	return s.TaxRateBP
+}
+
+// Line is one billable order line.
+type Line struct {
+	ID             string
+	Name           string
+	CategoryID     *string
+	Seat           *int
+	Quantity       int
+	UnitPriceMinor int64
+}
+
+// Discount takes Value basis points (percent) or Value minor units (amount)
+// off one line, or off the whole bill when LineID is empty.
+type Discount struct {
+	Kind   string `json:"kind"`
+	Value  int64  `json:"value"`
+	LineID string `json:"order_item_id"`
+	Reason string `json:"reason"`
+}
+
+// Split says how the bill is shared between payers. Parts is used by
+// SplitEven, Groups (lists of order item ids) by SplitItem.
+type Split struct {
+	Mode   string     `json:"mode"`
+	Parts  int        `json:"parts"`
+	Groups [][]string `json:"groups"`
+}
+
+// Request is what a server chooses when printing a bill.
+type Request struct {
+	Discounts []Discount `json:"discounts"`
+	Split     Split      `json:"split"`
+}
+
+func (r *Request) Validate() error {
+	v := validate.New()
+	for i, d := range r.Discounts {
+		field := fmt.Sprintf("discounts[%d]", i)
+		switch d.Kind {
+		case DiscountPercent:
+			v.Check(d.Value > 0 && d.Value <= basisPoints, field+".value",
+				"a percent discount value must be between 1 and 10000 basis points")
+		case DiscountAmount:
+			v.Check(d.Value > 0, field+".value", "an amount discount value must be greater than 0")
+		default:
+			v.Add(field+".kind", "discount kind must be percent or amount")
+		}
+	}
+
+	switch r.Split.Mode {
+	case "":
+		r.Split.Mode = SplitNone
+	case SplitNone, SplitSeat:
+	case SplitEven:
+		v.Check(r.Split.Parts >= 2 && r.Split.Parts <= maxSplitParts, "split.parts", "split parts must be between 2 and 50")
+	case SplitItem:
+		v.Check(len(r.Split.Groups) > 0, "split.groups", "split groups are required to split by item")
+	default:
+		v.Add("split.mode", "split mode must be one of none, even, seat, item")
+	}
+	return v.Err()
+}
+
+type BillLine struct {
+	OrderItemID    string `json:"order_item_id"`
+	Name           string `json:"name"`
+	Seat           *int   `json:"seat"`
+	Quantity       int    `json:"quantity"`
+	UnitPriceMinor int64  `json:"unit_price_minor"`
+	SubtotalMinor  int64  `json:"subtotal_minor"`
+	DiscountMinor  int64  `json:"discount_minor"`
+	ServiceMinor   int64  `json:"service_minor"`
+	TaxRateBP      int    `json:"tax_rate_bp"`
+	TaxMinor       int64  `json:"tax_minor"`
+	TotalMinor     int64  `json:"total_minor"`
+}
+
+// TaxLine sums the tax of every line taxed at one rate. Tax is rounded once
+// per rate, as printed on the bill, and then spread over the lines.
+type TaxLine struct {
+	RateBP    int   `json:"rate_bp"`
+	BaseMinor int64 `json:"base_minor"`
+	TaxMinor  int64 `json:"tax_minor"`
+}
+
+// Share is what one payer owes. The shares of a bill always add up to its
+// totals exactly.
+type Share struct {
+	ID            string   `json:"id,omitempty"`
+	Label         string   `json:"label"`
+	Seat          *int     `json:"seat,omitempty"`
+	OrderItemIDs  []string `json:"order_item_ids"`
+	SubtotalMinor int64    `json:"subtotal_minor"`
+	DiscountMinor int64    `json:"discount_minor"`
+	ServiceMinor  int64    `json:"service_minor"`
+	TaxMinor      int64    `json:"tax_minor"`
+	RoundingMinor int64    `json:"rounding_minor"`
+	TotalMinor    int64    `json:"total_minor"`
+}
+
+type Bill struct {
+	Currency           string     `json:"currency"`
+	Lines              []BillLine `json:"lines"`
+	Discounts          []Discount `json:"discounts"`
+	SubtotalMinor      int64      `json:"subtotal_minor"`
+	DiscountMinor      int64      `json:"discount_minor"`
+	ServiceChargeBP    int        `json:"service_charge_bp"`
+	ServiceChargeMinor int64      `json:"service_charge_minor"`
+	Taxes              []TaxLine  `json:"taxes"`
+	TaxMinor           int64      `json:"tax_minor"`
+	RoundingMinor      int64      `json:"rounding_minor"`
+	TotalMinor         int64      `json:"total_minor"`
+	SplitMode          string     `json:"split_mode"`
+	Shares             []Share    `json:"shares"`
+}
+
+// percentOf returns value*bp/10000 rounded half up, for value >= 0.
+func percentOf(value int64, bp int64) int64 {
+	return (value*bp + basisPoints/2) / basisPoints
+}
+
+// roundTo rounds a non-negative total to a multiple of increment.
+func roundTo(total, increment int64, mode string) int64 {
+	if increment <= 1 {
+		return total
+	}
+	rem := total % increment
+	if rem == 0 {
+		return total
+	}
+	switch mode {
+	case RoundUp:
+		return total - rem + increment
+	case RoundDown:
+		return total - rem
+	default:
+		if rem*2 >= increment {
+			return total - rem + increment
+		}
+		return total - rem
+	}
+}
+
+// Allocate splits total into parts proportional to weights using the largest
+// remainder method, so the parts always add up to total. Ties go to the
+// earlier part. With no positive weight the total is split evenly.
+func Allocate(total int64, weights []int64) []int64 {
+	parts := make([]int64, len(weights))
+	if len(weights) == 0 || total == 0 {
+		return parts
+	}
+
+	sign := int64(1)
+	if total < 0 {
+		sign, total = -1, -total
+	}
+
+	var sum int64
+	for _, w := range weights {
+		if w > 0 {
+			sum += w
+		}
+	}
+	if sum == 0 {
+		weights = make([]int64, len(parts))
+		for i := range weights {
+			weights[i] = 1
+		}
+		sum = int64(len(weights))
+	}
+
+	remainders := make([]int64, len(parts))
+	var given int64
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		parts[i] = total * w / sum
+		remainders[i] = total * w % sum
+		given += parts[i]
+	}
+
+	for left := total - given; left > 0; left-- {
+		best := -1
+		for i, r := range remainders {
+			if weights[i] > 0 && (best < 0 || r > remainders[best]) {
+				best = i
+			}
+		}
+		parts[best]++
+		remainders[best] = -1
+	}
+
+	for i := range parts {
+		parts[i] *= sign
+	}
+	return parts
+}
+
+// Calculate prices the lines under the settings. Discounts come off before
+// service and tax; bill-wide discounts and the service charge are spread
+// over the lines in proportion to their value so each line carries its own
+// share into the tax of its rate.
+func Calculate(settings Settings, currency string, lines []Line, req Request) (*Bill, error) {
+	if len(lines) == 0 {
+		return nil, ErrEmptyBill
+	}
+
+	bill := &Bill{
+		Currency:        currency,
+		Lines:           make([]BillLine, len(lines)),
+		Discounts:       req.Discounts,
+		ServiceChargeBP: settings.ServiceChargeBP,
+		Taxes:           []TaxLine{},
+		SplitMode:       req.Split.Mode,
+	}
+	if bill.Discounts == nil {
+		bill.Discounts = []Discount{}
+	}
+
+	index := map[string]int{}
+	for i, l := range lines {
+		index[l.ID] = i
+		bill.Lines[i] = BillLine{
+			OrderItemID:    l.ID,
+			Name:           l.Name,
+			Seat:           l.Seat,
+			Quantity:       l.Quantity,
+			UnitPriceMinor: l.UnitPriceMinor,
+			SubtotalMinor:  l.UnitPriceMinor * int64(l.Quantity),
+			TaxRateBP:      settings.taxRate(l.CategoryID),
+		}
+		bill.SubtotalMinor += bill.Lines[i].SubtotalMinor
+	}
+
+	net := func(i int) int64 {
+		return bill.Lines[i].SubtotalMinor - bill.Lines[i].DiscountMinor
+	}
+
+	// line discounts first, so bill-wide percentages apply to what is left
+	for _, d := range req.Discounts {
+		if d.LineID == "" {
+			continue
+		}
+		i, ok := index[d.LineID]
+		if !ok {
+			return nil, fmt.Errorf("%w: discount refers to unknown order item %s", ErrUnknownLine, d.LineID)
+		}
+		off := d.Value
+		if d.Kind == DiscountPercent {
+			off = percentOf(bill.Lines[i].SubtotalMinor, d.Value)
+		}
+		bill.Lines[i].DiscountMinor += min(off, net(i))
+	}
+
+	for _, d := range req.Discounts {
+		if d.LineID != "" {
+			continue
+		}
+		weights := make([]int64, len(lines))
+		var base int64
+		for i := range lines {
+			weights[i] = net(i)
+			base += weights[i]
+		}
+		off := d.Value
+		if d.Kind == DiscountPercent {
+			off = percentOf(base, d.Value)
+		}
+		for i, part := range Allocate(min(off, base), weights) {
+			bill.Lines[i].DiscountMinor += part
+		}
+	}
+
+	weights := make([]int64, len(lines))
+	var netTotal int64
+	for i := range lines {
+		bill.DiscountMinor += bill.Lines[i].DiscountMinor
+		weights[i] = net(i)
+		netTotal += weights[i]
+	}
+
+	bill.ServiceChargeMinor = percentOf(netTotal, int64(settings.ServiceChargeBP))
+	for i, part := range Allocate(bill.ServiceChargeMinor, weights) {
+		bill.Lines[i].ServiceMinor = part
+	}
+
+	// tax is worked out once per rate and then spread over its lines
+	var rates []int
+	byRate := map[int][]int{}
+	for i, l := range bill.Lines {
+		if _, ok := byRate[l.TaxRateBP]; !ok {
+			rates = append(rates, l.TaxRateBP)
+		}
+		byRate[l.TaxRateBP] = append(byRate[l.TaxRateBP], i)
+	}
+	for _, rate := range rates {
+		members := byRate[rate]
+		bases := make([]int64, len(members))
+		var base int64
+		for n, i := range members {
+			bases[n] = net(i)
+			if settings.ServiceChargeTaxable {
+				bases[n] += bill.Lines[i].ServiceMinor
+			}
+			base += bases[n]
+		}
+		tax := percentOf(base, int64(rate))
+		for n, part := range Allocate(tax, bases) {
+			bill.Lines[members[n]].TaxMinor = part
+		}
+		bill.Taxes = append(bill.Taxes, TaxLine{RateBP: rate, BaseMinor: base, TaxMinor: tax})
+		bill.TaxMinor += tax
+	}
+
+	for i := range bill.Lines {
+		l := &bill.Lines[i]
+		l.TotalMinor = l.SubtotalMinor - l.DiscountMinor + l.ServiceMinor + l.TaxMinor
+	}
+
+	beforeRounding := netTotal + bill.ServiceChargeMinor + bill.TaxMinor
+	bill.TotalMinor = roundTo(beforeRounding, settings.RoundingIncrement, settings.RoundingMode)
+	bill.RoundingMinor = bill.TotalMinor - beforeRounding
+
+	shares, err := split(bill, req.Split, index)
+	if err != nil {
+		return nil, err
+	}
+	bill.Shares = shares
+
+	return bill, nil
+}
+
+// split divides the bill between payers. Every amount of a line is divided
+// between the shares the line belongs to, and the rounding of the bill is
+// divided in proportion to the shares, so the shares add up to the bill.
+func split(bill *Bill, s Split, index map[string]int) ([]Share, error) {
+	var shares []Share
+	// owners lists the shares each line belongs to
+	owners := make([][]int, len(bill.Lines))
+
+	switch s.Mode {
+	case SplitEven:
+		for p := 0; p < s.Parts; p++ {
+			shares = append(shares, Share{Label: fmt.Sprintf("%d/%d", p+1, s.Parts)})
+		}
+		return splitEvenly(bill, shares), nil
+
+	case SplitSeat:
+		seatShare := map[int]int{}
+		var shared []int
+		for i, l := range bill.Lines {
+			if l.Seat == nil {
+				shared = append(shared, i)
+				continue
+			}
+			n, ok := seatShare[*l.Seat]
+			if !ok {
+				seat := *l.Seat
+				n = len(shares)
+				seatShare[seat] = n
+				shares = append(shares, Share{Label: fmt.Sprintf("Seat %d", seat), Seat: &seat})
+			}
+			owners[i] = []int{n}
+		}
+		if len(shares) == 0 {
+			return nil, ErrNoSeats
+		}
+		// lines without a seat, such as a shared bottle, are split between
+		// every seat
+		all := make([]int, len(shares))
+		for n := range all {
+			all[n] = n
+		}
+		for _, i := range shared {
+			owners[i] = all
+		}
+
+	case SplitItem:
+		for g, group := range s.Groups {
+			shares = append(shares, Share{Label: fmt.Sprintf("Group %d", g+1)})
+			for _, id := range group {
+				i, ok := index[id]
+				if !ok {
+					return nil, fmt.Errorf("%w: split group refers to unknown order item %s", ErrUnknownLine, id)
+				}
+				if owners[i] != nil {
+					return nil, fmt.Errorf("%w: order item %s is in more than one split group", ErrUnknownLine, id)
+				}
+				owners[i] = []int{g}
+			}
+		}
+		for i, o := range owners {
+			if o == nil {
+				return nil, fmt.Errorf("%w: order item %s is in no split group", ErrUnknownLine, bill.Lines[i].OrderItemID)
+			}
+		}
+
+	default:
+		shares = []Share{{Label: "Full bill"}}
+		for i := range owners {
+			owners[i] = []int{0}
+		}
+	}
+
+	for n := range shares {
+		shares[n].OrderItemIDs = []string{}
+	}
+
+	// rotating where the odd minor unit of a shared line lands keeps one
+	// payer from collecting all of them
+	turn := 0
+	for i, l := range bill.Lines {
+		members := owners[i]
+		even := func(v int64) []int64 {
+			parts := make([]int64, len(members))
+			size := int64(len(members))
+			for n := range parts {
+				parts[n] = v / size
+			}
+			for n := int64(0); n < v%size; n++ {
+				parts[(int(n)+turn)%len(members)]++
+			}
+			return parts
+		}
+
+		subtotal, discount := even(l.SubtotalMinor), even(l.DiscountMinor)
+		service, tax := even(l.ServiceMinor), even(l.TaxMinor)
+		if len(members) > 1 {
+			turn++
+		}
+		for n, share := range members {
+			sh := &shares[share]
+			sh.OrderItemIDs = append(sh.OrderItemIDs, l.OrderItemID)
+			sh.SubtotalMinor += subtotal[n]
+			sh.DiscountMinor += discount[n]
+			sh.ServiceMinor += service[n]
+			sh.TaxMinor += tax[n]
+		}
+	}
+
+	finishShares(bill, shares)
+	return shares, nil
+}
+
+// splitEvenly gives every share the same part of each bill total.
+func splitEvenly(bill *Bill, shares []Share) []Share {
+	weights := make([]int64, len(shares))
+	for n := range weights {
+		weights[n] = 1
+	}
+
+	subtotal := Allocate(bill.SubtotalMinor, weights)
+	discount := Allocate(bill.DiscountMinor, weights)
+	service := Allocate(bill.ServiceChargeMinor, weights)
+	tax := Allocate(bill.TaxMinor, weights)
+
+	ids := make([]string, len(bill.Lines))
+	for i, l := range bill.Lines {
+		ids[i] = l.OrderItemID
+	}
+
+	for n := range shares {
+		shares[n].OrderItemIDs = ids
+		shares[n].SubtotalMinor = subtotal[n]
+		shares[n].DiscountMinor = discount[n]
+		shares[n].ServiceMinor = service[n]
+		shares[n].TaxMinor = tax[n]
+	}
+
+	finishShares(bill, shares)
+	return shares
+}
+
+// finishShares spreads the bill rounding over the shares and totals them.
+func finishShares(bill *Bill, shares []Share) {
+	weights := make([]int64, len(shares))
+	for n, sh := range shares {
+		weights[n] = sh.SubtotalMinor - sh.DiscountMinor + sh.ServiceMinor + sh.TaxMinor
+	}
+	for n, part := range Allocate(bill.RoundingMinor, weights) {
+		shares[n].RoundingMinor = part
+		shares[n].TotalMinor = weights[n] + part
+	}
+}
@@ -0,0 +1,416 @@
+package billing
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func sum(values []int64) int64 {
+	var total int64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// amounts are the totals of a bill or a share.
+type amounts struct {
+	Subtotal, Discount, Service, Tax, Rounding, Total int64
+}
+
+func billAmounts(b *Bill) amounts {
+	return amounts{b.SubtotalMinor, b.DiscountMinor, b.ServiceChargeMinor, b.TaxMinor, b.RoundingMinor, b.TotalMinor}
+}
+
+// checkShares fails the test unless every amount of the shares adds up to
+// the same amount of the bill.
+func checkShares(t *testing.T, bill *Bill) {
+	t.Helper()
+
+	var got amounts
+	for _, sh := range bill.Shares {
+		got.Subtotal += sh.SubtotalMinor
+		got.Discount += sh.DiscountMinor
+		got.Service += sh.ServiceMinor
+		got.Tax += sh.TaxMinor
+		got.Rounding += sh.RoundingMinor
+		got.Total += sh.TotalMinor
+
+		if want := sh.SubtotalMinor - sh.DiscountMinor + sh.ServiceMinor + sh.TaxMinor + sh.RoundingMinor; sh.TotalMinor != want {
+			t.Errorf("share %s: total %d, want %d", sh.Label, sh.TotalMinor, want)
+		}
+	}
+
+	if want := billAmounts(bill); got != want {
+		t.Errorf("shares add up to %+v, want %+v", got, want)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		{"proportional", 10, []int64{1, 2, 3, 4}, []int64{1, 2, 3, 4}},
+		{"remainder to the largest fraction", 2, []int64{1, 2, 2}, []int64{0, 1, 1}},
+		{"ties go to the earlier part", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"single unit tie", 1, []int64{3, 3, 3}, []int64{1, 0, 0}},
+		{"negative total", -10, []int64{1, 1, 1}, []int64{-4, -3, -3}},
+		{"no positive weight splits evenly", 7, []int64{0, 0}, []int64{4, 3}},
+		{"zero weight gets nothing", 9, []int64{0, 1, 2}, []int64{0, 3, 6}},
+		{"zero total", 0, []int64{1, 2}, []int64{0, 0}},
+		{"single part", 17, []int64{5}, []int64{17}},
+		{"no parts", 5, nil, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.total, tt.weights)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Allocate(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+			}
+			if len(tt.weights) > 0 && sum(got) != tt.total {
+				t.Fatalf("parts add up to %d, want %d", sum(got), tt.total)
+			}
+		})
+	}
+}
+
+func TestRoundTo(t *testing.T) {
+	tests := []struct {
+		total, increment int64
+		mode             string
+		want             int64
+	}{
+		{1234, 1, RoundHalfUp, 1234},
+		{1234, 5, RoundHalfUp, 1235},
+		{1232, 5, RoundHalfUp, 1230},
+		{1235, 5, RoundDown, 1235},
+		{1234, 5, RoundDown, 1230},
+		{1231, 5, RoundUp, 1235},
+		{1250, 100, RoundHalfUp, 1300},
+		{1249, 100, RoundHalfUp, 1200},
+	}
+
+	for _, tt := range tests {
+		if got := roundTo(tt.total, tt.increment, tt.mode); got != tt.want {
+			t.Errorf("roundTo(%d, %d, %s) = %d, want %d", tt.total, tt.increment, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateTaxRates(t *testing.T) {
+	food, drinks := "food", "drinks"
+	settings := Settings{
+		TaxRateBP: 825,
+		CategoryTaxRates: []CategoryTaxRate{
+			{CategoryID: food, TaxRateBP: 1000},
+			{CategoryID: drinks, TaxRateBP: 0},
+		},
+		RoundingIncrement: 1,
+		RoundingMode:      RoundHalfUp,
+	}
+
+	tests := []struct {
+		name      string
+		lines     []Line
+		wantTaxes []TaxLine
+		wantTax   []int64
+	}{
+		{
+			name: "category rate replaces the restaurant rate",
+			lines: []Line{
+				{ID: "a", CategoryID: &food, Quantity: 1, UnitPriceMinor: 1000},
+				{ID: "b", CategoryID: &drinks, Quantity: 2, UnitPriceMinor: 300},
+				{ID: "c", Quantity: 1, UnitPriceMinor: 1000},
+			},
+			wantTaxes: []TaxLine{{1000, 1000, 100}, {0, 600, 0}, {825, 1000, 83}},
+			wantTax:   []int64{100, 0, 83},
+		},
+		{
+			name: "unknown category falls back to the restaurant rate",
+			lines: []Line{
+				{ID: "a", CategoryID: ptr("deleted"), Quantity: 1, UnitPriceMinor: 1000},
+			},
+			wantTaxes: []TaxLine{{825, 1000, 83}},
+			wantTax:   []int64{83},
+		},
+		{
+			// per line each tax is 0.3 and would round to 0
+			name: "tax is rounded once per rate",
+			lines: []Line{
+				{ID: "a", CategoryID: &food, Quantity: 1, UnitPriceMinor: 3},
+				{ID: "b", CategoryID: &food, Quantity: 1, UnitPriceMinor: 3},
+			},
+			wantTaxes: []TaxLine{{1000, 6, 1}},
+			wantTax:   []int64{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bill, err := Calculate(settings, "USD", tt.lines, Request{Split: Split{Mode: SplitNone}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(bill.Taxes, tt.wantTaxes) {
+				t.Errorf("taxes = %+v, want %+v", bill.Taxes, tt.wantTaxes)
+			}
+			var lineTax []int64
+			for _, l := range bill.Lines {
+				lineTax = append(lineTax, l.TaxMinor)
+			}
+			if !slices.Equal(lineTax, tt.wantTax) {
+				t.Errorf("line taxes = %v, want %v", lineTax, tt.wantTax)
+			}
+			if sum(lineTax) != bill.TaxMinor {
+				t.Errorf("line taxes add up to %d, bill tax is %d", sum(lineTax), bill.TaxMinor)
+			}
+			checkShares(t, bill)
+		})
+	}
+}
+
+func TestCalculateTotals(t *testing.T) {
+	lines := []Line{
+		{ID: "a", Seat: ptr(1), Quantity: 2, UnitPriceMinor: 1250},
+		{ID: "b", Seat: ptr(2), Quantity: 1, UnitPriceMinor: 999},
+		{ID: "c", Quantity: 1, UnitPriceMinor: 3001},
+	}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		req      Request
+		want     amounts
+	}{
+		{
+			name:     "no tax or service",
+			settings: Settings{RoundingIncrement: 1, RoundingMode: RoundHalfUp},
+			want:     amounts{Subtotal: 6500, Total: 6500},
+		},
+		{
+			name: "service charge not taxed",
+			settings: Settings{
+				TaxRateBP: 1000, ServiceChargeBP: 1000,
+				RoundingIncrement: 1, RoundingMode: RoundHalfUp,
+			},
+			want: amounts{Subtotal: 6500, Service: 650, Tax: 650, Total: 7800},
+		},
+		{
+			name: "service charge taxed",
+			settings: Settings{
+				TaxRateBP: 1000, ServiceChargeBP: 1000, ServiceChargeTaxable: true,
+				RoundingIncrement: 1, RoundingMode: RoundHalfUp,
+			},
+			want: amounts{Subtotal: 6500, Service: 650, Tax: 715, Total: 7865},
+		},
+		{
+			name: "line and bill discounts",
+			settings: Settings{
+				TaxRateBP: 1000, RoundingIncrement: 1, RoundingMode: RoundHalfUp,
+			},
+			req: Request{Discounts: []Discount{
+				{Kind: DiscountAmount, Value: 500, LineID: "a"},
+				{Kind: DiscountPercent, Value: 1000},
+			}},
+			// 6000 left after the line discount, 600 off that
+			want: amounts{Subtotal: 6500, Discount: 1100, Tax: 540, Total: 5940},
+		},
+		{
+			name: "cash rounding",
+			settings: Settings{
+				TaxRateBP: 825, RoundingIncrement: 5, RoundingMode: RoundHalfUp,
+			},
+			// 6500 + 536 = 7036
+			want: amounts{Subtotal: 6500, Tax: 536, Rounding: -1, Total: 7035},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, mode := range []string{SplitNone, SplitEven, SplitSeat} {
+				req := tt.req
+				req.Split = Split{Mode: mode, Parts: 3}
+
+				bill, err := Calculate(tt.settings, "USD", lines, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := billAmounts(bill); got != tt.want {
+					t.Fatalf("split %s: totals = %+v, want %+v", mode, got, tt.want)
+				}
+				checkShares(t, bill)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	settings := Settings{
+		TaxRateBP: 825, ServiceChargeBP: 1250,
+		RoundingIncrement: 5, RoundingMode: RoundHalfUp,
+	}
+	lines := []Line{
+		{ID: "a", Seat: ptr(1), Quantity: 1, UnitPriceMinor: 1001},
+		{ID: "b", Seat: ptr(2), Quantity: 3, UnitPriceMinor: 333},
+		{ID: "c", Quantity: 1, UnitPriceMinor: 1000},
+		{ID: "d", Seat: ptr(1), Quantity: 1, UnitPriceMinor: 1},
+	}
+
+	tests := []struct {
+		name       string
+		lines      []Line
+		split      Split
+		wantLabels []string
+		wantErr    error
+	}{
+		{
+			name:       "none is one share",
+			lines:      lines,
+			split:      Split{Mode: SplitNone},
+			wantLabels: []string{"Full bill"},
+		},
+		{
+			name:       "even",
+			lines:      lines,
+			split:      Split{Mode: SplitEven, Parts: 3},
+			wantLabels: []string{"1/3", "2/3", "3/3"},
+		},
+		{
+			name:       "even into more parts than minor units",
+			lines:      []Line{{ID: "a", Quantity: 1, UnitPriceMinor: 1}},
+			split:      Split{Mode: SplitEven, Parts: 7},
+			wantLabels: []string{"1/7", "2/7", "3/7", "4/7", "5/7", "6/7", "7/7"},
+		},
+		{
+			name:       "seat shares lines without a seat",
+			lines:      lines,
+			split:      Split{Mode: SplitSeat},
+			wantLabels: []string{"Seat 1", "Seat 2"},
+		},
+		{
+			name:    "seat without any seat",
+			lines:   []Line{{ID: "a", Quantity: 1, UnitPriceMinor: 100}},
+			split:   Split{Mode: SplitSeat},
+			wantErr: ErrNoSeats,
+		},
+		{
+			name:       "item",
+			lines:      lines,
+			split:      Split{Mode: SplitItem, Groups: [][]string{{"a", "c"}, {"b", "d"}}},
+			wantLabels: []string{"Group 1", "Group 2"},
+		},
+		{
+			name:    "item with an unknown line",
+			lines:   lines,
+			split:   Split{Mode: SplitItem, Groups: [][]string{{"a", "b", "c", "d", "x"}}},
+			wantErr: ErrUnknownLine,
+		},
+		{
+			name:    "item with a line in no group",
+			lines:   lines,
+			split:   Split{Mode: SplitItem, Groups: [][]string{{"a", "b"}, {"c"}}},
+			wantErr: ErrUnknownLine,
+		},
+		{
+			name:    "item with a line in two groups",
+			lines:   lines,
+			split:   Split{Mode: SplitItem, Groups: [][]string{{"a", "b", "c"}, {"c", "d"}}},
+			wantErr: ErrUnknownLine,
+		},
+		{
+			name:       "zero total",
+			lines:      []Line{{ID: "a", Seat: ptr(1), Quantity: 2, UnitPriceMinor: 0}},
+			split:      Split{Mode: SplitEven, Parts: 2},
+			wantLabels: []string{"1/2", "2/2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bill, err := Calculate(settings, "USD", tt.lines, Request{Split: tt.split})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var labels []string
+			for _, sh := range bill.Shares {
+				labels = append(labels, sh.Label)
+			}
+			if !slices.Equal(labels, tt.wantLabels) {
+				t.Fatalf("shares = %v, want %v", labels, tt.wantLabels)
+			}
+			checkShares(t, bill)
+		})
+	}
+}
+
+func TestSplitSharedLineRotatesOddUnits(t *testing.T) {
+	settings := Settings{RoundingIncrement: 1, RoundingMode: RoundHalfUp}
+	lines := []Line{
+		{ID: "a", Seat: ptr(1), Quantity: 1, UnitPriceMinor: 100},
+		{ID: "b", Seat: ptr(2), Quantity: 1, UnitPriceMinor: 100},
+		{ID: "c", Quantity: 1, UnitPriceMinor: 1},
+		{ID: "d", Quantity: 1, UnitPriceMinor: 1},
+	}
+
+	bill, err := Calculate(settings, "USD", lines, Request{Split: Split{Mode: SplitSeat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bill.Shares[0].TotalMinor != 101 || bill.Shares[1].TotalMinor != 101 {
+		t.Fatalf("share totals = %d, %d, want 101 each", bill.Shares[0].TotalMinor, bill.Shares[1].TotalMinor)
+	}
+	checkShares(t, bill)
+}
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     Request
+		wantErr bool
+	}{
+		{"empty mode defaults to none", Request{}, false},
+		{"even in two", Request{Split: Split{Mode: SplitEven, Parts: 2}}, false},
+		{"even in zero", Request{Split: Split{Mode: SplitEven, Parts: 0}}, true},
+		{"even in one", Request{Split: Split{Mode: SplitEven, Parts: 1}}, true},
+		{"even in too many", Request{Split: Split{Mode: SplitEven, Parts: maxSplitParts + 1}}, true},
+		{"item without groups", Request{Split: Split{Mode: SplitItem}}, true},
+		{"unknown mode", Request{Split: Split{Mode: "random"}}, true},
+		{"percent over 100%", Request{Discounts: []Discount{{Kind: DiscountPercent, Value: basisPoints + 1}}}, true},
+		{"zero amount", Request{Discounts: []Discount{{Kind: DiscountAmount, Value: 0}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalculateEmptyBill(t *testing.T) {
+	_, err := Calculate(Settings{RoundingIncrement: 1}, "USD", nil, Request{})
+	if !errors.Is(err, ErrEmptyBill) {
+		t.Fatalf("err = %v, want %v", err, ErrEmptyBill)
+	}
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	TableStatusChanged   = "table.status_changed"
+	BookingCreated       = "booking.created"
+	BookingUpdated       = "booking.updated"
+	BookingStatusChanged = "booking.status_changed"
+	BookingCancelled     = "booking.cancelled"
+	BookingDeleted       = "booking.deleted"
+	WaitlistChanged      = "waitlist.changed"
+	KitchenTicketFired   = "kitchen.ticket_fired"
+	KitchenTicketUpdated = "kitchen.ticket_updated"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events to it are dropped.
+const subscriberBuffer = 64
+
+type Event struct {
+	ID           uint64    `json:"id"`
+	Type         string    `json:"type"`
+	RestaurantID string    `json:"restaurant_id"`
+	Data         any       `json:"data"`
+	At           time.Time `json:"at"`
+}
+
+// Bus fans events out to the subscribers of the restaurant they belong to.
+// It lives in process memory, so only clients connected to this instance
+// receive them.
+type Bus struct {
+	mu     sync.Mutex
+	nextID uint64
+	subs   map[string]map[chan Event]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: map[string]map[chan Event]struct{}{},
+	}
+}
+
+// Subscribe returns a channel of the events of one restaurant and a function
+// that ends the subscription and closes the channel.
+func (b *Bus) Subscribe(restaurantID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[restaurantID] == nil {
+		b.subs[restaurantID] = map[chan Event]struct{}{}
+	}
+	b.subs[restaurantID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[restaurantID], ch)
+			if len(b.subs[restaurantID]) == 0 {
+				delete(b.subs, restaurantID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// Publish never blocks: a subscriber whose buffer is full misses the event
+// and is expected to resynchronise by refetching.
+func (b *Bus) Publish(restaurantID, eventType string, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	e := Event{
+		ID:           b.nextID,
+		Type:         eventType,
+		RestaurantID: restaurantID,
+		Data:         data,
+		At:           time.Now().UTC(),
+	}
+
+	for ch := range b.subs[restaurantID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
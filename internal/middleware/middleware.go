@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"htrr-apis/internal/permissions"
+	"htrr-apis/internal/store"
+	"htrr-apis/internal/tokens"
+	"htrr-apis/internal/utils"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type contextKey string
+
+const (
+	userContextKey  = contextKey("user")
+	scopeContextKey = contextKey("scope")
+)
+
+// AnonymousUser is put into the request context when no token was sent.
+var AnonymousUser = &store.User{}
+
+type UserMiddleware struct {
+	logger          *log.Logger
+	userStore       store.UserStore
+	restaurantStore store.RestaurantStore
+	tokens          *tokens.Manager
+	tickets         *tokens.TicketStore
+}
+
+func NewUserMiddleware(logger *log.Logger, userStore store.UserStore, restaurantStore store.RestaurantStore, tokenManager *tokens.Manager, tickets *tokens.TicketStore) *UserMiddleware {
+	return &UserMiddleware{
+		logger:          logger,
+		userStore:       userStore,
+		restaurantStore: restaurantStore,
+		tokens:          tokenManager,
+		tickets:         tickets,
+	}
+}
+
+func SetUser(r *http.Request, user *store.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// GetUser returns the user resolved by Authenticate, or AnonymousUser.
+func GetUser(r *http.Request) *store.User {
+	user, ok := r.Context().Value(userContextKey).(*store.User)
+	if !ok {
+		return AnonymousUser
+	}
+	return user
+}
+
+func IsAnonymous(user *store.User) bool {
+	return user == AnonymousUser
+}
+
+func SetScope(r *http.Request, scope store.Scope) *http.Request {
+	ctx := context.WithValue(r.Context(), scopeContextKey, scope)
+	return r.WithContext(ctx)
+}
+
+// GetScope returns the restaurant scope resolved by LoadScope. A route
+// that misses LoadScope gets the zero scope, which matches no restaurant.
+func GetScope(r *http.Request) store.Scope {
+	scope, ok := r.Context().Value(scopeContextKey).(store.Scope)
+	if !ok {
+		return store.Scope{}
+	}
+	return scope
+}
+
+// Authenticate validates the bearer token, if any, and stores the user it
+// belongs to in the request context.
+func (um *UserMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next.ServeHTTP(w, SetUser(r, AnonymousUser))
+			return
+		}
+
+		scheme, token, found := strings.Cut(authHeader, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			unauthorized(w, r, "invalid authorization header")
+			return
+		}
+
+		claims, err := um.tokens.Parse(token)
+		if errors.Is(err, tokens.ErrExpiredToken) {
+			unauthorized(w, r, "token has expired")
+			return
+		}
+		if err != nil {
+			unauthorized(w, r, "invalid token")
+			return
+		}
+
+		user, err := um.userStore.GetById(r.Context(), claims.Subject)
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrInvalidID) {
+			unauthorized(w, r, "invalid token")
+			return
+		}
+		if err != nil {
+			um.logger.Printf("ERROR: Authenticate get user by id: %v", err)
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+			return
+		}
+
+		if !user.IsActive {
+			unauthorized(w, r, "invalid token")
+			return
+		}
+
+		next.ServeHTTP(w, SetUser(r, user))
+	})
+}
+
+// AuthenticateStreamTicket lets requests without a bearer token
+// authenticate with a stream ticket in the `ticket` query param, for
+// browsers' EventSource. The ticket must have been issued for the
+// restaurant named by the URL param; it is consumed on first use.
+func (um *UserMiddleware) AuthenticateStreamTicket(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ticket := r.URL.Query().Get("ticket")
+			if ticket == "" || !IsAnonymous(GetUser(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, err := um.tickets.Redeem(ticket, chi.URLParam(r, param))
+			if errors.Is(err, tokens.ErrExpiredToken) {
+				unauthorized(w, r, "ticket has expired")
+				return
+			}
+			if err != nil {
+				unauthorized(w, r, "invalid ticket")
+				return
+			}
+
+			user, err := um.userStore.GetById(r.Context(), userID)
+			if errors.Is(err, store.ErrNotFound) {
+				unauthorized(w, r, "invalid ticket")
+				return
+			}
+			if err != nil {
+				um.logger.Printf("ERROR: AuthenticateStreamTicket get user by id: %v", err)
+				utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+				return
+			}
+
+			if !user.IsActive {
+				unauthorized(w, r, "invalid ticket")
+				return
+			}
+
+			next.ServeHTTP(w, SetUser(r, user))
+		})
+	}
+}
+
+// RequireUser rejects requests that were not authenticated.
+func (um *UserMiddleware) RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsAnonymous(GetUser(r)) {
+			unauthorized(w, r, "you must be logged in to access this resource")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission rejects anonymous requests with 401 and requests whose
+// user role is not granted the permission with 403.
+func (um *UserMiddleware) RequirePermission(permission permissions.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+			if IsAnonymous(user) {
+				unauthorized(w, r, "you must be logged in to access this resource")
+				return
+			}
+
+			if !permissions.Has(user.Role, permission) {
+				forbidden(w, r, permission)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LoadScope resolves which restaurants the authenticated user may access.
+// Owners see every restaurant, everyone else only the restaurants they are
+// employed at. It must run after RequireUser.
+func (um *UserMiddleware) LoadScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUser(r)
+		if user.Role == permissions.RoleOwner {
+			next.ServeHTTP(w, SetScope(r, store.UnscopedAccess))
+			return
+		}
+
+		ids, err := um.restaurantStore.ListIDsForEmployee(r.Context(), user.ID)
+		if err != nil {
+			um.logger.Printf("ERROR: LoadScope ListIDsForEmployee: %v", err)
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+			return
+		}
+
+		next.ServeHTTP(w, SetScope(r, store.Scope{RestaurantIDs: ids}))
+	})
+}
+
+// RequireRestaurantAccess guards routes nested under a restaurant, reading
+// the restaurant id from the named URL param. Restaurants outside the scope
+// are reported as not found so their existence is not leaked.
+func (um *UserMiddleware) RequireRestaurantAccess(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !GetScope(r).Allows(chi.URLParam(r, param)) {
+				utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "restaurant not found")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request, permission permissions.Permission) {
+	p := utils.NewProblem(http.StatusForbidden, utils.CodeForbidden, "you do not have permission to access this resource")
+	p.Extensions = map[string]any{"required_permission": permission}
+	utils.WriteProblem(w, r, p)
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, message)
+}
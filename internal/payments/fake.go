@@ -0,0 +1,205 @@
+package payments
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	OpAuthorize = "authorize"
+	OpCapture   = "capture"
+	OpVoid      = "void"
+	OpRefund    = "refund"
+)
+
+// DeclineToken is declined by FakeProvider on every authorization, the way
+// gateways publish test cards that always fail.
+const DeclineToken = "tok_decline"
+
+// tipAllowanceBP is how much more than it authorized FakeProvider lets a
+// capture take, in basis points, so a tip added on the slip can be captured
+// without a new authorization.
+const tipAllowanceBP = 2500
+
+type fakeAuthorization struct {
+	amountMinor   int64
+	capturedMinor int64
+	refundedMinor int64
+	captured      bool
+	voided        bool
+}
+
+// FakeProvider is an in-process PaymentProvider. It keeps its state in
+// memory and numbers its references in order, so the same calls always give
+// the same results. Failures are injected with FailNext and DeclineToken.
+type FakeProvider struct {
+	mu       sync.Mutex
+	seq      int
+	auths    map[string]*fakeAuthorization
+	keys     map[string]Result
+	failures map[string][]error
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		auths:    map[string]*fakeAuthorization{},
+		keys:     map[string]Result{},
+		failures: map[string][]error{},
+	}
+}
+
+// FailNext makes the next call of the operation fail with err, without
+// changing any state. Calls queue up: FailNext twice fails two calls.
+func (f *FakeProvider) FailNext(op string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[op] = append(f.failures[op], err)
+}
+
+// injected pops the next failure queued for op. The caller holds f.mu.
+func (f *FakeProvider) injected(op string) error {
+	queue := f.failures[op]
+	if len(queue) == 0 {
+		return nil
+	}
+	f.failures[op] = queue[1:]
+	return queue[0]
+}
+
+// reference issues the next reference. The caller holds f.mu.
+func (f *FakeProvider) reference(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("fake_%s_%06d", prefix, f.seq)
+}
+
+func (f *FakeProvider) Authorize(req AuthorizeRequest) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.injected(OpAuthorize); err != nil {
+		return Result{}, err
+	}
+
+	if req.IdempotencyKey != "" {
+		if res, ok := f.keys[OpAuthorize+":"+req.IdempotencyKey]; ok {
+			return res, nil
+		}
+	}
+
+	if req.AmountMinor <= 0 || strings.HasPrefix(req.Token, DeclineToken) {
+		return Result{}, ErrDeclined
+	}
+
+	res := Result{Reference: f.reference("auth")}
+	f.auths[res.Reference] = &fakeAuthorization{amountMinor: req.AmountMinor}
+	if req.IdempotencyKey != "" {
+		f.keys[OpAuthorize+":"+req.IdempotencyKey] = res
+	}
+
+	return res, nil
+}
+
+// Capture takes money from an authorization once. Capturing the same
+// authorization again with the same amount, or repeating the capture with
+// the same idempotency key, returns the same result.
+func (f *FakeProvider) Capture(reference, idempotencyKey string, amountMinor int64) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.injected(OpCapture); err != nil {
+		return Result{}, err
+	}
+
+	if idempotencyKey != "" {
+		if res, ok := f.keys[OpCapture+":"+idempotencyKey]; ok {
+			if res.Reference != reference || f.auths[reference].capturedMinor != amountMinor {
+				return Result{}, ErrInvalidAmount
+			}
+			return res, nil
+		}
+	}
+
+	a, ok := f.auths[reference]
+	if !ok || a.voided {
+		return Result{}, ErrUnknownReference
+	}
+
+	if a.captured {
+		if a.capturedMinor != amountMinor {
+			return Result{}, ErrInvalidAmount
+		}
+		return Result{Reference: reference}, nil
+	}
+
+	limit := a.amountMinor + a.amountMinor*tipAllowanceBP/10000
+	if amountMinor <= 0 || amountMinor > limit {
+		return Result{}, ErrInvalidAmount
+	}
+
+	a.captured = true
+	a.capturedMinor = amountMinor
+
+	res := Result{Reference: reference}
+	if idempotencyKey != "" {
+		f.keys[OpCapture+":"+idempotencyKey] = res
+	}
+
+	return res, nil
+}
+
+// Void releases an authorization that was not captured. Voiding twice is
+// not an error.
+func (f *FakeProvider) Void(reference string) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.injected(OpVoid); err != nil {
+		return Result{}, err
+	}
+
+	a, ok := f.auths[reference]
+	if !ok || a.captured {
+		return Result{}, ErrUnknownReference
+	}
+
+	a.voided = true
+
+	return Result{Reference: reference}, nil
+}
+
+// Refund gives back part or all of a capture. A refund repeated with the
+// same idempotency key returns the first one.
+func (f *FakeProvider) Refund(reference, idempotencyKey string, amountMinor int64) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.injected(OpRefund); err != nil {
+		return Result{}, err
+	}
+
+	if idempotencyKey != "" {
+		if res, ok := f.keys[OpRefund+":"+idempotencyKey]; ok {
+			return res, nil
+		}
+	}
+
+	a, ok := f.auths[reference]
+	if !ok || !a.captured {
+		return Result{}, ErrUnknownReference
+	}
+
+	if amountMinor <= 0 || a.refundedMinor+amountMinor > a.capturedMinor {
+		return Result{}, ErrInvalidAmount
+	}
+
+	a.refundedMinor += amountMinor
+
+	res := Result{Reference: f.reference("refund")}
+	if idempotencyKey != "" {
+		f.keys[OpRefund+":"+idempotencyKey] = res
+	}
+
+	return res, nil
+}
@@ -0,0 +1,320 @@
+package payments
+
+import (
+	"errors"
+	"testing"
+)
+
+// authorized returns the reference of a new authorization of amountMinor.
+func authorized(t *testing.T, f *FakeProvider, key string, amountMinor int64) string {
+	t.Helper()
+
+	res, err := f.Authorize(AuthorizeRequest{
+		IdempotencyKey: key,
+		Method:         MethodCard,
+		Token:          "tok_visa",
+		AmountMinor:    amountMinor,
+		Currency:       "USD",
+	})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return res.Reference
+}
+
+func TestFakeAuthorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		amount  int64
+		wantErr error
+	}{
+		{name: "approved", token: "tok_visa", amount: 1000},
+		{name: "decline token", token: DeclineToken, amount: 1000, wantErr: ErrDeclined},
+		{name: "decline token with suffix", token: DeclineToken + "_insufficient_funds", amount: 1000, wantErr: ErrDeclined},
+		{name: "zero amount", token: "tok_visa", amount: 0, wantErr: ErrDeclined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFakeProvider()
+			res, err := f.Authorize(AuthorizeRequest{
+				IdempotencyKey: "key",
+				Method:         MethodCard,
+				Token:          tt.token,
+				AmountMinor:    tt.amount,
+				Currency:       "USD",
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(f.auths) != 0 {
+					t.Errorf("declined authorization was kept")
+				}
+				return
+			}
+			if res.Reference == "" {
+				t.Errorf("no reference")
+			}
+		})
+	}
+}
+
+func TestFakeAuthorizeRetry(t *testing.T) {
+	f := NewFakeProvider()
+	f.FailNext(OpAuthorize, ErrUnavailable)
+
+	req := AuthorizeRequest{IdempotencyKey: "r1:k1", Method: MethodCard, Token: "tok_visa", AmountMinor: 1000, Currency: "USD"}
+	_, err := f.Authorize(req)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("first call: err = %v, want %v", err, ErrUnavailable)
+	}
+	if len(f.auths) != 0 {
+		t.Fatalf("failed call authorized %d times", len(f.auths))
+	}
+
+	first, err := f.Authorize(req)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	again, err := f.Authorize(req)
+	if err != nil {
+		t.Fatalf("repeat: %v", err)
+	}
+
+	if again != first {
+		t.Errorf("repeat returned %q, want %q", again.Reference, first.Reference)
+	}
+	if len(f.auths) != 1 {
+		t.Errorf("authorized %d times, want 1", len(f.auths))
+	}
+
+	req.IdempotencyKey = "r1:k2"
+	other, err := f.Authorize(req)
+	if err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	if other == first {
+		t.Errorf("new key returned the first authorization")
+	}
+}
+
+func TestFakeCapture(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup runs against an authorization of 1000 and returns the
+		// reference to capture
+		setup   func(f *FakeProvider, ref string) string
+		amount  int64
+		wantErr error
+	}{
+		{name: "authorized amount", amount: 1000},
+		{name: "less than authorized", amount: 400},
+		{name: "tip within allowance", amount: 1250},
+		{name: "tip beyond allowance", amount: 1251, wantErr: ErrInvalidAmount},
+		{name: "zero", amount: 0, wantErr: ErrInvalidAmount},
+		{
+			name:    "unknown reference",
+			setup:   func(f *FakeProvider, ref string) string { return "fake_auth_unknown" },
+			amount:  1000,
+			wantErr: ErrUnknownReference,
+		},
+		{
+			name: "voided",
+			setup: func(f *FakeProvider, ref string) string {
+				f.Void(ref)
+				return ref
+			},
+			amount:  1000,
+			wantErr: ErrUnknownReference,
+		},
+		{
+			name: "captured again with the same amount",
+			setup: func(f *FakeProvider, ref string) string {
+				f.Capture(ref, "", 1000)
+				return ref
+			},
+			amount: 1000,
+		},
+		{
+			name: "captured again with another amount",
+			setup: func(f *FakeProvider, ref string) string {
+				f.Capture(ref, "", 1000)
+				return ref
+			},
+			amount:  1100,
+			wantErr: ErrInvalidAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFakeProvider()
+			ref := authorized(t, f, "auth", 1000)
+			if tt.setup != nil {
+				ref = tt.setup(f, ref)
+			}
+
+			res, err := f.Capture(ref, "capture", tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && res.Reference != ref {
+				t.Errorf("reference = %q, want %q", res.Reference, ref)
+			}
+		})
+	}
+}
+
+func TestFakeCaptureRetry(t *testing.T) {
+	f := NewFakeProvider()
+	ref := authorized(t, f, "auth", 1000)
+	f.FailNext(OpCapture, ErrUnavailable)
+
+	_, err := f.Capture(ref, "capture", 1200)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("first call: err = %v, want %v", err, ErrUnavailable)
+	}
+	if f.auths[ref].captured {
+		t.Fatalf("failed call captured")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.Capture(ref, "capture", 1200); err != nil {
+			t.Fatalf("call %d: %v", i+2, err)
+		}
+	}
+	if got := f.auths[ref].capturedMinor; got != 1200 {
+		t.Errorf("captured %d, want 1200", got)
+	}
+
+	_, err = f.Capture(ref, "capture", 1000)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("same key with another amount: err = %v, want %v", err, ErrInvalidAmount)
+	}
+}
+
+func TestFakeVoid(t *testing.T) {
+	f := NewFakeProvider()
+	ref := authorized(t, f, "auth", 1000)
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.Void(ref); err != nil {
+			t.Fatalf("void %d: %v", i+1, err)
+		}
+	}
+
+	captured := authorized(t, f, "auth2", 1000)
+	if _, err := f.Capture(captured, "", 1000); err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if _, err := f.Void(captured); !errors.Is(err, ErrUnknownReference) {
+		t.Errorf("void of a capture: err = %v, want %v", err, ErrUnknownReference)
+	}
+}
+
+func TestFakeRefund(t *testing.T) {
+	type refund struct {
+		key     string
+		amount  int64
+		wantErr error
+	}
+
+	tests := []struct {
+		name         string
+		refunds      []refund
+		wantRefunded int64
+	}{
+		{
+			name:         "full",
+			refunds:      []refund{{key: "a", amount: 1200}},
+			wantRefunded: 1200,
+		},
+		{
+			name:         "partial",
+			refunds:      []refund{{key: "a", amount: 500}, {key: "b", amount: 700}},
+			wantRefunded: 1200,
+		},
+		{
+			name:         "more than captured",
+			refunds:      []refund{{key: "a", amount: 1201, wantErr: ErrInvalidAmount}},
+			wantRefunded: 0,
+		},
+		{
+			name:         "partial then more than is left",
+			refunds:      []refund{{key: "a", amount: 1000}, {key: "b", amount: 201, wantErr: ErrInvalidAmount}},
+			wantRefunded: 1000,
+		},
+		{
+			name:         "repeated key counts once",
+			refunds:      []refund{{key: "a", amount: 800}, {key: "a", amount: 800}, {key: "b", amount: 400}},
+			wantRefunded: 1200,
+		},
+		{
+			name:         "zero",
+			refunds:      []refund{{key: "a", amount: 0, wantErr: ErrInvalidAmount}},
+			wantRefunded: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFakeProvider()
+			ref := authorized(t, f, "auth", 1000)
+			if _, err := f.Capture(ref, "", 1200); err != nil {
+				t.Fatalf("Capture: %v", err)
+			}
+
+			for _, r := range tt.refunds {
+				_, err := f.Refund(ref, r.key, r.amount)
+				if !errors.Is(err, r.wantErr) {
+					t.Errorf("refund %s of %d: err = %v, want %v", r.key, r.amount, err, r.wantErr)
+				}
+			}
+
+			if got := f.auths[ref].refundedMinor; got != tt.wantRefunded {
+				t.Errorf("refunded %d, want %d", got, tt.wantRefunded)
+			}
+		})
+	}
+}
+
+func TestFakeRefundRetry(t *testing.T) {
+	f := NewFakeProvider()
+	ref := authorized(t, f, "auth", 1000)
+	if _, err := f.Capture(ref, "", 1000); err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	f.FailNext(OpRefund, ErrUnavailable)
+
+	if _, err := f.Refund(ref, "r1", 300); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("first call: err = %v, want %v", err, ErrUnavailable)
+	}
+
+	first, err := f.Refund(ref, "r1", 300)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	again, err := f.Refund(ref, "r1", 300)
+	if err != nil {
+		t.Fatalf("repeat: %v", err)
+	}
+
+	if again != first {
+		t.Errorf("repeat returned %q, want %q", again.Reference, first.Reference)
+	}
+	if got := f.auths[ref].refundedMinor; got != 300 {
+		t.Errorf("refunded %d, want 300", got)
+	}
+}
+
+func TestFakeRefundNotCaptured(t *testing.T) {
+	f := NewFakeProvider()
+	ref := authorized(t, f, "auth", 1000)
+
+	if _, err := f.Refund(ref, "r1", 100); !errors.Is(err, ErrUnknownReference) {
+		t.Errorf("err = %v, want %v", err, ErrUnknownReference)
+	}
+}
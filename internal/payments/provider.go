@@ -0,0 +1,66 @@
+// Package payments talks to the gateway that moves card and e-wallet money.
+// Cash never reaches a provider; it is recorded by the payment store alone.
+package payments
+
+import "errors"
+
+const (
+	MethodCash    = "cash"
+	MethodCard    = "card"
+	MethodEWallet = "ewallet"
+)
+
+func IsValidMethod(method string) bool {
+	switch method {
+	case MethodCash, MethodCard, MethodEWallet:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrDeclined is a final answer from the provider: retrying the same
+	// request will not change it.
+	ErrDeclined = errors.New("payment was declined")
+	// ErrUnavailable means the provider could not be reached or failed
+	// before answering. The request is safe to retry with the same key.
+	ErrUnavailable = errors.New("payment provider is unavailable")
+	// ErrUnknownReference is returned for references the provider never
+	// issued, and for references the call can no longer apply to, such as
+	// capturing a voided authorization or refunding one never captured.
+	ErrUnknownReference = errors.New("payment provider does not know this reference")
+	// ErrInvalidAmount is returned for amounts that are not positive or go
+	// beyond what the reference allows: capturing more than was authorized
+	// or refunding more than was captured.
+	ErrInvalidAmount = errors.New("amount exceeds what the provider holds for this reference")
+)
+
+// AuthorizeRequest reserves AmountMinor on the customer's card or wallet.
+// Token is what the client-side SDK of the provider returned for the card
+// or wallet; card numbers never reach this service. IdempotencyKey makes a
+// repeated call return the first authorization instead of a second one.
+type AuthorizeRequest struct {
+	IdempotencyKey string
+	Method         string
+	Token          string
+	AmountMinor    int64
+	Currency       string
+}
+
+// Result is the provider's answer. Reference identifies the authorization
+// for Authorize and Capture, and the refund for Refund.
+type Result struct {
+	Reference string
+}
+
+// PaymentProvider is a card and e-wallet gateway. Money is authorized first
+// and then captured, up to the authorized amount; an authorization that is
+// not captured can be voided, and captured money refunded in parts.
+// Authorize, Capture and Refund take an idempotency key; a call repeated
+// with the same key returns the first result instead of moving money again.
+type PaymentProvider interface {
+	Authorize(req AuthorizeRequest) (Result, error)
+	Capture(reference, idempotencyKey string, amountMinor int64) (Result, error)
+	Void(reference string) (Result, error)
+	Refund(reference, idempotencyKey string, amountMinor int64) (Result, error)
+}
@@ -0,0 +1,75 @@
+package permissions
+
+const (
+	RoleOwner   = "OWNER"
+	RoleManager = "MANAGER"
+	RoleStaff   = "STAFF"
+	RoleHost    = "HOST"
+)
+
+type Permission string
+
+const (
+	RestaurantCreate Permission = "restaurant:create"
+	RestaurantWrite  Permission = "restaurant:write"
+	RestaurantDelete Permission = "restaurant:delete"
+	StaffManage      Permission = "staff:manage"
+	TableManage      Permission = "table:manage"
+	BookingManage    Permission = "booking:manage"
+	MenuManage       Permission = "menu:manage"
+	OrderManage      Permission = "order:manage"
+	PaymentRefund    Permission = "payment:refund"
+	UserManage       Permission = "user:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleOwner: {
+		RestaurantCreate,
+		RestaurantWrite,
+		RestaurantDelete,
+		StaffManage,
+		TableManage,
+		BookingManage,
+		MenuManage,
+		OrderManage,
+		PaymentRefund,
+		UserManage,
+	},
+	RoleManager: {
+		RestaurantWrite,
+		RestaurantDelete,
+		StaffManage,
+		TableManage,
+		BookingManage,
+		MenuManage,
+		OrderManage,
+		PaymentRefund,
+	},
+	RoleHost: {
+		BookingManage,
+	},
+	RoleStaff: {
+		OrderManage,
+	},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Has reports whether the role is granted the permission. Unknown roles have
+// no permissions.
+func Has(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// For lists the permissions granted to the role.
+func For(role string) []Permission {
+	return append([]Permission{}, rolePermissions[role]...)
+}
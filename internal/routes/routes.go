@@ -2,25 +2,190 @@ package routes
 
 import (
 	"htrr-apis/internal/app"
+	"htrr-apis/internal/permissions"
+	"htrr-apis/internal/utils"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 )
 
 func SetupRoutes(app *app.Application) *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(app.Middleware.Authenticate)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodeNotFound, "no route matches "+r.URL.Path)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteError(w, r, http.StatusMethodNotAllowed, utils.CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+	})
+
+	can := app.Middleware.RequirePermission
 
 	// health
 	r.Get("/health", app.HealthCheck)
 
+	// auth
+	r.Post("/auth/login", app.AuthHandler.HandleLogin)
+	r.Post("/auth/refresh", app.AuthHandler.HandleRefresh)
+	r.Post("/auth/logout", app.AuthHandler.HandleLogout)
+
 	// user
 	r.Post("/user", app.UserHandler.HandleCreateUser)
 
-	// restaurants
-	r.Get("/restaurants", app.RestaurantHandler.HandleSearchRestaurant)
-	r.Post("/restaurant", app.RestaurantHandler.HandleCreateRestaurant)
-	r.Get("/restaurant/{id}", app.RestaurantHandler.HandleGetRestaurantById)
-	r.Patch("/restaurant/{id}", app.RestaurantHandler.HandleUpdateRestaurant)
-	r.Delete("/restaurant/{id}", app.RestaurantHandler.HandleDeleteRestaurant)
-	r.Delete("/restaurants", app.RestaurantHandler.HandleBulkDeleteRestaurants)
+	// public booking page
+	r.Get("/restaurant/{id}/availability", app.AvailabilityHandler.HandleGetAvailability)
+	r.Get("/restaurant/{id}/hours", app.RestaurantHandler.HandleGetHours)
+	r.Get("/restaurant/{id}/menu", app.MenuHandler.HandleGetMenuTree)
+
+	// live events: browsers' EventSource cannot send the Authorization
+	// header, so the stream also accepts a ticket from /events/ticket
+	r.Group(func(r chi.Router) {
+		r.Use(app.Middleware.AuthenticateStreamTicket("id"))
+		r.Use(app.Middleware.RequireUser)
+		r.Use(app.Middleware.LoadScope)
+		r.Use(app.Middleware.RequireRestaurantAccess("id"))
+
+		r.Get("/restaurant/{id}/events", app.EventHandler.HandleStream)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(app.Middleware.RequireUser)
+		r.Use(app.Middleware.LoadScope)
+
+		r.Get("/auth/sessions", app.AuthHandler.HandleListSessions)
+		r.Delete("/auth/sessions/{id}", app.AuthHandler.HandleRevokeSession)
+
+		r.With(can(permissions.UserManage)).Patch("/user/{id}/role", app.UserHandler.HandleUpdateUserRole)
+
+		// positions
+		r.Get("/positions", app.PositionHandler.HandleListPositions)
+		r.Get("/positions/{id}", app.PositionHandler.HandleGetPositionById)
+		r.With(can(permissions.StaffManage)).Post("/positions", app.PositionHandler.HandleCreatePosition)
+		r.With(can(permissions.StaffManage)).Patch("/positions/{id}", app.PositionHandler.HandleUpdatePosition)
+		r.With(can(permissions.StaffManage)).Delete("/positions/{id}", app.PositionHandler.HandleDeletePosition)
+
+		// employees
+		r.Get("/employees", app.EmployeeHandler.HandleListEmployees)
+		r.Get("/employees/{id}", app.EmployeeHandler.HandleGetEmployeeById)
+		r.With(can(permissions.StaffManage)).Post("/employees", app.EmployeeHandler.HandleCreateEmployee)
+		r.With(can(permissions.StaffManage)).Patch("/employees/{id}", app.EmployeeHandler.HandleUpdateEmployee)
+		r.With(can(permissions.StaffManage)).Delete("/employees/{id}", app.EmployeeHandler.HandleDeleteEmployee)
+
+		// bookings
+		r.Get("/bookings", app.BookingHandler.HandleListBookings)
+		r.Get("/bookings/{id}", app.BookingHandler.HandleGetBookingById)
+		r.With(can(permissions.BookingManage)).Post("/bookings", app.BookingHandler.HandleCreateBooking)
+		r.With(can(permissions.BookingManage)).Patch("/bookings/{id}", app.BookingHandler.HandleUpdateBooking)
+		r.With(can(permissions.BookingManage)).Delete("/bookings/{id}", app.BookingHandler.HandleDeleteBooking)
+		r.Get("/bookings/{id}/history", app.BookingHandler.HandleGetBookingHistory)
+		r.With(can(permissions.BookingManage)).Post("/bookings/{id}/confirm", app.BookingHandler.HandleConfirmBooking)
+		r.With(can(permissions.BookingManage)).Post("/bookings/{id}/seat", app.BookingHandler.HandleSeatBooking)
+		r.With(can(permissions.BookingManage)).Post("/bookings/{id}/complete", app.BookingHandler.HandleCompleteBooking)
+		r.With(can(permissions.BookingManage)).Post("/bookings/{id}/cancel", app.BookingHandler.HandleCancelBooking)
+		r.With(can(permissions.BookingManage)).Post("/bookings/{id}/no-show", app.BookingHandler.HandleNoShowBooking)
+
+		// customers
+		r.Get("/customers", app.CustomerHandler.HandleListCustomers)
+		r.Get("/customers/{id}", app.CustomerHandler.HandleGetCustomerById)
+		r.Get("/customers/{id}/history", app.CustomerHandler.HandleGetCustomerHistory)
+		r.With(can(permissions.BookingManage)).Post("/customers", app.CustomerHandler.HandleCreateCustomer)
+		r.With(can(permissions.BookingManage)).Patch("/customers/{id}", app.CustomerHandler.HandleUpdateCustomer)
+		r.With(can(permissions.BookingManage)).Delete("/customers/{id}", app.CustomerHandler.HandleDeleteCustomer)
+
+		// restaurants
+		r.Get("/restaurants", app.RestaurantHandler.HandleSearchRestaurant)
+		r.With(can(permissions.RestaurantDelete)).Delete("/restaurants", app.RestaurantHandler.HandleBulkDeleteRestaurants)
+		r.With(can(permissions.RestaurantCreate)).Post("/restaurant", app.RestaurantHandler.HandleCreateRestaurant)
+
+		r.Route("/restaurant/{id}", func(r chi.Router) {
+			r.Use(app.Middleware.RequireRestaurantAccess("id"))
+
+			r.Get("/", app.RestaurantHandler.HandleGetRestaurantById)
+			r.With(can(permissions.RestaurantWrite)).Patch("/", app.RestaurantHandler.HandleUpdateRestaurant)
+			r.With(can(permissions.RestaurantDelete)).Delete("/", app.RestaurantHandler.HandleDeleteRestaurant)
+			r.With(can(permissions.RestaurantWrite)).Put("/hours", app.RestaurantHandler.HandleUpdateHours)
+
+			// tables
+			r.Get("/tables", app.TableHandler.HandleListTables)
+			r.Get("/tables/{tableId}", app.TableHandler.HandleGetTableById)
+			r.With(can(permissions.TableManage)).Post("/tables", app.TableHandler.HandleCreateTable)
+			r.With(can(permissions.TableManage)).Patch("/tables/{tableId}", app.TableHandler.HandleUpdateTable)
+			r.With(can(permissions.TableManage)).Delete("/tables/{tableId}", app.TableHandler.HandleDeleteTable)
+
+			r.Post("/events/ticket", app.EventHandler.HandleIssueStreamTicket)
+
+			// floor: hosts run the room, so status changes follow booking rights
+			r.Get("/floor", app.TableHandler.HandleGetFloor)
+			r.With(can(permissions.BookingManage)).Put("/tables/{tableId}/status", app.TableHandler.HandleSetTableStatus)
+
+			// waitlist
+			r.Get("/waitlist", app.WaitlistHandler.HandleListWaitlist)
+			r.With(can(permissions.BookingManage)).Post("/waitlist", app.WaitlistHandler.HandleCreateWaitlistEntry)
+			r.With(can(permissions.BookingManage)).Patch("/waitlist/{entryId}", app.WaitlistHandler.HandleMoveWaitlistEntry)
+			r.With(can(permissions.BookingManage)).Delete("/waitlist/{entryId}", app.WaitlistHandler.HandleRemoveWaitlistEntry)
+			r.With(can(permissions.BookingManage)).Post("/waitlist/{entryId}/seat", app.WaitlistHandler.HandleSeatWaitlistEntry)
+
+			// menus: the public tree is GET /restaurant/{id}/menu
+			r.Get("/menus", app.MenuHandler.HandleListMenus)
+			r.Get("/menus/{menuId}", app.MenuHandler.HandleGetMenuById)
+			r.With(can(permissions.MenuManage)).Post("/menus", app.MenuHandler.HandleCreateMenu)
+			r.With(can(permissions.MenuManage)).Patch("/menus/{menuId}", app.MenuHandler.HandleUpdateMenu)
+			r.With(can(permissions.MenuManage)).Delete("/menus/{menuId}", app.MenuHandler.HandleDeleteMenu)
+
+			r.Get("/menu-categories/{categoryId}", app.MenuHandler.HandleGetMenuCategoryById)
+			r.With(can(permissions.MenuManage)).Post("/menu-categories", app.MenuHandler.HandleCreateMenuCategory)
+			r.With(can(permissions.MenuManage)).Patch("/menu-categories/{categoryId}", app.MenuHandler.HandleUpdateMenuCategory)
+			r.With(can(permissions.MenuManage)).Delete("/menu-categories/{categoryId}", app.MenuHandler.HandleDeleteMenuCategory)
+
+			r.Get("/menu-items/{itemId}", app.MenuHandler.HandleGetMenuItemById)
+			r.With(can(permissions.MenuManage)).Post("/menu-items", app.MenuHandler.HandleCreateMenuItem)
+			r.With(can(permissions.MenuManage)).Patch("/menu-items/{itemId}", app.MenuHandler.HandleUpdateMenuItem)
+			r.With(can(permissions.MenuManage)).Delete("/menu-items/{itemId}", app.MenuHandler.HandleDeleteMenuItem)
+
+			r.Get("/modifier-groups/{groupId}", app.MenuHandler.HandleGetModifierGroupById)
+			r.With(can(permissions.MenuManage)).Post("/modifier-groups", app.MenuHandler.HandleCreateModifierGroup)
+			r.With(can(permissions.MenuManage)).Patch("/modifier-groups/{groupId}", app.MenuHandler.HandleUpdateModifierGroup)
+			r.With(can(permissions.MenuManage)).Delete("/modifier-groups/{groupId}", app.MenuHandler.HandleDeleteModifierGroup)
+
+			r.Get("/modifiers/{modifierId}", app.MenuHandler.HandleGetModifierById)
+			r.With(can(permissions.MenuManage)).Post("/modifiers", app.MenuHandler.HandleCreateModifier)
+			r.With(can(permissions.MenuManage)).Patch("/modifiers/{modifierId}", app.MenuHandler.HandleUpdateModifier)
+			r.With(can(permissions.MenuManage)).Delete("/modifiers/{modifierId}", app.MenuHandler.HandleDeleteModifier)
+
+			// orders
+			r.Get("/orders", app.OrderHandler.HandleListOrders)
+			r.Get("/orders/{orderId}", app.OrderHandler.HandleGetOrderById)
+			r.With(can(permissions.OrderManage)).Post("/orders", app.OrderHandler.HandleOpenOrder)
+			r.With(can(permissions.OrderManage)).Post("/orders/{orderId}/items", app.OrderHandler.HandleAddOrderItem)
+			r.With(can(permissions.OrderManage)).Patch("/orders/{orderId}/items/{itemId}", app.OrderHandler.HandleSetOrderItemSeat)
+			r.With(can(permissions.OrderManage)).Delete("/orders/{orderId}/items/{itemId}", app.OrderHandler.HandleRemoveOrderItem)
+			r.With(can(permissions.OrderManage)).Post("/orders/{orderId}/send", app.OrderHandler.HandleSendOrder)
+			r.With(can(permissions.OrderManage)).Post("/orders/{orderId}/close", app.OrderHandler.HandleCloseOrder)
+
+			// bills
+			r.Get("/billing", app.BillHandler.HandleGetSettings)
+			r.With(can(permissions.RestaurantWrite)).Put("/billing", app.BillHandler.HandleUpdateSettings)
+			r.Get("/orders/{orderId}/bill", app.BillHandler.HandleGetBill)
+			r.With(can(permissions.OrderManage)).Post("/orders/{orderId}/bill/preview", app.BillHandler.HandlePreviewBill)
+			r.With(can(permissions.OrderManage)).Post("/orders/{orderId}/bill/finalize", app.BillHandler.HandleFinalizeBill)
+
+			// payments
+			r.Get("/orders/{orderId}/bill/payments", app.PaymentHandler.HandleListPayments)
+			r.With(can(permissions.OrderManage)).Post("/orders/{orderId}/bill/payments", app.PaymentHandler.HandleTakePayment)
+			r.Get("/payments/{paymentId}", app.PaymentHandler.HandleGetPaymentById)
+			r.With(can(permissions.OrderManage)).Post("/payments/{paymentId}/capture", app.PaymentHandler.HandleCapturePayment)
+			r.With(can(permissions.PaymentRefund)).Post("/payments/{paymentId}/void", app.PaymentHandler.HandleVoidPayment)
+			r.With(can(permissions.PaymentRefund)).Post("/payments/{paymentId}/refunds", app.PaymentHandler.HandleRefundPayment)
+
+			// kitchen display: screens follow live changes on /events
+			r.Get("/kitchen/routing", app.KitchenHandler.HandleGetRouting)
+			r.With(can(permissions.MenuManage)).Put("/kitchen/routing", app.KitchenHandler.HandleUpdateRouting)
+			r.Get("/kitchen/tickets", app.KitchenHandler.HandleListTickets)
+			r.Get("/kitchen/tickets/{ticketId}", app.KitchenHandler.HandleGetTicketById)
+			r.With(can(permissions.OrderManage)).Post("/kitchen/tickets/{ticketId}/bump", app.KitchenHandler.HandleBumpTicket)
+			r.With(can(permissions.OrderManage)).Post("/kitchen/tickets/{ticketId}/items/{itemId}/bump", app.KitchenHandler.HandleBumpTicketItem)
+		})
+	})
 	return r
 }
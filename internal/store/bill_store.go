@@ -0,0 +1,335 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"htrr-apis/internal/billing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBillFinalized       = conflict("order already has a final bill")
+	ErrTaxCategoryNotFound = invalid("category_tax_rates", "category does not exist in this restaurant")
+)
+
+type PostgresBillStore struct {
+	db *sql.DB
+}
+
+func NewPostgresBillStore(db *sql.DB) *PostgresBillStore {
+	return &PostgresBillStore{
+		db: db,
+	}
+}
+
+// Bill is a finalized bill. Its amounts are those worked out when it was
+// finalized and do not follow later changes to the billing settings.
+type Bill struct {
+	ID           string  `json:"id"`
+	RestaurantID string  `json:"restaurant_id"`
+	OrderID      string  `json:"order_id"`
+	FinalizedBy  *string `json:"finalized_by"`
+	billing.Bill
+	FinalizedAt time.Time `json:"finalized_at"`
+}
+
+type BillStore interface {
+	GetSettings(restaurantID string) (*billing.Settings, error)
+	ReplaceSettings(restaurantID string, settings *billing.Settings) error
+	Preview(restaurantID, orderID string, req billing.Request) (*billing.Bill, error)
+	Finalize(restaurantID, orderID, finalizedBy string, req billing.Request) (*Bill, error)
+	GetByOrder(restaurantID, orderID string) (*Bill, error)
+}
+
+// loadBillingSettings returns the settings of a restaurant, or no tax, no
+// service charge and no rounding when it has none.
+func loadBillingSettings(q queryer, restaurantID string) (*billing.Settings, error) {
+	s := &billing.Settings{
+		CategoryTaxRates:  []billing.CategoryTaxRate{},
+		RoundingIncrement: 1,
+		RoundingMode:      billing.RoundHalfUp,
+	}
+
+	err := q.QueryRow(`
+	SELECT tax_rate_bp, service_charge_bp, service_charge_taxable, rounding_increment, rounding_mode
+	FROM billing_settings
+	WHERE restaurant_id = $1
+	`, restaurantID).Scan(&s.TaxRateBP, &s.ServiceChargeBP, &s.ServiceChargeTaxable, &s.RoundingIncrement, &s.RoundingMode)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+	SELECT category_id, tax_rate_bp
+	FROM category_tax_rates
+	WHERE restaurant_id = $1
+	ORDER BY category_id
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c billing.CategoryTaxRate
+		if err := rows.Scan(&c.CategoryID, &c.TaxRateBP); err != nil {
+			return nil, err
+		}
+		s.CategoryTaxRates = append(s.CategoryTaxRates, c)
+	}
+
+	return s, rows.Err()
+}
+
+// loadBillLines returns the lines of an order that are not voided, with the
+// menu category each one was ordered under and is taxed by.
+func loadBillLines(q queryer, orderID string) ([]billing.Line, error) {
+	rows, err := q.Query(`
+	SELECT i.id, i.name, i.category_id, i.seat, i.quantity, i.unit_price_minor
+	FROM order_items i
+	WHERE i.order_id = $1 AND i.status <> 'voided'
+	ORDER BY i.created_at, i.id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []billing.Line
+	for rows.Next() {
+		var l billing.Line
+		if err := rows.Scan(&l.ID, &l.Name, &l.CategoryID, &l.Seat, &l.Quantity, &l.UnitPriceMinor); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+
+	return lines, rows.Err()
+}
+
+func calculateBill(q queryer, o *Order, req billing.Request) (*billing.Bill, error) {
+	settings, err := loadBillingSettings(q, o.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := loadBillLines(q, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return billing.Calculate(*settings, o.Currency, lines, req)
+}
+
+func (pg *PostgresBillStore) GetSettings(restaurantID string) (*billing.Settings, error) {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	return loadBillingSettings(pg.db, restaurantID)
+}
+
+// ReplaceSettings overwrites the billing settings of a restaurant, category
+// rates included. Bills already finalized keep their amounts.
+func (pg *PostgresBillStore) ReplaceSettings(restaurantID string, s *billing.Settings) error {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+	INSERT INTO billing_settings (restaurant_id, tax_rate_bp, service_charge_bp, service_charge_taxable,
+			rounding_increment, rounding_mode)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (restaurant_id) DO UPDATE SET
+		tax_rate_bp = EXCLUDED.tax_rate_bp,
+		service_charge_bp = EXCLUDED.service_charge_bp,
+		service_charge_taxable = EXCLUDED.service_charge_taxable,
+		rounding_increment = EXCLUDED.rounding_increment,
+		rounding_mode = EXCLUDED.rounding_mode
+	`, restaurantID, s.TaxRateBP, s.ServiceChargeBP, s.ServiceChargeTaxable, s.RoundingIncrement, s.RoundingMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM category_tax_rates WHERE restaurant_id = $1`, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range s.CategoryTaxRates {
+		_, err := uuid.Parse(c.CategoryID)
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		result, err := tx.Exec(`
+		INSERT INTO category_tax_rates (category_id, restaurant_id, tax_rate_bp)
+		SELECT id, $1, $2 FROM menu_categories WHERE id = $3 AND restaurant_id = $1
+		`, restaurantID, c.TaxRateBP, c.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrTaxCategoryNotFound, c.CategoryID)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Preview works out the bill of an order as it stands without saving
+// anything, so it can be shown while the table is still ordering.
+func (pg *PostgresBillStore) Preview(restaurantID, orderID string, req billing.Request) (*billing.Bill, error) {
+	_, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	o := &Order{}
+	err = scanOrder(pg.db.QueryRow(orderSelect+`
+	WHERE o.id = $1 AND o.restaurant_id = $2
+	`, orderID, restaurantID), o)
+	if err == sql.ErrNoRows {
+		return nil, notFound("order")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateBill(pg.db, o, req)
+}
+
+// Finalize works out the bill of an order and saves it, with one share per
+// payer, then closes the order if it is still open. An order is billed once;
+// its lines must all have been sent to the kitchen.
+func (pg *PostgresBillStore) Finalize(restaurantID, orderID, finalizedBy string, req billing.Request) (*Bill, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := lockOrder(tx, restaurantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var billed, pending bool
+	err = tx.QueryRow(`
+	SELECT EXISTS (SELECT 1 FROM bills WHERE order_id = $1),
+		EXISTS (SELECT 1 FROM order_items WHERE order_id = $1 AND status = 'pending')
+	`, o.ID).Scan(&billed, &pending)
+	if err != nil {
+		return nil, err
+	}
+
+	if billed {
+		return nil, ErrBillFinalized
+	}
+
+	if pending {
+		return nil, ErrOrderHasUnsentItems
+	}
+
+	calculated, err := calculateBill(tx, o, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := range calculated.Shares {
+		calculated.Shares[n].ID = uuid.NewString()
+	}
+
+	details, err := json.Marshal(calculated)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bill{
+		RestaurantID: restaurantID,
+		OrderID:      o.ID,
+		FinalizedBy:  &finalizedBy,
+		Bill:         *calculated,
+	}
+
+	err = tx.QueryRow(`
+	INSERT INTO bills (restaurant_id, order_id, currency, subtotal_minor, discount_minor, service_charge_minor,
+			tax_minor, rounding_minor, total_minor, split_mode, details, finalized_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	RETURNING id, finalized_at
+	`, restaurantID, o.ID, b.Currency, b.SubtotalMinor, b.DiscountMinor, b.ServiceChargeMinor,
+		b.TaxMinor, b.RoundingMinor, b.TotalMinor, b.SplitMode, string(details), finalizedBy).
+		Scan(&b.ID, &b.FinalizedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, sh := range b.Shares {
+		_, err = tx.Exec(`
+		INSERT INTO bill_shares (id, bill_id, position, label, total_minor)
+		VALUES ($1, $2, $3, $4, $5)
+		`, sh.ID, b.ID, n, sh.Label, sh.TotalMinor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Status == OrderStatusOpen {
+		_, err = tx.Exec(`
+		UPDATE orders SET status = 'closed', closed_by = $1, closed_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		`, finalizedBy, o.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, tx.Commit()
+}
+
+func (pg *PostgresBillStore) GetByOrder(restaurantID, orderID string) (*Bill, error) {
+	_, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	b := &Bill{}
+	var details []byte
+	err = pg.db.QueryRow(`
+	SELECT id, restaurant_id, order_id, finalized_by, finalized_at, details
+	FROM bills
+	WHERE order_id = $1 AND restaurant_id = $2
+	`, orderID, restaurantID).Scan(&b.ID, &b.RestaurantID, &b.OrderID, &b.FinalizedBy, &b.FinalizedAt, &details)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("bill")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(details, &b.Bill)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,745 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"htrr-apis/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BookingStatusPending   = "pending"
+	BookingStatusConfirmed = "confirmed"
+	BookingStatusSeated    = "seated"
+	BookingStatusCompleted = "completed"
+	BookingStatusCancelled = "cancelled"
+	BookingStatusNoShow    = "no_show"
+)
+
+var (
+	ErrBookingConflict   = conflict("table is already booked for this time")
+	ErrPartySizeMismatch = invalid("party_size", "party size does not suit this table")
+	ErrIllegalTransition = conflict("booking cannot move to that status from its current status")
+	ErrBookingFinal      = conflict("booking is completed, cancelled or no_show and can no longer be changed")
+)
+
+// bookingTransitions lists the statuses a booking may move to from each
+// status. completed, cancelled and no_show are final.
+var bookingTransitions = map[string][]string{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusSeated, BookingStatusCancelled, BookingStatusNoShow},
+	BookingStatusConfirmed: {BookingStatusSeated, BookingStatusCancelled, BookingStatusNoShow},
+	BookingStatusSeated:    {BookingStatusCompleted},
+}
+
+// CanTransition reports whether a booking may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, next := range bookingTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isFinal reports whether a booking in status can no longer change.
+func isFinal(status string) bool {
+	return len(bookingTransitions[status]) == 0
+}
+
+type PostgresBookingStore struct {
+	db *sql.DB
+}
+
+func NewPostgresBookingStore(db *sql.DB) *PostgresBookingStore {
+	return &PostgresBookingStore{
+		db: db,
+	}
+}
+
+type Booking struct {
+	ID              string     `json:"id"`
+	RestaurantID    string     `json:"restaurant_id"`
+	TableID         string     `json:"table_id"`
+	CustomerID      *string    `json:"customer_id"`
+	CustomerName    string     `json:"customer_name"`
+	BookingTime     time.Time  `json:"booking_time"`
+	PartySize       int        `json:"party_size"`
+	DurationMinutes int        `json:"duration_minutes"`
+	EndsAt          time.Time  `json:"ends_at"`
+	Status          string     `json:"status"`
+	StatusChangedAt *time.Time `json:"status_changed_at"`
+	StatusChangedBy *string    `json:"status_changed_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// ContactPhone and ContactEmail are only read by Create, to find or
+	// create the customer when CustomerID is not set.
+	ContactPhone string `json:"-"`
+	ContactEmail string `json:"-"`
+}
+
+type BookingStatusChange struct {
+	ID         string    `json:"id"`
+	BookingID  string    `json:"booking_id"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *string   `json:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+type ListBookingParams struct {
+	Page         int
+	PageSize     int
+	RestaurantID string
+	TableID      string
+	Status       string
+	From         *time.Time
+	To           *time.Time
+	Scope        Scope
+}
+
+type BookingStore interface {
+	Create(context.Context, *Booking, Scope) error
+	List(context.Context, ListBookingParams) ([]Booking, int, error)
+	GetById(context.Context, string, Scope) (*Booking, error)
+	Update(context.Context, *Booking, Scope) ([]Table, error)
+	Delete(context.Context, string, Scope) (*Table, error)
+	Availability(context.Context, AvailabilityParams) ([]Slot, error)
+	Transition(ctx context.Context, id, to, changedBy string, scope Scope) (*Booking, *Table, error)
+	History(ctx context.Context, id string, scope Scope) ([]BookingStatusChange, error)
+}
+
+const bookingSelect = `
+	SELECT b.id, t.restaurant_id, b.table_id, b.customer_id, b.customer_name, b.booking_time,
+			b.party_size, b.duration_minutes, b.ends_at, b.status,
+			b.status_changed_at, b.status_changed_by, b.created_at, b.updated_at
+	FROM bookings b
+	JOIN tables t ON t.id = b.table_id
+	`
+
+func scanBooking(row interface{ Scan(...any) error }, b *Booking, extra ...any) error {
+	dest := []any{
+		&b.ID,
+		&b.RestaurantID,
+		&b.TableID,
+		&b.CustomerID,
+		&b.CustomerName,
+		&b.BookingTime,
+		&b.PartySize,
+		&b.DurationMinutes,
+		&b.EndsAt,
+		&b.Status,
+		&b.StatusChangedAt,
+		&b.StatusChangedBy,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func mapBookingError(err error) error {
+	if pgCode(err) == pgExclusionViolation {
+		return ErrBookingConflict
+	}
+	return err
+}
+
+// lockTable loads the table a booking targets, as long as it is inside the
+// scope, and checks the party fits it and the table is in service.
+func lockTable(ctx context.Context, tx *sql.Tx, tableID string, partySize int, scope Scope) (*Table, error) {
+	_, err := uuid.Parse(tableID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	t := &Table{}
+	err = tx.QueryRowContext(ctx, `
+	SELECT id, restaurant_id, status, min_party_size, max_party_size
+	FROM tables
+	WHERE id = $1 AND ($2::uuid[] IS NULL OR restaurant_id = ANY($2))
+	FOR SHARE
+	`, tableID, scope.Arg()).Scan(
+		&t.ID,
+		&t.RestaurantID,
+		&t.Status,
+		&t.MinPartySize,
+		&t.MaxPartySize)
+	if err == sql.ErrNoRows {
+		return nil, notFound("table")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Status == TableStatusOutOfService {
+		return nil, ErrTableOutOfService
+	}
+
+	if !t.Fits(partySize) {
+		return nil, ErrPartySizeMismatch
+	}
+
+	return t, nil
+}
+
+// checkOpeningHours rejects a booking that does not fit entirely inside the
+// restaurant's opening hours.
+func checkOpeningHours(ctx context.Context, q queryer, restaurantID string, b *Booking) error {
+	hours, err := loadHours(ctx, q, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	end := b.BookingTime.Add(time.Duration(b.DurationMinutes) * time.Minute)
+	if !hours.Covers(b.BookingTime, end) {
+		return ErrOutsideOpeningHours
+	}
+
+	return nil
+}
+
+// Create inserts a booking. Overlapping bookings on the same table are
+// rejected by the ex_bookings_table_overlap exclusion constraint, which holds
+// even for concurrent requests, and surface as ErrBookingConflict. It returns
+// ErrNotFound when the table is missing or out of scope, and
+// ErrOutsideOpeningHours when the restaurant is closed for part of the slot.
+func (pg *PostgresBookingStore) Create(ctx context.Context, b *Booking, scope Scope) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	t, err := lockTable(ctx, tx, b.TableID, b.PartySize, scope)
+	if err != nil {
+		return err
+	}
+
+	err = checkOpeningHours(ctx, tx, t.RestaurantID, b)
+	if err != nil {
+		return err
+	}
+
+	if b.CustomerID != nil {
+		err = checkCustomer(tx, b.CustomerID, t.RestaurantID)
+	} else {
+		b.CustomerID, err = resolveCustomer(tx, t.RestaurantID, b.CustomerName, b.ContactPhone, b.ContactEmail)
+	}
+	if err != nil {
+		return err
+	}
+
+	if b.Status == "" {
+		b.Status = BookingStatusPending
+	}
+
+	var id string
+	err = tx.QueryRowContext(ctx, `
+	INSERT INTO bookings (table_id, customer_id, customer_name, booking_time, party_size,
+		duration_minutes, status, status_changed_at, status_changed_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, $8)
+	RETURNING id
+	`, b.TableID, b.CustomerID, b.CustomerName, b.BookingTime, b.PartySize,
+		b.DurationMinutes, b.Status, b.StatusChangedBy).
+		Scan(&id)
+	if err != nil {
+		return mapBookingError(err)
+	}
+
+	err = recordStatusChange(ctx, tx, id, nil, b.Status, b.StatusChangedBy)
+	if err != nil {
+		return err
+	}
+
+	err = scanBooking(tx.QueryRowContext(ctx, bookingSelect+`WHERE b.id = $1`, id), b)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresBookingStore) List(ctx context.Context, params ListBookingParams) ([]Booking, int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	q := `
+	SELECT b.id, t.restaurant_id, b.table_id, b.customer_id, b.customer_name, b.booking_time,
+			b.party_size, b.duration_minutes, b.ends_at, b.status,
+			b.status_changed_at, b.status_changed_by, b.created_at, b.updated_at,
+			COUNT(*) OVER()
+	FROM bookings b
+	JOIN tables t ON t.id = b.table_id
+	WHERE ($1 = '' OR t.restaurant_id::text = $1)
+		AND ($2 = '' OR b.table_id::text = $2)
+		AND ($3 = '' OR b.status = $3)
+		AND ($4::timestamptz IS NULL OR b.ends_at > $4)
+		AND ($5::timestamptz IS NULL OR b.booking_time < $5)
+		AND ($6::uuid[] IS NULL OR t.restaurant_id = ANY($6))
+	ORDER BY b.booking_time
+	LIMIT $7 OFFSET $8
+	`
+	limit, offset := utils.GetOffset(&params.Page, &params.PageSize)
+
+	rows, err := pg.db.QueryContext(ctx, q,
+		params.RestaurantID,
+		params.TableID,
+		params.Status,
+		params.From,
+		params.To,
+		params.Scope.Arg(),
+		limit,
+		offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var total int
+	list := []Booking{}
+	for rows.Next() {
+		var b Booking
+		if err := scanBooking(rows, &b, &total); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, b)
+	}
+
+	return list, total, rows.Err()
+}
+
+func (pg *PostgresBookingStore) GetById(ctx context.Context, id string, scope Scope) (*Booking, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	q := bookingSelect + `
+	WHERE b.id = $1 AND ($2::uuid[] IS NULL OR t.restaurant_id = ANY($2))
+	`
+	b := &Booking{}
+	err = scanBooking(pg.db.QueryRowContext(ctx, q, id, scope.Arg()), b)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("booking")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Update rewrites the booking details, possibly moving it to another table.
+// The status only changes through Transition, and a booking in a final status
+// cannot be edited at all: Update returns ErrBookingFinal so past visits stay
+// as they happened. Both the current and the target table must be inside the
+// scope. Opening hours are only checked again when the table, time or
+// duration changes, so edits to an existing booking are not blocked by a later
+// change of schedule. Moving a seated booking occupies the new table and frees
+// the old one; the tables whose status changed are returned.
+func (pg *PostgresBookingStore) Update(ctx context.Context, b *Booking, scope Scope) ([]Table, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(b.ID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := lockTable(ctx, tx, b.TableID, b.PartySize, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var current Booking
+	err = tx.QueryRowContext(ctx, `
+	SELECT table_id, booking_time, duration_minutes, status FROM bookings WHERE id = $1 FOR UPDATE
+	`, b.ID).Scan(&current.TableID, &current.BookingTime, &current.DurationMinutes, &current.Status)
+	if err == sql.ErrNoRows {
+		return nil, notFound("booking")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if isFinal(current.Status) {
+		return nil, ErrBookingFinal
+	}
+
+	if current.TableID != b.TableID ||
+		!current.BookingTime.Equal(b.BookingTime) ||
+		current.DurationMinutes != b.DurationMinutes {
+		err = checkOpeningHours(ctx, tx, t.RestaurantID, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = checkCustomer(tx, b.CustomerID, t.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE bookings
+	SET table_id = $1, customer_id = $2, customer_name = $3, booking_time = $4, party_size = $5,
+		duration_minutes = $6
+	WHERE id = $7
+		AND table_id IN (
+			SELECT id FROM tables WHERE ($8::uuid[] IS NULL OR restaurant_id = ANY($8))
+		)
+	`, b.TableID, b.CustomerID, b.CustomerName, b.BookingTime, b.PartySize, b.DurationMinutes,
+		b.ID, scope.Arg())
+	if err != nil {
+		return nil, mapBookingError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, notFound("booking")
+	}
+
+	// A seated party that moves takes the occupancy with it.
+	var moved []string
+	if current.Status == BookingStatusSeated && current.TableID != b.TableID {
+		ok, err := moveTable(tx, b.TableID, TableStatusOccupied)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrIllegalTableTransition
+		}
+		moved = append(moved, b.TableID)
+
+		ok, err = moveTable(tx, current.TableID, TableStatusAvailable)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			moved = append(moved, current.TableID)
+		}
+	}
+
+	err = scanBooking(tx.QueryRowContext(ctx, bookingSelect+`WHERE b.id = $1`, b.ID), b)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []Table{}
+	for _, id := range moved {
+		var t Table
+		err = scanTable(tx.QueryRowContext(ctx, tableSelect+`WHERE id = $1`, id), &t)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, tx.Commit()
+}
+
+func recordStatusChange(ctx context.Context, tx *sql.Tx, bookingID string, from *string, to string, changedBy *string) error {
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO booking_status_history (booking_id, from_status, to_status, changed_by)
+	VALUES ($1, $2, $3, $4)
+	`, bookingID, from, to, changedBy)
+	return err
+}
+
+// Transition moves a booking to another status when bookingTransitions allows
+// it, records who did it, and keeps the linked table in step: seating a party
+// marks the table occupied, and it becomes available again once the party
+// leaves. The table is returned only when its status changed.
+func (pg *PostgresBookingStore) Transition(ctx context.Context, id, to, changedBy string, scope Scope) (*Booking, *Table, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var from, tableID string
+	err = tx.QueryRowContext(ctx, `
+	SELECT b.status, b.table_id
+	FROM bookings b
+	JOIN tables t ON t.id = b.table_id
+	WHERE b.id = $1 AND ($2::uuid[] IS NULL OR t.restaurant_id = ANY($2))
+	FOR UPDATE OF b
+	`, id, scope.Arg()).Scan(&from, &tableID)
+	if err == sql.ErrNoRows {
+		return nil, nil, notFound("booking")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !CanTransition(from, to) {
+		return nil, nil, ErrIllegalTransition
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE bookings
+	SET status = $1, status_changed_at = CURRENT_TIMESTAMP, status_changed_by = $2
+	WHERE id = $3
+	`, to, changedBy, id)
+	if err != nil {
+		return nil, nil, mapBookingError(err)
+	}
+
+	err = recordStatusChange(ctx, tx, id, &from, to, &changedBy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tableMoved bool
+	switch {
+	case to == BookingStatusSeated:
+		tableMoved, err = moveTable(tx, tableID, TableStatusOccupied)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !tableMoved {
+			return nil, nil, ErrIllegalTableTransition
+		}
+	case from == BookingStatusSeated:
+		// Staff may already have moved the table on, e.g. to cleaning.
+		tableMoved, err = moveTable(tx, tableID, TableStatusAvailable)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	b := &Booking{}
+	err = scanBooking(tx.QueryRowContext(ctx, bookingSelect+`WHERE b.id = $1`, id), b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var t *Table
+	if tableMoved {
+		t = &Table{}
+		err = scanTable(tx.QueryRowContext(ctx, tableSelect+`WHERE id = $1`, tableID), t)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return b, t, tx.Commit()
+}
+
+// History returns the status changes of a booking, oldest first.
+func (pg *PostgresBookingStore) History(ctx context.Context, id string, scope Scope) ([]BookingStatusChange, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	q := `
+	SELECT h.id, h.booking_id, h.from_status, h.to_status, h.changed_by, h.changed_at
+	FROM booking_status_history h
+	JOIN bookings b ON b.id = h.booking_id
+	JOIN tables t ON t.id = b.table_id
+	WHERE h.booking_id = $1 AND ($2::uuid[] IS NULL OR t.restaurant_id = ANY($2))
+	ORDER BY h.changed_at, h.id
+	`
+	rows, err := pg.db.QueryContext(ctx, q, id, scope.Arg())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []BookingStatusChange{}
+	for rows.Next() {
+		var c BookingStatusChange
+		err := rows.Scan(&c.ID, &c.BookingID, &c.FromStatus, &c.ToStatus, &c.ChangedBy, &c.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+
+	return list, rows.Err()
+}
+
+// Delete removes a booking. Deleting a seated booking frees its table, which
+// is returned only when its status changed.
+func (pg *PostgresBookingStore) Delete(ctx context.Context, id string, scope Scope) (*Table, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status, tableID string
+	err = tx.QueryRowContext(ctx, `
+	SELECT b.status, b.table_id
+	FROM bookings b
+	JOIN tables t ON t.id = b.table_id
+	WHERE b.id = $1 AND ($2::uuid[] IS NULL OR t.restaurant_id = ANY($2))
+	FOR UPDATE OF b
+	`, id, scope.Arg()).Scan(&status, &tableID)
+	if err == sql.ErrNoRows {
+		return nil, notFound("booking")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM bookings WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var t *Table
+	if status == BookingStatusSeated {
+		moved, err := moveTable(tx, tableID, TableStatusAvailable)
+		if err != nil {
+			return nil, err
+		}
+		if moved {
+			t = &Table{}
+			err = scanTable(tx.QueryRowContext(ctx, tableSelect+`WHERE id = $1`, tableID), t)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t, tx.Commit()
+}
+
+type AvailabilityParams struct {
+	RestaurantID    string
+	PartySize       int
+	From            time.Time
+	To              time.Time
+	DurationMinutes int
+	IntervalMinutes int
+}
+
+type AvailableTable struct {
+	ID          string `json:"id"`
+	TableNumber string `json:"table_number"`
+	Capacity    int    `json:"capacity"`
+}
+
+type Slot struct {
+	StartsAt time.Time        `json:"starts_at"`
+	EndsAt   time.Time        `json:"ends_at"`
+	Tables   []AvailableTable `json:"tables"`
+}
+
+// Availability walks the window in IntervalMinutes steps and returns every
+// start time in [From, To) at which at least one table suiting the party is
+// free for the whole duration. A slot may end after To. A table is taken when it has a pending, confirmed or seated
+// booking overlapping the slot, which mirrors ex_bookings_table_overlap.
+// Slots the opening hours do not fully cover are left out.
+func (pg *PostgresBookingStore) Availability(ctx context.Context, params AvailabilityParams) ([]Slot, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(params.RestaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	q := `
+	WITH slots AS (
+		SELECT s AS starts_at, s + make_interval(mins => $5) AS ends_at
+		FROM generate_series($3::timestamptz, $4::timestamptz, make_interval(mins => $6)) AS s
+		WHERE s < $4
+	)
+	SELECT s.starts_at, s.ends_at, t.id, t.table_number, t.capacity
+	FROM slots s
+	CROSS JOIN tables t
+	WHERE t.restaurant_id = $1
+		AND t.status <> 'out_of_service'
+		AND $2 BETWEEN t.min_party_size AND t.max_party_size
+		AND NOT EXISTS (
+			SELECT 1 FROM bookings b
+			WHERE b.table_id = t.id
+				AND b.status IN ('pending', 'confirmed', 'seated')
+				AND tstzrange(b.booking_time, b.ends_at, '[)') && tstzrange(s.starts_at, s.ends_at, '[)')
+		)
+	ORDER BY s.starts_at, t.capacity, t.table_number
+	`
+	hours, err := loadHours(ctx, pg.db, params.RestaurantID)
+	if err == sql.ErrNoRows {
+		return []Slot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.db.QueryContext(ctx, q,
+		params.RestaurantID,
+		params.PartySize,
+		params.From,
+		params.To,
+		params.DurationMinutes,
+		params.IntervalMinutes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := []Slot{}
+	for rows.Next() {
+		var startsAt, endsAt time.Time
+		var t AvailableTable
+		err := rows.Scan(&startsAt, &endsAt, &t.ID, &t.TableNumber, &t.Capacity)
+		if err != nil {
+			return nil, err
+		}
+
+		if !hours.Covers(startsAt, endsAt) {
+			continue
+		}
+
+		if n := len(slots); n == 0 || !slots[n-1].StartsAt.Equal(startsAt) {
+			slots = append(slots, Slot{StartsAt: startsAt, EndsAt: endsAt})
+		}
+		last := &slots[len(slots)-1]
+		last.Tables = append(last.Tables, t)
+	}
+
+	return slots, rows.Err()
+}
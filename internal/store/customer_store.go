@@ -0,0 +1,405 @@
+package store
+
+import (
+	"database/sql"
+	"htrr-apis/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateCustomer       = conflict("a customer with this phone or email already exists")
+	ErrCustomerWrongRestaurant = invalid("customer_id", "customer belongs to another restaurant")
+	ErrCustomerNotFound        = invalid("customer_id", "customer does not exist")
+)
+
+type PostgresCustomerStore struct {
+	db *sql.DB
+}
+
+func NewPostgresCustomerStore(db *sql.DB) *PostgresCustomerStore {
+	return &PostgresCustomerStore{
+		db: db,
+	}
+}
+
+type Customer struct {
+	ID           string    `json:"id"`
+	RestaurantID string    `json:"restaurant_id"`
+	FullName     string    `json:"full_name"`
+	Phone        string    `json:"phone"`
+	Email        string    `json:"email"`
+	Notes        string    `json:"notes"`
+	Allergies    []string  `json:"allergies"`
+	Tags         []string  `json:"tags"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CustomerStats counts the bookings of a customer by outcome.
+type CustomerStats struct {
+	Visits        int        `json:"visits"`
+	NoShows       int        `json:"no_shows"`
+	Cancellations int        `json:"cancellations"`
+	Upcoming      int        `json:"upcoming"`
+	LastVisitAt   *time.Time `json:"last_visit_at"`
+}
+
+type CustomerHistory struct {
+	Customer *Customer     `json:"customer"`
+	Stats    CustomerStats `json:"stats"`
+	Bookings []Booking     `json:"bookings"`
+}
+
+type ListCustomerParams struct {
+	Page         int
+	PageSize     int
+	RestaurantID string
+	Query        string
+	Tag          string
+	Scope        Scope
+}
+
+type CustomerStore interface {
+	Create(*Customer, Scope) error
+	List(ListCustomerParams) ([]Customer, int, error)
+	GetById(string, Scope) (*Customer, error)
+	Update(*Customer, Scope) error
+	Delete(string, Scope) error
+	History(string, Scope) (*CustomerHistory, error)
+}
+
+// NormalizePhone keeps the digits and a leading plus, so "+1 (555) 010-0199"
+// and "+15550100199" dedupe to the same customer.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if (r >= '0' && r <= '9') || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+const customerSelect = `
+	SELECT c.id, c.restaurant_id, c.full_name, COALESCE(c.phone, ''), COALESCE(c.email, ''),
+			c.notes, c.allergies, c.tags, c.created_at, c.updated_at
+	FROM customers c
+	`
+
+func scanCustomer(row interface{ Scan(...any) error }, c *Customer, extra ...any) error {
+	dest := []any{
+		&c.ID,
+		&c.RestaurantID,
+		&c.FullName,
+		&c.Phone,
+		&c.Email,
+		&c.Notes,
+		pq.Array(&c.Allergies),
+		pq.Array(&c.Tags),
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if c.Allergies == nil {
+		c.Allergies = []string{}
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	return err
+}
+
+func mapCustomerError(err error) error {
+	if pgCode(err) == pgUniqueViolation {
+		return ErrDuplicateCustomer
+	}
+	return err
+}
+
+// resolveCustomer finds the customer of a restaurant with the given phone or
+// email, preferring a phone match, and creates one when there is none. It
+// returns nil when neither phone nor email is given.
+func resolveCustomer(tx *sql.Tx, restaurantID, name, phone, email string) (*string, error) {
+	phone, email = NormalizePhone(phone), NormalizeEmail(email)
+	if phone == "" && email == "" {
+		return nil, nil
+	}
+
+	find := `
+	SELECT id FROM customers
+	WHERE restaurant_id = $1
+		AND (($2 <> '' AND phone = $2) OR ($3 <> '' AND email = $3))
+	ORDER BY ($2 <> '' AND phone = $2) DESC
+	LIMIT 1
+	`
+
+	var id string
+	err := tx.QueryRow(find, restaurantID, phone, email).Scan(&id)
+	if err == nil {
+		return &id, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// A concurrent request may create the same guest first; ON CONFLICT
+	// then skips the insert and the lookup below finds their row.
+	err = tx.QueryRow(`
+	INSERT INTO customers (restaurant_id, full_name, phone, email)
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+	ON CONFLICT DO NOTHING
+	RETURNING id
+	`, restaurantID, name, phone, email).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow(find, restaurantID, phone, email).Scan(&id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// checkCustomer makes sure a customer picked for a booking belongs to the
+// same restaurant as the table.
+func checkCustomer(tx *sql.Tx, customerID *string, restaurantID string) error {
+	if customerID == nil {
+		return nil
+	}
+
+	_, err := uuid.Parse(*customerID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	var owner string
+	err = tx.QueryRow(`SELECT restaurant_id FROM customers WHERE id = $1`, *customerID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return ErrCustomerNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if owner != restaurantID {
+		return ErrCustomerWrongRestaurant
+	}
+
+	return nil
+}
+
+// Create inserts the customer only when its restaurant is inside the scope.
+// It returns ErrNotFound when the restaurant is missing or out of scope.
+func (pg *PostgresCustomerStore) Create(c *Customer, scope Scope) error {
+	_, err := uuid.Parse(c.RestaurantID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	c.Phone, c.Email = NormalizePhone(c.Phone), NormalizeEmail(c.Email)
+
+	q := `
+	INSERT INTO customers (restaurant_id, full_name, phone, email, notes, allergies, tags)
+	SELECT r.id, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7
+	FROM restaurants r
+	WHERE r.id = $1 AND ($8::uuid[] IS NULL OR r.id = ANY($8))
+	RETURNING id, created_at, updated_at
+	`
+	err = pg.db.QueryRow(q,
+		c.RestaurantID,
+		c.FullName,
+		c.Phone,
+		c.Email,
+		c.Notes,
+		pq.Array(c.Allergies),
+		pq.Array(c.Tags),
+		scope.Arg()).
+		Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFound("restaurant")
+	}
+	if err != nil {
+		return mapCustomerError(err)
+	}
+
+	return nil
+}
+
+// List searches customers by name, phone or email and optionally by tag.
+func (pg *PostgresCustomerStore) List(params ListCustomerParams) ([]Customer, int, error) {
+	q := `
+	SELECT c.id, c.restaurant_id, c.full_name, COALESCE(c.phone, ''), COALESCE(c.email, ''),
+			c.notes, c.allergies, c.tags, c.created_at, c.updated_at,
+			COUNT(*) OVER()
+	FROM customers c
+	WHERE ($1 = '' OR c.restaurant_id::text = $1)
+		AND ($2 = '' OR c.full_name ILIKE $2 ESCAPE '\'
+			OR c.phone LIKE $2 ESCAPE '\'
+			OR c.email ILIKE $2 ESCAPE '\')
+		AND ($3 = '' OR $3 = ANY(c.tags))
+		AND ($4::uuid[] IS NULL OR c.restaurant_id = ANY($4))
+	ORDER BY c.full_name
+	LIMIT $5 OFFSET $6
+	`
+	limit, offset := utils.GetOffset(&params.Page, &params.PageSize)
+
+	rows, err := pg.db.Query(q,
+		params.RestaurantID,
+		containsPattern(params.Query),
+		params.Tag,
+		params.Scope.Arg(),
+		limit,
+		offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var total int
+	list := []Customer{}
+	for rows.Next() {
+		var c Customer
+		if err := scanCustomer(rows, &c, &total); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, c)
+	}
+
+	return list, total, rows.Err()
+}
+
+func (pg *PostgresCustomerStore) GetById(id string, scope Scope) (*Customer, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	q := customerSelect + `
+	WHERE c.id = $1 AND ($2::uuid[] IS NULL OR c.restaurant_id = ANY($2))
+	`
+	c := &Customer{}
+	err = scanCustomer(pg.db.QueryRow(q, id, scope.Arg()), c)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("customer")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (pg *PostgresCustomerStore) Update(c *Customer, scope Scope) error {
+	_, err := uuid.Parse(c.ID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	c.Phone, c.Email = NormalizePhone(c.Phone), NormalizeEmail(c.Email)
+
+	q := `
+	UPDATE customers
+	SET full_name = $1, phone = NULLIF($2, ''), email = NULLIF($3, ''), notes = $4,
+		allergies = $5, tags = $6
+	WHERE id = $7 AND ($8::uuid[] IS NULL OR restaurant_id = ANY($8))
+	RETURNING updated_at
+	`
+	err = pg.db.QueryRow(q,
+		c.FullName,
+		c.Phone,
+		c.Email,
+		c.Notes,
+		pq.Array(c.Allergies),
+		pq.Array(c.Tags),
+		c.ID,
+		scope.Arg()).
+		Scan(&c.UpdatedAt)
+	if err != nil {
+		return mapCustomerError(err)
+	}
+
+	return nil
+}
+
+func (pg *PostgresCustomerStore) Delete(id string, scope Scope) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := pg.db.Exec(`
+	DELETE FROM customers WHERE id = $1 AND ($2::uuid[] IS NULL OR restaurant_id = ANY($2))
+	`, id, scope.Arg())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("customer")
+	}
+
+	return nil
+}
+
+// History returns a customer with all their bookings, newest first, and how
+// those bookings ended. A visit is a completed booking.
+func (pg *PostgresCustomerStore) History(id string, scope Scope) (*CustomerHistory, error) {
+	c, err := pg.GetById(id, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.db.Query(bookingSelect+`
+	WHERE b.customer_id = $1
+	ORDER BY b.booking_time DESC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	h := &CustomerHistory{Customer: c, Bookings: []Booking{}}
+	now := time.Now()
+	for rows.Next() {
+		var b Booking
+		if err := scanBooking(rows, &b); err != nil {
+			return nil, err
+		}
+
+		switch b.Status {
+		case BookingStatusCompleted:
+			h.Stats.Visits++
+			if h.Stats.LastVisitAt == nil {
+				visit := b.BookingTime
+				h.Stats.LastVisitAt = &visit
+			}
+		case BookingStatusNoShow:
+			h.Stats.NoShows++
+		case BookingStatusCancelled:
+			h.Stats.Cancellations++
+		case BookingStatusPending, BookingStatusConfirmed:
+			if b.BookingTime.After(now) {
+				h.Stats.Upcoming++
+			}
+		}
+
+		h.Bookings = append(h.Bookings, b)
+	}
+
+	return h, rows.Err()
+}
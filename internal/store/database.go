@@ -1,8 +1,11 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"io/fs"
+	"strings"
+	"time"
 
 	_ "github.com/jackc/pgx/v4/stdlib"
 
@@ -11,6 +14,28 @@ import (
 	"fmt"
 )
 
+// queryTimeout bounds every store call on top of the request's own
+// cancellation, so a stuck query gives its connection back.
+const queryTimeout = 5 * time.Second
+
+// withQueryTimeout returns the context a store call runs its statements in.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// likeEscaper escapes the LIKE wildcards in user input, for patterns
+// matched with `ESCAPE '\'`.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns a LIKE pattern matching values that contain s
+// literally.
+func containsPattern(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
 func Open(dataSourceName string) (*sql.DB, error) {
 	fmt.Println("Connecting to database...")
 
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"database/sql"
+	"htrr-apis/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmployeeUserTaken      = conflict("user is already linked to another employee")
+	ErrEmployeeInvalidLinkage = invalid("", "linked user or position does not exist")
+)
+
+type PostgresEmployeeStore struct {
+	db *sql.DB
+}
+
+func NewPostgresEmployeeStore(db *sql.DB) *PostgresEmployeeStore {
+	return &PostgresEmployeeStore{
+		db: db,
+	}
+}
+
+// Employee belongs to one restaurant. The user and position links are
+// optional: the schema sets them to NULL when the user or position is
+// deleted, so the employee record survives and just loses the link.
+type Employee struct {
+	ID            string    `json:"id"`
+	RestaurantID  string    `json:"restaurant_id"`
+	UserID        *string   `json:"user_id"`
+	UserEmail     *string   `json:"user_email"`
+	PositionID    *string   `json:"position_id"`
+	PositionTitle *string   `json:"position_title"`
+	FullName      string    `json:"full_name"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type ListEmployeeParams struct {
+	Page         int
+	PageSize     int
+	RestaurantID string
+	PositionID   string
+	Scope        Scope
+}
+
+type EmployeeStore interface {
+	Create(*Employee, Scope) error
+	List(ListEmployeeParams) ([]Employee, int, error)
+	GetById(string, Scope) (*Employee, error)
+	Update(*Employee, Scope) error
+	Delete(string, Scope) error
+}
+
+const employeeSelect = `
+	SELECT e.id, e.restaurant_id, e.user_id, u.email, e.position_id, p.title,
+			e.full_name, e.created_at, e.updated_at
+	FROM employees e
+	LEFT JOIN users u ON u.id = e.user_id
+	LEFT JOIN positions p ON p.id = e.position_id
+	`
+
+func scanEmployee(row interface{ Scan(...any) error }, emp *Employee, extra ...any) error {
+	dest := []any{
+		&emp.ID,
+		&emp.RestaurantID,
+		&emp.UserID,
+		&emp.UserEmail,
+		&emp.PositionID,
+		&emp.PositionTitle,
+		&emp.FullName,
+		&emp.CreatedAt,
+		&emp.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func validEmployeeLinks(emp *Employee) bool {
+	for _, id := range []*string{emp.UserID, emp.PositionID} {
+		if id == nil {
+			continue
+		}
+		if _, err := uuid.Parse(*id); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func mapEmployeeError(err error) error {
+	switch pgCode(err) {
+	case pgUniqueViolation:
+		return ErrEmployeeUserTaken
+	case pgForeignKeyViolation:
+		return ErrEmployeeInvalidLinkage
+	}
+	return err
+}
+
+// Create inserts the employee only when its restaurant is inside the scope.
+// It returns ErrNotFound when the restaurant is missing or out of scope.
+func (pg *PostgresEmployeeStore) Create(emp *Employee, scope Scope) error {
+	_, err := uuid.Parse(emp.RestaurantID)
+	if err != nil || !validEmployeeLinks(emp) {
+		return ErrInvalidID
+	}
+
+	q := `
+	INSERT INTO employees (restaurant_id, user_id, position_id, full_name)
+	SELECT r.id, $2, $3, $4
+	FROM restaurants r
+	WHERE r.id = $1 AND ($5::uuid[] IS NULL OR r.id = ANY($5))
+	RETURNING id
+	`
+	err = pg.db.QueryRow(q,
+		emp.RestaurantID,
+		emp.UserID,
+		emp.PositionID,
+		emp.FullName,
+		scope.Arg()).
+		Scan(&emp.ID)
+	if err == sql.ErrNoRows {
+		return notFound("restaurant")
+	}
+	if err != nil {
+		return mapEmployeeError(err)
+	}
+
+	created, err := pg.GetById(emp.ID, UnscopedAccess)
+	if err != nil {
+		return err
+	}
+	*emp = *created
+
+	return nil
+}
+
+func (pg *PostgresEmployeeStore) List(params ListEmployeeParams) ([]Employee, int, error) {
+	q := `
+	SELECT e.id, e.restaurant_id, e.user_id, u.email, e.position_id, p.title,
+			e.full_name, e.created_at, e.updated_at,
+			COUNT(*) OVER()
+	FROM employees e
+	LEFT JOIN users u ON u.id = e.user_id
+	LEFT JOIN positions p ON p.id = e.position_id
+	WHERE ($1 = '' OR e.restaurant_id::text = $1)
+		AND ($2 = '' OR e.position_id::text = $2)
+		AND ($3::uuid[] IS NULL OR e.restaurant_id = ANY($3))
+	ORDER BY e.full_name
+	LIMIT $4 OFFSET $5
+	`
+	limit, offset := utils.GetOffset(&params.Page, &params.PageSize)
+
+	rows, err := pg.db.Query(q,
+		params.RestaurantID,
+		params.PositionID,
+		params.Scope.Arg(),
+		limit,
+		offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var total int
+	list := []Employee{}
+	for rows.Next() {
+		var emp Employee
+		if err := scanEmployee(rows, &emp, &total); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, emp)
+	}
+
+	return list, total, rows.Err()
+}
+
+func (pg *PostgresEmployeeStore) GetById(id string, scope Scope) (*Employee, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	q := employeeSelect + `
+	WHERE e.id = $1 AND ($2::uuid[] IS NULL OR e.restaurant_id = ANY($2))
+	`
+	emp := &Employee{}
+	err = scanEmployee(pg.db.QueryRow(q, id, scope.Arg()), emp)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("employee")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return emp, nil
+}
+
+// Update writes the name and the user and position links. The restaurant of
+// an employee cannot change.
+func (pg *PostgresEmployeeStore) Update(emp *Employee, scope Scope) error {
+	_, err := uuid.Parse(emp.ID)
+	if err != nil || !validEmployeeLinks(emp) {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE employees
+	SET full_name = $1, user_id = $2, position_id = $3
+	WHERE id = $4 AND ($5::uuid[] IS NULL OR restaurant_id = ANY($5))
+	`
+	result, err := pg.db.Exec(q,
+		emp.FullName,
+		emp.UserID,
+		emp.PositionID,
+		emp.ID,
+		scope.Arg())
+	if err != nil {
+		return mapEmployeeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("employee")
+	}
+
+	updated, err := pg.GetById(emp.ID, UnscopedAccess)
+	if err != nil {
+		return err
+	}
+	*emp = *updated
+
+	return nil
+}
+
+func (pg *PostgresEmployeeStore) Delete(id string, scope Scope) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	DELETE FROM employees
+	WHERE id = $1 AND ($2::uuid[] IS NULL OR restaurant_id = ANY($2))
+	`
+	result, err := pg.db.Exec(q, id, scope.Arg())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("employee")
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgconn"
+)
+
+// The kinds of error a store returns. Every error a store method reports
+// on purpose is, or wraps, one of them, so callers can tell what went wrong
+// with errors.Is without matching messages. Anything else is a failure of
+// the database itself.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrInvalidID  = errors.New("invalid id format")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+)
+
+// kindError is an error of one kind with its own message.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+// notFound reports that the resource, named as it is in messages to
+// clients, does not exist or is out of the caller's reach.
+func notFound(resource string) error {
+	return &kindError{kind: ErrNotFound, msg: resource + " not found"}
+}
+
+// conflict reports a request that clashes with the current state of the
+// data, such as a duplicate or a status change that is not allowed.
+func conflict(msg string) error {
+	return &kindError{kind: ErrConflict, msg: msg}
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is a request that cannot be carried out as given, with
+// the fields at fault. Field is empty when the fault is not in one field.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
+
+// invalid reports a single field at fault.
+func invalid(field, msg string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: msg}}}
+}
+
+// Postgres error codes the stores translate.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+	pgExclusionViolation  = "23P01"
+	pgInvalidText         = "22P02"
+)
+
+// pgCode returns the Postgres error code behind err, or "".
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// Classify gives database errors that no store translated itself one of
+// the kinds above: a missing row becomes ErrNotFound, unique violations
+// conflicts, foreign key violations conflicts with the rows they refer to,
+// check violations validation errors and malformed uuids ErrInvalidID.
+// Other errors are returned as they are.
+func Classify(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("record")
+	}
+
+	switch pgCode(err) {
+	case pgUniqueViolation:
+		return conflict("a record with these values already exists")
+	case pgForeignKeyViolation:
+		return conflict("the record refers to a record that does not exist, or is still referred to")
+	case pgCheckViolation:
+		return invalid("", "a value is out of the allowed range")
+	case pgInvalidText:
+		return ErrInvalidID
+	}
+	return err
+}
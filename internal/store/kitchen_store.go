@@ -0,0 +1,697 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"htrr-apis/internal/validate"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	TicketStatusQueued    = "queued"
+	TicketStatusPreparing = "preparing"
+	TicketStatusReady     = "ready"
+	TicketStatusServed    = "served"
+)
+
+// DefaultStation takes every item of a restaurant that has not configured
+// any station.
+const DefaultStation = "kitchen"
+
+// servedWindow is how far back served tickets are listed, so a screen can
+// recall what it bumped recently.
+const servedWindow = time.Hour
+
+var (
+	ErrTicketServed        = conflict("ticket item has already been served")
+	ErrTicketItemVoided    = conflict("ticket item was voided")
+	ErrRouteTargetNotFound = invalid("routes", "route target does not exist in this restaurant")
+)
+
+// ticketFlow is the order in which ticket items move through the kitchen.
+var ticketFlow = []string{TicketStatusQueued, TicketStatusPreparing, TicketStatusReady, TicketStatusServed}
+
+// ticketTimestamps names the column stamped when an item or ticket reaches a
+// status.
+var ticketTimestamps = map[string]string{
+	TicketStatusPreparing: "started_at",
+	TicketStatusReady:     "ready_at",
+	TicketStatusServed:    "served_at",
+}
+
+func ticketRank(status string) int {
+	for i, s := range ticketFlow {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+func IsValidTicketStatus(status string) bool {
+	return ticketRank(status) >= 0
+}
+
+// nextTicketStatus returns the status a bump moves an item to.
+func nextTicketStatus(status string) (string, bool) {
+	i := ticketRank(status)
+	if i < 0 || i == len(ticketFlow)-1 {
+		return "", false
+	}
+	return ticketFlow[i+1], true
+}
+
+type PostgresKitchenStore struct {
+	db *sql.DB
+}
+
+func NewPostgresKitchenStore(db *sql.DB) *PostgresKitchenStore {
+	return &PostgresKitchenStore{
+		db: db,
+	}
+}
+
+type KitchenStation struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	IsDefault bool   `json:"is_default"`
+	Position  int    `json:"position"`
+}
+
+// KitchenRoute sends a menu category or a single menu item to a station.
+// Exactly one of CategoryID and MenuItemID is set.
+type KitchenRoute struct {
+	Station    string  `json:"station"`
+	CategoryID *string `json:"category_id"`
+	MenuItemID *string `json:"menu_item_id"`
+}
+
+// KitchenRouting is the station setup of a restaurant. Items are routed by
+// their own route first, then by their category's, then to the default
+// station, and to DefaultStation when the restaurant has none.
+type KitchenRouting struct {
+	Stations []KitchenStation `json:"stations"`
+	Routes   []KitchenRoute   `json:"routes"`
+}
+
+// KitchenTicket holds the items of one send of an order that go to one
+// station. Its status is that of its least advanced item that is not voided.
+type KitchenTicket struct {
+	ID             string              `json:"id"`
+	RestaurantID   string              `json:"restaurant_id"`
+	OrderID        string              `json:"order_id"`
+	TableID        *string             `json:"table_id"`
+	TableNumber    string              `json:"table_number"`
+	Station        string              `json:"station"`
+	Status         string              `json:"status"`
+	FiredAt        time.Time           `json:"fired_at"`
+	StartedAt      *time.Time          `json:"started_at"`
+	ReadyAt        *time.Time          `json:"ready_at"`
+	ServedAt       *time.Time          `json:"served_at"`
+	ElapsedSeconds int64               `json:"elapsed_seconds"`
+	Items          []KitchenTicketItem `json:"items"`
+}
+
+type KitchenTicketItem struct {
+	ID          string     `json:"id"`
+	TicketID    string     `json:"ticket_id"`
+	OrderItemID string     `json:"order_item_id"`
+	Name        string     `json:"name"`
+	Quantity    int        `json:"quantity"`
+	Notes       string     `json:"notes"`
+	Modifiers   []string   `json:"modifiers"`
+	Status      string     `json:"status"`
+	StartedAt   *time.Time `json:"started_at"`
+	ReadyAt     *time.Time `json:"ready_at"`
+	ServedAt    *time.Time `json:"served_at"`
+	VoidedAt    *time.Time `json:"voided_at"`
+}
+
+// ListTicketParams filters tickets. An empty Status lists the tickets still
+// in the kitchen; "served" lists those served within servedWindow.
+type ListTicketParams struct {
+	RestaurantID string
+	Station      string
+	Status       string
+}
+
+type KitchenStore interface {
+	GetRouting(restaurantID string) (*KitchenRouting, error)
+	ReplaceRouting(restaurantID string, routing *KitchenRouting) error
+	ListTickets(ListTicketParams) ([]KitchenTicket, error)
+	GetTicket(restaurantID, id string) (*KitchenTicket, error)
+	BumpTicket(restaurantID, id string) (*KitchenTicket, error)
+	BumpItem(restaurantID, ticketID, itemID string) (*KitchenTicket, error)
+}
+
+func isStationCode(code string) bool {
+	if code == "" || len(code) > 50 {
+		return false
+	}
+	for _, r := range code {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func (k *KitchenRouting) Validate() error {
+	v := validate.New()
+	stations := map[string]bool{}
+	defaults := 0
+	for i, s := range k.Stations {
+		field := fmt.Sprintf("stations[%d]", i)
+		v.Check(isStationCode(s.Code), field+".code",
+			fmt.Sprintf("station code %q must be 1 to 50 lowercase letters, digits, '_' or '-'", s.Code))
+		v.Check(!stations[s.Code], field+".code", fmt.Sprintf("station %s is listed twice", s.Code))
+		v.Check(s.Name != "" && len(s.Name) <= 255, field+".name",
+			fmt.Sprintf("station %s needs a name of at most 255 characters", s.Code))
+		if s.IsDefault {
+			defaults++
+		}
+		stations[s.Code] = true
+	}
+	v.Check(defaults <= 1, "stations", "only one station can be the default")
+
+	targets := map[string]bool{}
+	for i, r := range k.Routes {
+		field := fmt.Sprintf("routes[%d]", i)
+		v.Check(stations[r.Station], field+".station", fmt.Sprintf("route to unknown station %q", r.Station))
+		if (r.CategoryID == nil) == (r.MenuItemID == nil) {
+			v.Add(field, "each route needs exactly one of category_id and menu_item_id")
+			continue
+		}
+
+		target, targetField := "", field+".category_id"
+		if r.CategoryID != nil {
+			target = *r.CategoryID
+		} else {
+			target, targetField = *r.MenuItemID, field+".menu_item_id"
+		}
+		_, err := uuid.Parse(target)
+		v.Check(err == nil, targetField, targetField+" must be a valid UUID")
+		v.Check(!targets[target], targetField, fmt.Sprintf("%s is routed twice", target))
+		targets[target] = true
+	}
+
+	return v.Err()
+}
+
+const kitchenTicketSelect = `
+	SELECT t.id, t.restaurant_id, t.order_id, t.table_id, COALESCE(tb.table_number, ''),
+			t.station, t.status, t.fired_at, t.started_at, t.ready_at, t.served_at
+	FROM kitchen_tickets t
+	LEFT JOIN tables tb ON tb.id = t.table_id
+	`
+
+// loadTickets returns the tickets matching the condition on kitchen_tickets t,
+// oldest first, with their items.
+func loadTickets(q queryer, where string, args ...any) ([]KitchenTicket, error) {
+	rows, err := q.Query(kitchenTicketSelect+where+` ORDER BY t.fired_at, t.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	tickets := []KitchenTicket{}
+	var ids []string
+	for rows.Next() {
+		var t KitchenTicket
+		err := rows.Scan(
+			&t.ID,
+			&t.RestaurantID,
+			&t.OrderID,
+			&t.TableID,
+			&t.TableNumber,
+			&t.Station,
+			&t.Status,
+			&t.FiredAt,
+			&t.StartedAt,
+			&t.ReadyAt,
+			&t.ServedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		end := now
+		if t.ServedAt != nil {
+			end = *t.ServedAt
+		}
+		t.ElapsedSeconds = int64(end.Sub(t.FiredAt).Seconds())
+		t.Items = []KitchenTicketItem{}
+
+		tickets = append(tickets, t)
+		ids = append(ids, t.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(`
+	SELECT k.id, k.ticket_id, k.order_item_id, k.name, k.quantity, k.notes, k.modifiers,
+			k.status, k.started_at, k.ready_at, k.served_at, k.voided_at
+	FROM kitchen_ticket_items k
+	JOIN order_items oi ON oi.id = k.order_item_id
+	WHERE k.ticket_id = ANY($1)
+	ORDER BY oi.created_at, k.id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := map[string]int{}
+	for n, t := range tickets {
+		index[t.ID] = n
+	}
+	for rows.Next() {
+		var i KitchenTicketItem
+		err := rows.Scan(
+			&i.ID,
+			&i.TicketID,
+			&i.OrderItemID,
+			&i.Name,
+			&i.Quantity,
+			&i.Notes,
+			pq.Array(&i.Modifiers),
+			&i.Status,
+			&i.StartedAt,
+			&i.ReadyAt,
+			&i.ServedAt,
+			&i.VoidedAt)
+		if err != nil {
+			return nil, err
+		}
+		if i.Modifiers == nil {
+			i.Modifiers = []string{}
+		}
+		n := index[i.TicketID]
+		tickets[n].Items = append(tickets[n].Items, i)
+	}
+
+	return tickets, rows.Err()
+}
+
+// fireTickets routes freshly sent order lines to their stations and opens one
+// ticket per station. It runs inside the transaction that sends the lines.
+func fireTickets(tx *sql.Tx, o *Order, itemIDs []string) ([]KitchenTicket, error) {
+	rows, err := tx.Query(`
+	SELECT i.id, i.name, i.quantity, i.notes,
+			ARRAY(SELECT m.name FROM order_item_modifiers m
+				WHERE m.order_item_id = i.id ORDER BY m.group_name, m.name),
+			COALESCE(ri.station_code, rc.station_code, ds.code, $2)
+	FROM order_items i
+	LEFT JOIN menu_items mi ON mi.id = i.menu_item_id
+	LEFT JOIN kitchen_routes ri ON ri.menu_item_id = i.menu_item_id
+	LEFT JOIN kitchen_routes rc ON rc.category_id = mi.category_id
+	LEFT JOIN kitchen_stations ds ON ds.restaurant_id = i.restaurant_id AND ds.is_default
+	WHERE i.id = ANY($1)
+	ORDER BY i.created_at, i.id
+	`, pq.Array(itemIDs), DefaultStation)
+	if err != nil {
+		return nil, err
+	}
+
+	type line struct {
+		orderItemID string
+		name        string
+		quantity    int
+		notes       string
+		modifiers   []string
+	}
+	var stations []string
+	lines := map[string][]line{}
+	for rows.Next() {
+		var l line
+		var station string
+		if err := rows.Scan(&l.orderItemID, &l.name, &l.quantity, &l.notes, pq.Array(&l.modifiers), &station); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if l.modifiers == nil {
+			l.modifiers = []string{}
+		}
+		if _, ok := lines[station]; !ok {
+			stations = append(stations, station)
+		}
+		lines[station] = append(lines[station], l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var ticketIDs []string
+	for _, station := range stations {
+		var ticketID string
+		err = tx.QueryRow(`
+		INSERT INTO kitchen_tickets (restaurant_id, order_id, table_id, station)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+		`, o.RestaurantID, o.ID, o.TableID, station).Scan(&ticketID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range lines[station] {
+			_, err = tx.Exec(`
+			INSERT INTO kitchen_ticket_items (ticket_id, order_item_id, name, quantity, notes, modifiers)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			`, ticketID, l.orderItemID, l.name, l.quantity, l.notes, pq.Array(l.modifiers))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ticketIDs = append(ticketIDs, ticketID)
+	}
+
+	return loadTickets(tx, `WHERE t.id = ANY($1)`, pq.Array(ticketIDs))
+}
+
+// refreshTicket sets the status of a ticket to that of its least advanced
+// item that is not voided, stamping the times it first reached each status.
+// A ticket whose items are all voided has nothing left to cook and counts as
+// served.
+func refreshTicket(tx *sql.Tx, ticketID string) (*KitchenTicket, error) {
+	rows, err := tx.Query(`
+	SELECT status FROM kitchen_ticket_items WHERE ticket_id = $1 AND voided_at IS NULL
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := TicketStatusServed
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if ticketRank(s) < ticketRank(status) {
+			status = s
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+	UPDATE kitchen_tickets
+	SET status = $1,
+		started_at = CASE WHEN $2 >= 1 THEN COALESCE(started_at, CURRENT_TIMESTAMP) END,
+		ready_at = CASE WHEN $2 >= 2 THEN COALESCE(ready_at, CURRENT_TIMESTAMP) END,
+		served_at = CASE WHEN $2 >= 3 THEN COALESCE(served_at, CURRENT_TIMESTAMP) END
+	WHERE id = $3
+	`, status, ticketRank(status), ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets, err := loadTickets(tx, `WHERE t.id = $1`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, notFound("ticket")
+	}
+
+	return &tickets[0], nil
+}
+
+// voidTicketItem marks the ticket item of a voided order line and returns its
+// refreshed ticket, or nil when the line never reached the kitchen.
+func voidTicketItem(tx *sql.Tx, orderItemID string) (*KitchenTicket, error) {
+	var ticketID string
+	err := tx.QueryRow(`
+	UPDATE kitchen_ticket_items SET voided_at = CURRENT_TIMESTAMP
+	WHERE order_item_id = $1 AND voided_at IS NULL
+	RETURNING ticket_id
+	`, orderItemID).Scan(&ticketID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return refreshTicket(tx, ticketID)
+}
+
+func (pg *PostgresKitchenStore) GetRouting(restaurantID string) (*KitchenRouting, error) {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	k := &KitchenRouting{Stations: []KitchenStation{}, Routes: []KitchenRoute{}}
+
+	rows, err := pg.db.Query(`
+	SELECT code, name, is_default, position
+	FROM kitchen_stations
+	WHERE restaurant_id = $1
+	ORDER BY position, code
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s KitchenStation
+		if err := rows.Scan(&s.Code, &s.Name, &s.IsDefault, &s.Position); err != nil {
+			return nil, err
+		}
+		k.Stations = append(k.Stations, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = pg.db.Query(`
+	SELECT station_code, category_id, menu_item_id
+	FROM kitchen_routes
+	WHERE restaurant_id = $1
+	ORDER BY station_code, category_id, menu_item_id
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r KitchenRoute
+		if err := rows.Scan(&r.Station, &r.CategoryID, &r.MenuItemID); err != nil {
+			return nil, err
+		}
+		k.Routes = append(k.Routes, r)
+	}
+
+	return k, rows.Err()
+}
+
+// ReplaceRouting overwrites the stations and routes of a restaurant in one
+// transaction. Tickets already fired keep their station.
+func (pg *PostgresKitchenStore) ReplaceRouting(restaurantID string, k *KitchenRouting) error {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM kitchen_stations WHERE restaurant_id = $1`, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range k.Stations {
+		_, err = tx.Exec(`
+		INSERT INTO kitchen_stations (restaurant_id, code, name, is_default, position)
+		VALUES ($1, $2, $3, $4, $5)
+		`, restaurantID, s.Code, s.Name, s.IsDefault, s.Position)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range k.Routes {
+		q := `
+		INSERT INTO kitchen_routes (restaurant_id, station_code, category_id)
+		SELECT $1, $2, id FROM menu_categories WHERE id = $3 AND restaurant_id = $1
+		`
+		target := r.CategoryID
+		if r.MenuItemID != nil {
+			q = `
+			INSERT INTO kitchen_routes (restaurant_id, station_code, menu_item_id)
+			SELECT $1, $2, id FROM menu_items WHERE id = $3 AND restaurant_id = $1
+			`
+			target = r.MenuItemID
+		}
+
+		result, err := tx.Exec(q, restaurantID, r.Station, *target)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrRouteTargetNotFound, *target)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresKitchenStore) ListTickets(params ListTicketParams) ([]KitchenTicket, error) {
+	_, err := uuid.Parse(params.RestaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	return loadTickets(pg.db, `
+	WHERE t.restaurant_id = $1
+		AND ($2 = '' OR t.station = $2)
+		AND (($3 = '' AND t.status <> 'served') OR t.status = $3)
+		AND (t.served_at IS NULL OR t.served_at > $4)
+	`, params.RestaurantID, params.Station, params.Status, time.Now().Add(-servedWindow))
+}
+
+func (pg *PostgresKitchenStore) GetTicket(restaurantID, id string) (*KitchenTicket, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	tickets, err := loadTickets(pg.db, `WHERE t.id = $1 AND t.restaurant_id = $2`, id, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tickets) == 0 {
+		return nil, notFound("ticket")
+	}
+
+	return &tickets[0], nil
+}
+
+// lockTicket holds a ticket until the transaction ends and returns its status.
+func lockTicket(tx *sql.Tx, restaurantID, id string) (string, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return "", ErrInvalidID
+	}
+
+	var status string
+	err = tx.QueryRow(`
+	SELECT status FROM kitchen_tickets WHERE id = $1 AND restaurant_id = $2 FOR UPDATE
+	`, id, restaurantID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", notFound("ticket")
+	}
+	return status, err
+}
+
+// BumpTicket moves every item at the ticket's current status one step on,
+// e.g. from queued to preparing for the whole ticket.
+func (pg *PostgresKitchenStore) BumpTicket(restaurantID, id string) (*KitchenTicket, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, err := lockTicket(tx, restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	next, ok := nextTicketStatus(status)
+	if !ok {
+		return nil, ErrTicketServed
+	}
+
+	_, err = tx.Exec(`
+	UPDATE kitchen_ticket_items SET status = $1, `+ticketTimestamps[next]+` = CURRENT_TIMESTAMP
+	WHERE ticket_id = $2 AND status = $3 AND voided_at IS NULL
+	`, next, id, status)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := refreshTicket(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
+
+// BumpItem moves a single item of a ticket one step on.
+func (pg *PostgresKitchenStore) BumpItem(restaurantID, ticketID, itemID string) (*KitchenTicket, error) {
+	_, err := uuid.Parse(itemID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = lockTicket(tx, restaurantID, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	var status string
+	var voided bool
+	err = tx.QueryRow(`
+	SELECT status, voided_at IS NOT NULL FROM kitchen_ticket_items WHERE id = $1 AND ticket_id = $2
+	`, itemID, ticketID).Scan(&status, &voided)
+	if err == sql.ErrNoRows {
+		return nil, notFound("ticket item")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if voided {
+		return nil, ErrTicketItemVoided
+	}
+
+	next, ok := nextTicketStatus(status)
+	if !ok {
+		return nil, ErrTicketServed
+	}
+
+	_, err = tx.Exec(`
+	UPDATE kitchen_ticket_items SET status = $1, `+ticketTimestamps[next]+` = CURRENT_TIMESTAMP
+	WHERE id = $2
+	`, next, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := refreshTicket(tx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
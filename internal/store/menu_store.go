@@ -0,0 +1,812 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PostgresMenuStore struct {
+	db *sql.DB
+}
+
+func NewPostgresMenuStore(db *sql.DB) *PostgresMenuStore {
+	return &PostgresMenuStore{
+		db: db,
+	}
+}
+
+// Menu is a named set of categories, such as "Lunch" or "Drinks". The nested
+// slices are only filled when the menu is read as part of a tree.
+type Menu struct {
+	ID           string         `json:"id"`
+	RestaurantID string         `json:"restaurant_id"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	IsActive     bool           `json:"is_active"`
+	Position     int            `json:"position"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Categories   []MenuCategory `json:"categories,omitempty"`
+}
+
+type MenuCategory struct {
+	ID           string     `json:"id"`
+	RestaurantID string     `json:"restaurant_id"`
+	MenuID       string     `json:"menu_id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Position     int        `json:"position"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Items        []MenuItem `json:"items,omitempty"`
+}
+
+// MenuItem prices are kept in minor units of Currency, e.g. 1250 USD is
+// $12.50, so totals never go through floating point.
+type MenuItem struct {
+	ID             string          `json:"id"`
+	RestaurantID   string          `json:"restaurant_id"`
+	CategoryID     string          `json:"category_id"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	PriceMinor     int64           `json:"price_minor"`
+	Currency       string          `json:"currency"`
+	IsAvailable    bool            `json:"is_available"`
+	DietaryTags    []string        `json:"dietary_tags"`
+	Position       int             `json:"position"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	ModifierGroups []ModifierGroup `json:"modifier_groups,omitempty"`
+}
+
+// ModifierGroup is a choice offered with an item, such as "Side" or
+// "Doneness", of which a guest picks between MinSelections and MaxSelections
+// modifiers.
+type ModifierGroup struct {
+	ID            string     `json:"id"`
+	RestaurantID  string     `json:"restaurant_id"`
+	ItemID        string     `json:"item_id"`
+	Name          string     `json:"name"`
+	MinSelections int        `json:"min_selections"`
+	MaxSelections int        `json:"max_selections"`
+	Position      int        `json:"position"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Modifiers     []Modifier `json:"modifiers,omitempty"`
+}
+
+// Modifier is one option of a group. PriceMinor is added to the item price.
+type Modifier struct {
+	ID           string    `json:"id"`
+	RestaurantID string    `json:"restaurant_id"`
+	GroupID      string    `json:"group_id"`
+	Name         string    `json:"name"`
+	PriceMinor   int64     `json:"price_minor"`
+	IsAvailable  bool      `json:"is_available"`
+	Position     int       `json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MenuStore keeps every row below a restaurant. Create methods return a
+// ValidationError on the parent's field when the parent row does not exist
+// in that restaurant.
+type MenuStore interface {
+	Tree(restaurantID string, activeOnly bool) ([]Menu, error)
+
+	CreateMenu(*Menu) error
+	ListMenus(restaurantID string) ([]Menu, error)
+	GetMenu(restaurantID, id string) (*Menu, error)
+	UpdateMenu(*Menu) error
+	DeleteMenu(restaurantID, id string) error
+
+	CreateCategory(*MenuCategory) error
+	GetCategory(restaurantID, id string) (*MenuCategory, error)
+	UpdateCategory(*MenuCategory) error
+	DeleteCategory(restaurantID, id string) error
+
+	CreateItem(*MenuItem) error
+	GetItem(restaurantID, id string) (*MenuItem, error)
+	UpdateItem(*MenuItem) error
+	DeleteItem(restaurantID, id string) error
+
+	CreateModifierGroup(*ModifierGroup) error
+	GetModifierGroup(restaurantID, id string) (*ModifierGroup, error)
+	UpdateModifierGroup(*ModifierGroup) error
+	DeleteModifierGroup(restaurantID, id string) error
+
+	CreateModifier(*Modifier) error
+	GetModifier(restaurantID, id string) (*Modifier, error)
+	UpdateModifier(*Modifier) error
+	DeleteModifier(restaurantID, id string) error
+}
+
+const (
+	menuSelect = `
+	SELECT id, restaurant_id, name, description, is_active, position, created_at, updated_at
+	FROM menus
+	`
+	menuCategorySelect = `
+	SELECT id, restaurant_id, menu_id, name, description, position, created_at, updated_at
+	FROM menu_categories
+	`
+	menuItemSelect = `
+	SELECT id, restaurant_id, category_id, name, description, price_minor, currency,
+			is_available, dietary_tags, position, created_at, updated_at
+	FROM menu_items
+	`
+	modifierGroupSelect = `
+	SELECT id, restaurant_id, item_id, name, min_selections, max_selections, position,
+			created_at, updated_at
+	FROM modifier_groups
+	`
+	modifierSelect = `
+	SELECT id, restaurant_id, group_id, name, price_minor, is_available, position,
+			created_at, updated_at
+	FROM modifiers
+	`
+)
+
+func scanMenu(row interface{ Scan(...any) error }, m *Menu) error {
+	return row.Scan(
+		&m.ID,
+		&m.RestaurantID,
+		&m.Name,
+		&m.Description,
+		&m.IsActive,
+		&m.Position,
+		&m.CreatedAt,
+		&m.UpdatedAt)
+}
+
+func scanMenuCategory(row interface{ Scan(...any) error }, c *MenuCategory) error {
+	return row.Scan(
+		&c.ID,
+		&c.RestaurantID,
+		&c.MenuID,
+		&c.Name,
+		&c.Description,
+		&c.Position,
+		&c.CreatedAt,
+		&c.UpdatedAt)
+}
+
+func scanMenuItem(row interface{ Scan(...any) error }, i *MenuItem) error {
+	err := row.Scan(
+		&i.ID,
+		&i.RestaurantID,
+		&i.CategoryID,
+		&i.Name,
+		&i.Description,
+		&i.PriceMinor,
+		&i.Currency,
+		&i.IsAvailable,
+		pq.Array(&i.DietaryTags),
+		&i.Position,
+		&i.CreatedAt,
+		&i.UpdatedAt)
+	if i.DietaryTags == nil {
+		i.DietaryTags = []string{}
+	}
+	return err
+}
+
+func scanModifierGroup(row interface{ Scan(...any) error }, g *ModifierGroup) error {
+	return row.Scan(
+		&g.ID,
+		&g.RestaurantID,
+		&g.ItemID,
+		&g.Name,
+		&g.MinSelections,
+		&g.MaxSelections,
+		&g.Position,
+		&g.CreatedAt,
+		&g.UpdatedAt)
+}
+
+func scanModifier(row interface{ Scan(...any) error }, m *Modifier) error {
+	return row.Scan(
+		&m.ID,
+		&m.RestaurantID,
+		&m.GroupID,
+		&m.Name,
+		&m.PriceMinor,
+		&m.IsAvailable,
+		&m.Position,
+		&m.CreatedAt,
+		&m.UpdatedAt)
+}
+
+// getMenuRow runs a single-row select limited to one restaurant. It returns
+// sql.ErrNoRows untouched so callers can name the row that is missing.
+func getMenuRow(q queryer, sel, restaurantID, id string, scan func(interface{ Scan(...any) error }) error) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	return scan(q.QueryRow(sel+`WHERE id = $1 AND restaurant_id = $2`, id, restaurantID))
+}
+
+// deleteMenuRow removes one row of a menu table; rows below it go with it
+// through ON DELETE CASCADE. resource names the row in the not found error.
+func (pg *PostgresMenuStore) deleteMenuRow(table, resource, restaurantID, id string) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := pg.db.Exec(`DELETE FROM `+table+` WHERE id = $1 AND restaurant_id = $2`, id, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound(resource)
+	}
+
+	return nil
+}
+
+// loadModifierGroups returns the groups of the given items, keyed by item id,
+// each with its modifiers.
+func loadModifierGroups(q queryer, restaurantID string, itemIDs []string) (map[string][]ModifierGroup, error) {
+	rows, err := q.Query(modifierSelect+`
+	WHERE restaurant_id = $1
+		AND group_id IN (SELECT id FROM modifier_groups WHERE item_id = ANY($2))
+	ORDER BY position, name
+	`, restaurantID, pq.Array(itemIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modifiers := map[string][]Modifier{}
+	for rows.Next() {
+		var m Modifier
+		if err := scanModifier(rows, &m); err != nil {
+			return nil, err
+		}
+		modifiers[m.GroupID] = append(modifiers[m.GroupID], m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(modifierGroupSelect+`
+	WHERE restaurant_id = $1 AND item_id = ANY($2)
+	ORDER BY position, name
+	`, restaurantID, pq.Array(itemIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := map[string][]ModifierGroup{}
+	for rows.Next() {
+		var g ModifierGroup
+		if err := scanModifierGroup(rows, &g); err != nil {
+			return nil, err
+		}
+		g.Modifiers = modifiers[g.ID]
+		groups[g.ItemID] = append(groups[g.ItemID], g)
+	}
+
+	return groups, rows.Err()
+}
+
+// Tree returns the menus of a restaurant with their categories, items,
+// modifier groups and modifiers, each level sorted by position then name.
+// activeOnly leaves out inactive menus; unavailable items are kept so guests
+// can see what is sold out.
+func (pg *PostgresMenuStore) Tree(restaurantID string, activeOnly bool) ([]Menu, error) {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	rows, err := pg.db.Query(menuItemSelect+`
+	WHERE restaurant_id = $1
+	ORDER BY position, name
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var itemIDs []string
+	var allItems []MenuItem
+	for rows.Next() {
+		var i MenuItem
+		if err := scanMenuItem(rows, &i); err != nil {
+			return nil, err
+		}
+		itemIDs = append(itemIDs, i.ID)
+		allItems = append(allItems, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	groups, err := loadModifierGroups(pg.db, restaurantID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items := map[string][]MenuItem{}
+	for _, i := range allItems {
+		i.ModifierGroups = groups[i.ID]
+		items[i.CategoryID] = append(items[i.CategoryID], i)
+	}
+
+	rows, err = pg.db.Query(menuCategorySelect+`
+	WHERE restaurant_id = $1
+	ORDER BY position, name
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := map[string][]MenuCategory{}
+	for rows.Next() {
+		var c MenuCategory
+		if err := scanMenuCategory(rows, &c); err != nil {
+			return nil, err
+		}
+		c.Items = items[c.ID]
+		categories[c.MenuID] = append(categories[c.MenuID], c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = pg.db.Query(menuSelect+`
+	WHERE restaurant_id = $1 AND (NOT $2 OR is_active)
+	ORDER BY position, name
+	`, restaurantID, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	menus := []Menu{}
+	for rows.Next() {
+		var m Menu
+		if err := scanMenu(rows, &m); err != nil {
+			return nil, err
+		}
+		m.Categories = categories[m.ID]
+		menus = append(menus, m)
+	}
+
+	return menus, rows.Err()
+}
+
+func (pg *PostgresMenuStore) CreateMenu(m *Menu) error {
+	_, err := uuid.Parse(m.RestaurantID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	INSERT INTO menus (restaurant_id, name, description, is_active, position)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at
+	`
+	return pg.db.QueryRow(q,
+		m.RestaurantID,
+		m.Name,
+		m.Description,
+		m.IsActive,
+		m.Position).
+		Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+}
+
+func (pg *PostgresMenuStore) ListMenus(restaurantID string) ([]Menu, error) {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	rows, err := pg.db.Query(menuSelect+`
+	WHERE restaurant_id = $1
+	ORDER BY position, name
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Menu{}
+	for rows.Next() {
+		var m Menu
+		if err := scanMenu(rows, &m); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+
+	return list, rows.Err()
+}
+
+func (pg *PostgresMenuStore) GetMenu(restaurantID, id string) (*Menu, error) {
+	m := &Menu{}
+	err := getMenuRow(pg.db, menuSelect, restaurantID, id, func(row interface{ Scan(...any) error }) error {
+		return scanMenu(row, m)
+	})
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("menu")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (pg *PostgresMenuStore) UpdateMenu(m *Menu) error {
+	_, err := uuid.Parse(m.ID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE menus
+	SET name = $1, description = $2, is_active = $3, position = $4
+	WHERE id = $5 AND restaurant_id = $6
+	RETURNING updated_at
+	`
+	err = pg.db.QueryRow(q,
+		m.Name,
+		m.Description,
+		m.IsActive,
+		m.Position,
+		m.ID,
+		m.RestaurantID).
+		Scan(&m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFound("menu")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) DeleteMenu(restaurantID, id string) error {
+	return pg.deleteMenuRow("menus", "menu", restaurantID, id)
+}
+
+func (pg *PostgresMenuStore) CreateCategory(c *MenuCategory) error {
+	_, err := uuid.Parse(c.MenuID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	INSERT INTO menu_categories (restaurant_id, menu_id, name, description, position)
+	SELECT restaurant_id, id, $3, $4, $5
+	FROM menus
+	WHERE id = $1 AND restaurant_id = $2
+	RETURNING id, created_at, updated_at
+	`
+	err = pg.db.QueryRow(q,
+		c.MenuID,
+		c.RestaurantID,
+		c.Name,
+		c.Description,
+		c.Position).
+		Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return invalid("menu_id", "menu_id does not exist in this restaurant")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) GetCategory(restaurantID, id string) (*MenuCategory, error) {
+	c := &MenuCategory{}
+	err := getMenuRow(pg.db, menuCategorySelect, restaurantID, id, func(row interface{ Scan(...any) error }) error {
+		return scanMenuCategory(row, c)
+	})
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("category")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (pg *PostgresMenuStore) UpdateCategory(c *MenuCategory) error {
+	_, err := uuid.Parse(c.ID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE menu_categories
+	SET name = $1, description = $2, position = $3
+	WHERE id = $4 AND restaurant_id = $5
+	RETURNING updated_at
+	`
+	err = pg.db.QueryRow(q,
+		c.Name,
+		c.Description,
+		c.Position,
+		c.ID,
+		c.RestaurantID).
+		Scan(&c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFound("category")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) DeleteCategory(restaurantID, id string) error {
+	return pg.deleteMenuRow("menu_categories", "category", restaurantID, id)
+}
+
+func (pg *PostgresMenuStore) CreateItem(i *MenuItem) error {
+	_, err := uuid.Parse(i.CategoryID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	INSERT INTO menu_items (restaurant_id, category_id, name, description, price_minor,
+			currency, is_available, dietary_tags, position)
+	SELECT restaurant_id, id, $3, $4, $5, $6, $7, $8, $9
+	FROM menu_categories
+	WHERE id = $1 AND restaurant_id = $2
+	RETURNING id, created_at, updated_at
+	`
+	err = pg.db.QueryRow(q,
+		i.CategoryID,
+		i.RestaurantID,
+		i.Name,
+		i.Description,
+		i.PriceMinor,
+		i.Currency,
+		i.IsAvailable,
+		pq.Array(i.DietaryTags),
+		i.Position).
+		Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return invalid("category_id", "category_id does not exist in this restaurant")
+	}
+	return err
+}
+
+// GetItem returns an item with its modifier groups and modifiers.
+func (pg *PostgresMenuStore) GetItem(restaurantID, id string) (*MenuItem, error) {
+	i := &MenuItem{}
+	err := getMenuRow(pg.db, menuItemSelect, restaurantID, id, func(row interface{ Scan(...any) error }) error {
+		return scanMenuItem(row, i)
+	})
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("item")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := loadModifierGroups(pg.db, restaurantID, []string{i.ID})
+	if err != nil {
+		return nil, err
+	}
+	i.ModifierGroups = groups[i.ID]
+
+	return i, nil
+}
+
+func (pg *PostgresMenuStore) UpdateItem(i *MenuItem) error {
+	_, err := uuid.Parse(i.ID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE menu_items
+	SET name = $1, description = $2, price_minor = $3, currency = $4, is_available = $5,
+		dietary_tags = $6, position = $7
+	WHERE id = $8 AND restaurant_id = $9
+	RETURNING updated_at
+	`
+	err = pg.db.QueryRow(q,
+		i.Name,
+		i.Description,
+		i.PriceMinor,
+		i.Currency,
+		i.IsAvailable,
+		pq.Array(i.DietaryTags),
+		i.Position,
+		i.ID,
+		i.RestaurantID).
+		Scan(&i.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFound("item")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) DeleteItem(restaurantID, id string) error {
+	return pg.deleteMenuRow("menu_items", "item", restaurantID, id)
+}
+
+func (pg *PostgresMenuStore) CreateModifierGroup(g *ModifierGroup) error {
+	_, err := uuid.Parse(g.ItemID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	INSERT INTO modifier_groups (restaurant_id, item_id, name, min_selections, max_selections, position)
+	SELECT restaurant_id, id, $3, $4, $5, $6
+	FROM menu_items
+	WHERE id = $1 AND restaurant_id = $2
+	RETURNING id, created_at, updated_at
+	`
+	err = pg.db.QueryRow(q,
+		g.ItemID,
+		g.RestaurantID,
+		g.Name,
+		g.MinSelections,
+		g.MaxSelections,
+		g.Position).
+		Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return invalid("item_id", "item_id does not exist in this restaurant")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) GetModifierGroup(restaurantID, id string) (*ModifierGroup, error) {
+	g := &ModifierGroup{}
+	err := getMenuRow(pg.db, modifierGroupSelect, restaurantID, id, func(row interface{ Scan(...any) error }) error {
+		return scanModifierGroup(row, g)
+	})
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("modifier group")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.db.Query(modifierSelect+`
+	WHERE group_id = $1
+	ORDER BY position, name
+	`, g.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m Modifier
+		if err := scanModifier(rows, &m); err != nil {
+			return nil, err
+		}
+		g.Modifiers = append(g.Modifiers, m)
+	}
+
+	return g, rows.Err()
+}
+
+func (pg *PostgresMenuStore) UpdateModifierGroup(g *ModifierGroup) error {
+	_, err := uuid.Parse(g.ID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE modifier_groups
+	SET name = $1, min_selections = $2, max_selections = $3, position = $4
+	WHERE id = $5 AND restaurant_id = $6
+	RETURNING updated_at
+	`
+	err = pg.db.QueryRow(q,
+		g.Name,
+		g.MinSelections,
+		g.MaxSelections,
+		g.Position,
+		g.ID,
+		g.RestaurantID).
+		Scan(&g.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFound("modifier group")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) DeleteModifierGroup(restaurantID, id string) error {
+	return pg.deleteMenuRow("modifier_groups", "modifier group", restaurantID, id)
+}
+
+func (pg *PostgresMenuStore) CreateModifier(m *Modifier) error {
+	_, err := uuid.Parse(m.GroupID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	INSERT INTO modifiers (restaurant_id, group_id, name, price_minor, is_available, position)
+	SELECT restaurant_id, id, $3, $4, $5, $6
+	FROM modifier_groups
+	WHERE id = $1 AND restaurant_id = $2
+	RETURNING id, created_at, updated_at
+	`
+	err = pg.db.QueryRow(q,
+		m.GroupID,
+		m.RestaurantID,
+		m.Name,
+		m.PriceMinor,
+		m.IsAvailable,
+		m.Position).
+		Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return invalid("group_id", "group_id does not exist in this restaurant")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) GetModifier(restaurantID, id string) (*Modifier, error) {
+	m := &Modifier{}
+	err := getMenuRow(pg.db, modifierSelect, restaurantID, id, func(row interface{ Scan(...any) error }) error {
+		return scanModifier(row, m)
+	})
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("modifier")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (pg *PostgresMenuStore) UpdateModifier(m *Modifier) error {
+	_, err := uuid.Parse(m.ID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE modifiers
+	SET name = $1, price_minor = $2, is_available = $3, position = $4
+	WHERE id = $5 AND restaurant_id = $6
+	RETURNING updated_at
+	`
+	err = pg.db.QueryRow(q,
+		m.Name,
+		m.PriceMinor,
+		m.IsAvailable,
+		m.Position,
+		m.ID,
+		m.RestaurantID).
+		Scan(&m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFound("modifier")
+	}
+	return err
+}
+
+func (pg *PostgresMenuStore) DeleteModifier(restaurantID, id string) error {
+	return pg.deleteMenuRow("modifiers", "modifier", restaurantID, id)
+}
@@ -0,0 +1,753 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"htrr-apis/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	OrderStatusOpen   = "open"
+	OrderStatusClosed = "closed"
+
+	OrderItemStatusPending = "pending"
+	OrderItemStatusSent    = "sent"
+	OrderItemStatusVoided  = "voided"
+)
+
+var (
+	ErrTableHasOpenOrder     = conflict("table already has an open order")
+	ErrOrderTableNotFound    = invalid("table_id", "table does not exist in this restaurant")
+	ErrOrderBookingNotFound  = invalid("booking_id", "booking does not exist in this restaurant")
+	ErrOrderClosed           = conflict("order is closed")
+	ErrOrderHasUnsentItems   = conflict("order has items that were not sent to the kitchen")
+	ErrNothingToSend         = conflict("order has no items waiting to be sent")
+	ErrMenuItemNotFound      = invalid("menu_item_id", "menu item does not exist in this restaurant")
+	ErrMenuItemUnavailable   = invalid("menu_item_id", "menu item is not available")
+	ErrInvalidModifiers      = invalid("modifier_ids", "invalid modifiers")
+	ErrOrderCurrencyMismatch = invalid("currency", "item currency differs from the order currency")
+	ErrOrderCurrencyRequired = invalid("currency", "currency is required for the first custom item of an order")
+)
+
+type PostgresOrderStore struct {
+	db *sql.DB
+}
+
+func NewPostgresOrderStore(db *sql.DB) *PostgresOrderStore {
+	return &PostgresOrderStore{
+		db: db,
+	}
+}
+
+// Order is the running tab of a table. SubtotalMinor sums the lines that are
+// not voided, before tax and service.
+type Order struct {
+	ID            string      `json:"id"`
+	RestaurantID  string      `json:"restaurant_id"`
+	TableID       *string     `json:"table_id"`
+	BookingID     *string     `json:"booking_id"`
+	Status        string      `json:"status"`
+	Currency      string      `json:"currency"`
+	Notes         string      `json:"notes"`
+	SubtotalMinor int64       `json:"subtotal_minor"`
+	OpenedBy      *string     `json:"opened_by"`
+	OpenedAt      time.Time   `json:"opened_at"`
+	ClosedBy      *string     `json:"closed_by"`
+	ClosedAt      *time.Time  `json:"closed_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	Items         []OrderItem `json:"items,omitempty"`
+}
+
+// OrderItem is one line of an order. Name and UnitPriceMinor are copied from
+// the menu when the line is added; UnitPriceMinor includes the modifiers.
+type OrderItem struct {
+	ID             string              `json:"id"`
+	OrderID        string              `json:"order_id"`
+	MenuItemID     *string             `json:"menu_item_id"`
+	Name           string              `json:"name"`
+	UnitPriceMinor int64               `json:"unit_price_minor"`
+	Quantity       int                 `json:"quantity"`
+	Seat           *int                `json:"seat"`
+	TotalMinor     int64               `json:"total_minor"`
+	Notes          string              `json:"notes"`
+	Status         string              `json:"status"`
+	SentAt         *time.Time          `json:"sent_at"`
+	VoidedAt       *time.Time          `json:"voided_at"`
+	CreatedAt      time.Time           `json:"created_at"`
+	Modifiers      []OrderItemModifier `json:"modifiers"`
+}
+
+type OrderItemModifier struct {
+	ModifierID *string `json:"modifier_id"`
+	GroupName  string  `json:"group_name"`
+	Name       string  `json:"name"`
+	PriceMinor int64   `json:"price_minor"`
+}
+
+// AddOrderItemParams describes a new line. With MenuItemID set, the name,
+// price and currency come from the menu and ModifierIDs are checked against
+// the item's modifier groups. Without it the line is a custom item priced by
+// Name, PriceMinor and Currency.
+type AddOrderItemParams struct {
+	MenuItemID  string
+	ModifierIDs []string
+	Name        string
+	PriceMinor  int64
+	Currency    string
+	Quantity    int
+	Seat        *int
+	Notes       string
+}
+
+type ListOrderParams struct {
+	Page         int
+	PageSize     int
+	RestaurantID string
+	TableID      string
+	Status       string
+}
+
+type OrderStore interface {
+	Open(*Order) error
+	List(ListOrderParams) ([]Order, int, error)
+	GetById(restaurantID, id string) (*Order, error)
+	AddItem(restaurantID, orderID string, params AddOrderItemParams) (*OrderItem, error)
+	RemoveItem(restaurantID, orderID, itemID string) (*KitchenTicket, error)
+	Send(restaurantID, orderID string) ([]OrderItem, []KitchenTicket, error)
+	SetItemSeat(restaurantID, orderID, itemID string, seat *int) (*OrderItem, error)
+	Close(restaurantID, orderID, closedBy string) (*Order, error)
+}
+
+const orderSelect = `
+	SELECT o.id, o.restaurant_id, o.table_id, o.booking_id, o.status, COALESCE(o.currency, ''),
+			o.notes,
+			COALESCE((SELECT SUM(i.unit_price_minor * i.quantity) FROM order_items i
+				WHERE i.order_id = o.id AND i.status <> 'voided'), 0),
+			o.opened_by, o.opened_at, o.closed_by, o.closed_at, o.created_at, o.updated_at
+	FROM orders o
+	`
+
+func scanOrder(row interface{ Scan(...any) error }, o *Order, extra ...any) error {
+	dest := []any{
+		&o.ID,
+		&o.RestaurantID,
+		&o.TableID,
+		&o.BookingID,
+		&o.Status,
+		&o.Currency,
+		&o.Notes,
+		&o.SubtotalMinor,
+		&o.OpenedBy,
+		&o.OpenedAt,
+		&o.ClosedBy,
+		&o.ClosedAt,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+const orderItemSelect = `
+	SELECT id, order_id, menu_item_id, name, unit_price_minor, quantity, seat, notes, status,
+			sent_at, voided_at, created_at
+	FROM order_items
+	`
+
+func scanOrderItem(row interface{ Scan(...any) error }, i *OrderItem) error {
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.MenuItemID,
+		&i.Name,
+		&i.UnitPriceMinor,
+		&i.Quantity,
+		&i.Seat,
+		&i.Notes,
+		&i.Status,
+		&i.SentAt,
+		&i.VoidedAt,
+		&i.CreatedAt)
+	i.TotalMinor = i.UnitPriceMinor * int64(i.Quantity)
+	return err
+}
+
+// loadOrderItems returns the lines matching the condition on order_items,
+// oldest first, each with its modifiers.
+func loadOrderItems(q queryer, where string, args ...any) ([]OrderItem, error) {
+	rows, err := q.Query(orderItemSelect+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []OrderItem{}
+	var ids []string
+	for rows.Next() {
+		var i OrderItem
+		if err := scanOrderItem(rows, &i); err != nil {
+			return nil, err
+		}
+		i.Modifiers = []OrderItemModifier{}
+		items = append(items, i)
+		ids = append(ids, i.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(`
+	SELECT order_item_id, modifier_id, group_name, name, price_minor
+	FROM order_item_modifiers
+	WHERE order_item_id = ANY($1)
+	ORDER BY group_name, name
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := map[string]int{}
+	for n, i := range items {
+		index[i.ID] = n
+	}
+	for rows.Next() {
+		var itemID string
+		var m OrderItemModifier
+		if err := rows.Scan(&itemID, &m.ModifierID, &m.GroupName, &m.Name, &m.PriceMinor); err != nil {
+			return nil, err
+		}
+		n := index[itemID]
+		items[n].Modifiers = append(items[n].Modifiers, m)
+	}
+
+	return items, rows.Err()
+}
+
+// lockOrder reads an order and holds it until the transaction ends, so lines
+// are added, sent and closed one request at a time.
+func lockOrder(tx *sql.Tx, restaurantID, id string) (*Order, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	o := &Order{}
+	err = scanOrder(tx.QueryRow(orderSelect+`
+	WHERE o.id = $1 AND o.restaurant_id = $2
+	FOR UPDATE OF o
+	`, id, restaurantID), o)
+	if err == sql.ErrNoRows {
+		return nil, notFound("order")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// Open starts an order on a table of the restaurant, optionally tied to one
+// of its bookings.
+func (pg *PostgresOrderStore) Open(o *Order) error {
+	if o.TableID == nil {
+		return ErrOrderTableNotFound
+	}
+	_, err := uuid.Parse(*o.TableID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	var status string
+	err = pg.db.QueryRow(`
+	SELECT status FROM tables WHERE id = $1 AND restaurant_id = $2
+	`, *o.TableID, o.RestaurantID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrOrderTableNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if status == TableStatusOutOfService {
+		return ErrTableOutOfService
+	}
+
+	if o.BookingID != nil {
+		_, err = uuid.Parse(*o.BookingID)
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		var exists bool
+		err = pg.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM bookings b JOIN tables t ON t.id = b.table_id
+			WHERE b.id = $1 AND t.restaurant_id = $2
+		)
+		`, *o.BookingID, o.RestaurantID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return ErrOrderBookingNotFound
+		}
+	}
+
+	q := `
+	INSERT INTO orders (restaurant_id, table_id, booking_id, notes, opened_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, status, opened_at, created_at, updated_at
+	`
+	err = pg.db.QueryRow(q,
+		o.RestaurantID,
+		o.TableID,
+		o.BookingID,
+		o.Notes,
+		o.OpenedBy).
+		Scan(&o.ID, &o.Status, &o.OpenedAt, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		if pgCode(err) == pgUniqueViolation {
+			return ErrTableHasOpenOrder
+		}
+		return err
+	}
+
+	o.Items = []OrderItem{}
+	return nil
+}
+
+// List returns the orders of a restaurant, newest first, without their lines.
+func (pg *PostgresOrderStore) List(params ListOrderParams) ([]Order, int, error) {
+	_, err := uuid.Parse(params.RestaurantID)
+	if err != nil {
+		return nil, 0, ErrInvalidID
+	}
+
+	q := `
+	SELECT o.id, o.restaurant_id, o.table_id, o.booking_id, o.status, COALESCE(o.currency, ''),
+			o.notes,
+			COALESCE((SELECT SUM(i.unit_price_minor * i.quantity) FROM order_items i
+				WHERE i.order_id = o.id AND i.status <> 'voided'), 0),
+			o.opened_by, o.opened_at, o.closed_by, o.closed_at, o.created_at, o.updated_at,
+			COUNT(*) OVER()
+	FROM orders o
+	WHERE o.restaurant_id = $1
+		AND ($2 = '' OR o.table_id::text = $2)
+		AND ($3 = '' OR o.status = $3)
+	ORDER BY o.opened_at DESC
+	LIMIT $4 OFFSET $5
+	`
+	limit, offset := utils.GetOffset(&params.Page, &params.PageSize)
+
+	rows, err := pg.db.Query(q,
+		params.RestaurantID,
+		params.TableID,
+		params.Status,
+		limit,
+		offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var total int
+	list := []Order{}
+	for rows.Next() {
+		var o Order
+		if err := scanOrder(rows, &o, &total); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, o)
+	}
+
+	return list, total, rows.Err()
+}
+
+// GetById returns an order with all of its lines, voided ones included.
+func (pg *PostgresOrderStore) GetById(restaurantID, id string) (*Order, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	o := &Order{}
+	err = scanOrder(pg.db.QueryRow(orderSelect+`
+	WHERE o.id = $1 AND o.restaurant_id = $2
+	`, id, restaurantID), o)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("order")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	o.Items, err = loadOrderItems(pg.db, `WHERE order_id = $1`, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// pickModifiers resolves the chosen modifier ids against the groups of an
+// item and checks every group's min/max selections.
+func pickModifiers(groups []ModifierGroup, ids []string) ([]OrderItemModifier, error) {
+	type choice struct {
+		group    *ModifierGroup
+		modifier Modifier
+	}
+	offered := map[string]choice{}
+	for g := range groups {
+		for _, m := range groups[g].Modifiers {
+			offered[m.ID] = choice{group: &groups[g], modifier: m}
+		}
+	}
+
+	picked := []OrderItemModifier{}
+	counts := map[string]int{}
+	for _, id := range ids {
+		c, ok := offered[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: modifier %s is not offered with this item", ErrInvalidModifiers, id)
+		}
+		if !c.modifier.IsAvailable {
+			return nil, fmt.Errorf("%w: %s is not available", ErrInvalidModifiers, c.modifier.Name)
+		}
+		counts[c.group.ID]++
+		modifierID := c.modifier.ID
+		picked = append(picked, OrderItemModifier{
+			ModifierID: &modifierID,
+			GroupName:  c.group.Name,
+			Name:       c.modifier.Name,
+			PriceMinor: c.modifier.PriceMinor,
+		})
+	}
+
+	for _, g := range groups {
+		n := counts[g.ID]
+		if n < g.MinSelections || n > g.MaxSelections {
+			return nil, fmt.Errorf("%w: choose between %d and %d from %s", ErrInvalidModifiers, g.MinSelections, g.MaxSelections, g.Name)
+		}
+	}
+
+	return picked, nil
+}
+
+// AddItem appends a line to an open order. The first line fixes the order's
+// currency; later lines must use the same one.
+func (pg *PostgresOrderStore) AddItem(restaurantID, orderID string, params AddOrderItemParams) (*OrderItem, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := lockOrder(tx, restaurantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Status != OrderStatusOpen {
+		return nil, ErrOrderClosed
+	}
+
+	line := &OrderItem{
+		OrderID:   o.ID,
+		Name:      params.Name,
+		Quantity:  params.Quantity,
+		Seat:      params.Seat,
+		Notes:     params.Notes,
+		Modifiers: []OrderItemModifier{},
+	}
+	unitPrice := params.PriceMinor
+	currency := params.Currency
+	var categoryID *string
+
+	if params.MenuItemID != "" {
+		menuItem := &MenuItem{}
+		err = getMenuRow(tx, menuItemSelect, restaurantID, params.MenuItemID, func(row interface{ Scan(...any) error }) error {
+			return scanMenuItem(row, menuItem)
+		})
+		if err == sql.ErrNoRows {
+			return nil, ErrMenuItemNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !menuItem.IsAvailable {
+			return nil, ErrMenuItemUnavailable
+		}
+
+		groups, err := loadModifierGroups(tx, restaurantID, []string{menuItem.ID})
+		if err != nil {
+			return nil, err
+		}
+
+		line.Modifiers, err = pickModifiers(groups[menuItem.ID], params.ModifierIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		line.MenuItemID = &menuItem.ID
+		line.Name = menuItem.Name
+		unitPrice = menuItem.PriceMinor
+		for _, m := range line.Modifiers {
+			unitPrice += m.PriceMinor
+		}
+		currency = menuItem.Currency
+		categoryID = &menuItem.CategoryID
+	}
+
+	switch {
+	case currency == "" && o.Currency == "":
+		return nil, ErrOrderCurrencyRequired
+	case currency == "":
+		currency = o.Currency
+	case o.Currency == "":
+		_, err = tx.Exec(`UPDATE orders SET currency = $1 WHERE id = $2`, currency, o.ID)
+		if err != nil {
+			return nil, err
+		}
+	case currency != o.Currency:
+		return nil, ErrOrderCurrencyMismatch
+	}
+
+	line.UnitPriceMinor = unitPrice
+	line.TotalMinor = unitPrice * int64(line.Quantity)
+
+	err = tx.QueryRow(`
+	INSERT INTO order_items (restaurant_id, order_id, menu_item_id, category_id, name, unit_price_minor,
+		quantity, seat, notes)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, status, created_at
+	`, restaurantID, o.ID, line.MenuItemID, categoryID, line.Name, line.UnitPriceMinor,
+		line.Quantity, line.Seat, line.Notes).
+		Scan(&line.ID, &line.Status, &line.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range line.Modifiers {
+		_, err = tx.Exec(`
+		INSERT INTO order_item_modifiers (order_item_id, modifier_id, group_name, name, price_minor)
+		VALUES ($1, $2, $3, $4, $5)
+		`, line.ID, m.ModifierID, m.GroupName, m.Name, m.PriceMinor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return line, tx.Commit()
+}
+
+// RemoveItem takes a line off an open order. A line the kitchen has not seen
+// is deleted; one that was already sent is kept as voided, and so is its
+// kitchen ticket item, whose refreshed ticket is returned.
+func (pg *PostgresOrderStore) RemoveItem(restaurantID, orderID, itemID string) (*KitchenTicket, error) {
+	_, err := uuid.Parse(itemID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := lockOrder(tx, restaurantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Status != OrderStatusOpen {
+		return nil, ErrOrderClosed
+	}
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM order_items WHERE id = $1 AND order_id = $2`, itemID, o.ID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, notFound("order item")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket *KitchenTicket
+	switch status {
+	case OrderItemStatusPending:
+		_, err = tx.Exec(`DELETE FROM order_items WHERE id = $1`, itemID)
+	case OrderItemStatusSent:
+		_, err = tx.Exec(`
+		UPDATE order_items SET status = 'voided', voided_at = CURRENT_TIMESTAMP WHERE id = $1
+		`, itemID)
+		if err == nil {
+			ticket, err = voidTicketItem(tx, itemID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ticket, tx.Commit()
+}
+
+// SetItemSeat moves a line of an open order to another seat, or to no seat
+// with a nil seat. Sent lines can be moved too; the seat only matters to the
+// bill.
+func (pg *PostgresOrderStore) SetItemSeat(restaurantID, orderID, itemID string, seat *int) (*OrderItem, error) {
+	_, err := uuid.Parse(itemID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := lockOrder(tx, restaurantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Status != OrderStatusOpen {
+		return nil, ErrOrderClosed
+	}
+
+	result, err := tx.Exec(`UPDATE order_items SET seat = $1 WHERE id = $2 AND order_id = $3`, seat, itemID, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, notFound("order item")
+	}
+
+	items, err := loadOrderItems(tx, `WHERE id = $1`, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &items[0], tx.Commit()
+}
+
+// Send marks every pending line of an open order as sent to the kitchen and
+// returns those lines along with the tickets fired for them, one per station.
+func (pg *PostgresOrderStore) Send(restaurantID, orderID string) ([]OrderItem, []KitchenTicket, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := lockOrder(tx, restaurantID, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.Status != OrderStatusOpen {
+		return nil, nil, ErrOrderClosed
+	}
+
+	rows, err := tx.Query(`
+	UPDATE order_items SET status = 'sent', sent_at = CURRENT_TIMESTAMP
+	WHERE order_id = $1 AND status = 'pending'
+	RETURNING id
+	`, o.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, nil, ErrNothingToSend
+	}
+
+	sent, err := loadOrderItems(tx, `WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tickets, err := fireTickets(tx, o, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sent, tickets, tx.Commit()
+}
+
+// Close ends an open order. Lines that were never sent must be sent or
+// removed first.
+func (pg *PostgresOrderStore) Close(restaurantID, orderID, closedBy string) (*Order, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := lockOrder(tx, restaurantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Status != OrderStatusOpen {
+		return nil, ErrOrderClosed
+	}
+
+	var pending bool
+	err = tx.QueryRow(`
+	SELECT EXISTS (SELECT 1 FROM order_items WHERE order_id = $1 AND status = 'pending')
+	`, o.ID).Scan(&pending)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending {
+		return nil, ErrOrderHasUnsentItems
+	}
+
+	err = tx.QueryRow(`
+	UPDATE orders SET status = 'closed', closed_by = $1, closed_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	RETURNING status, closed_by, closed_at, updated_at
+	`, closedBy, o.ID).Scan(&o.Status, &o.ClosedBy, &o.ClosedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	o.Items, err = loadOrderItems(tx, `WHERE order_id = $1`, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return o, tx.Commit()
+}
@@ -0,0 +1,832 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"htrr-apis/internal/payments"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusVoided     = "voided"
+	PaymentStatusFailed     = "failed"
+
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+var (
+	ErrBillNotFinalized      = conflict("order has no final bill")
+	ErrShareNotFound         = invalid("share_id", "share does not belong to the bill of this order")
+	ErrPaymentExceedsBalance = invalid("amount_minor", "amount is more than what is left to pay")
+	ErrIdempotencyKeyReused  = conflict("idempotency key was already used for a different request")
+	ErrPaymentNotCapturable  = conflict("only an authorized payment can be captured")
+	ErrPaymentNotVoidable    = conflict("payment can no longer be voided")
+	ErrPaymentNotRefundable  = conflict("only a captured payment can be refunded")
+	ErrRefundExceedsPayment  = invalid("amount_minor", "refund is more than what is left of the payment")
+)
+
+// PostgresPaymentStore records payments and moves card and e-wallet money
+// through the provider. A provider call is made while the payment, or for a
+// new payment its bill, is locked, so calls for one payment never overlap.
+type PostgresPaymentStore struct {
+	db       *sql.DB
+	provider payments.PaymentProvider
+}
+
+func NewPostgresPaymentStore(db *sql.DB, provider payments.PaymentProvider) *PostgresPaymentStore {
+	return &PostgresPaymentStore{
+		db:       db,
+		provider: provider,
+	}
+}
+
+// Payment is money taken against a bill, or one share of it. TipMinor is on
+// top of AmountMinor and does not count toward the bill. A payment the
+// provider could not be reached for stays pending and is retried by sending
+// the same idempotency key again.
+type Payment struct {
+	ID                string           `json:"id"`
+	RestaurantID      string           `json:"restaurant_id"`
+	BillID            string           `json:"bill_id"`
+	ShareID           *string          `json:"share_id"`
+	IdempotencyKey    string           `json:"idempotency_key"`
+	Method            string           `json:"method"`
+	Status            string           `json:"status"`
+	Currency          string           `json:"currency"`
+	AmountMinor       int64            `json:"amount_minor"`
+	TipMinor          int64            `json:"tip_minor"`
+	RefundedMinor     int64            `json:"refunded_minor"`
+	ProviderReference *string          `json:"provider_reference"`
+	FailureReason     *string          `json:"failure_reason"`
+	CreatedBy         *string          `json:"created_by"`
+	AuthorizedAt      *time.Time       `json:"authorized_at"`
+	CapturedAt        *time.Time       `json:"captured_at"`
+	VoidedAt          *time.Time       `json:"voided_at"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+	Refunds           []PaymentRefund  `json:"refunds,omitempty"`
+	Attempts          []PaymentAttempt `json:"attempts,omitempty"`
+}
+
+type PaymentRefund struct {
+	ID                string    `json:"id"`
+	PaymentID         string    `json:"payment_id"`
+	IdempotencyKey    string    `json:"idempotency_key"`
+	Status            string    `json:"status"`
+	AmountMinor       int64     `json:"amount_minor"`
+	Reason            string    `json:"reason"`
+	ProviderReference *string   `json:"provider_reference"`
+	FailureReason     *string   `json:"failure_reason"`
+	CreatedBy         *string   `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// PaymentAttempt is one call made to the payment provider.
+type PaymentAttempt struct {
+	ID                string    `json:"id"`
+	RefundID          *string   `json:"refund_id"`
+	Operation         string    `json:"operation"`
+	AmountMinor       int64     `json:"amount_minor"`
+	Succeeded         bool      `json:"succeeded"`
+	ProviderReference *string   `json:"provider_reference"`
+	Error             *string   `json:"error"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// TakePaymentParams describes a new payment. Token comes from the
+// provider's client SDK and is needed for card and e-wallet payments. With
+// Capture false a card payment is only authorized, to be captured later,
+// typically once the tip is known.
+type TakePaymentParams struct {
+	IdempotencyKey string
+	ShareID        *string
+	Method         string
+	Token          string
+	AmountMinor    int64
+	TipMinor       int64
+	Capture        bool
+	CreatedBy      string
+}
+
+type RefundPaymentParams struct {
+	IdempotencyKey string
+	AmountMinor    int64
+	Reason         string
+	CreatedBy      string
+}
+
+type ShareBalance struct {
+	ShareID      string `json:"share_id"`
+	Label        string `json:"label"`
+	TotalMinor   int64  `json:"total_minor"`
+	PaidMinor    int64  `json:"paid_minor"`
+	PendingMinor int64  `json:"pending_minor"`
+	BalanceMinor int64  `json:"balance_minor"`
+}
+
+// BillPayments sums up the payments of a bill. PaidMinor counts captured
+// payments and PendingMinor those still pending or only authorized; both
+// are taken off BalanceMinor. Tips and refunds are reported on their own
+// and leave the balance alone.
+type BillPayments struct {
+	BillID        string         `json:"bill_id"`
+	Currency      string         `json:"currency"`
+	TotalMinor    int64          `json:"total_minor"`
+	PaidMinor     int64          `json:"paid_minor"`
+	PendingMinor  int64          `json:"pending_minor"`
+	BalanceMinor  int64          `json:"balance_minor"`
+	TipMinor      int64          `json:"tip_minor"`
+	RefundedMinor int64          `json:"refunded_minor"`
+	Shares        []ShareBalance `json:"shares"`
+	Payments      []Payment      `json:"payments"`
+}
+
+type PaymentStore interface {
+	Take(restaurantID, orderID string, params TakePaymentParams) (*Payment, bool, error)
+	ListForOrder(restaurantID, orderID string) (*BillPayments, error)
+	GetById(restaurantID, id string) (*Payment, error)
+	Capture(restaurantID, id string, tipMinor *int64) (*Payment, error)
+	Void(restaurantID, id string) (*Payment, error)
+	Refund(restaurantID, id string, params RefundPaymentParams) (*PaymentRefund, bool, error)
+}
+
+const paymentColumns = `
+	id, restaurant_id, bill_id, share_id, idempotency_key, method, status, currency,
+	amount_minor, tip_minor, refunded_minor, provider_reference, failure_reason, created_by,
+	authorized_at, captured_at, voided_at, created_at, updated_at
+	`
+
+func scanPayment(row interface{ Scan(...any) error }, p *Payment) error {
+	return row.Scan(
+		&p.ID,
+		&p.RestaurantID,
+		&p.BillID,
+		&p.ShareID,
+		&p.IdempotencyKey,
+		&p.Method,
+		&p.Status,
+		&p.Currency,
+		&p.AmountMinor,
+		&p.TipMinor,
+		&p.RefundedMinor,
+		&p.ProviderReference,
+		&p.FailureReason,
+		&p.CreatedBy,
+		&p.AuthorizedAt,
+		&p.CapturedAt,
+		&p.VoidedAt,
+		&p.CreatedAt,
+		&p.UpdatedAt)
+}
+
+const refundColumns = `
+	id, payment_id, idempotency_key, status, amount_minor, reason, provider_reference,
+	failure_reason, created_by, created_at, updated_at
+	`
+
+func scanRefund(row interface{ Scan(...any) error }, r *PaymentRefund) error {
+	return row.Scan(
+		&r.ID,
+		&r.PaymentID,
+		&r.IdempotencyKey,
+		&r.Status,
+		&r.AmountMinor,
+		&r.Reason,
+		&r.ProviderReference,
+		&r.FailureReason,
+		&r.CreatedBy,
+		&r.CreatedAt,
+		&r.UpdatedAt)
+}
+
+// providerError sorts a provider failure into a final one, returned as is,
+// and anything else, which is treated as the provider being unavailable so
+// the call can be retried.
+func providerError(err error) error {
+	if isProviderError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", payments.ErrUnavailable, err)
+}
+
+// isProviderError reports whether err came from the provider rather than
+// the database. Provider failures are saved before they are returned.
+func isProviderError(err error) bool {
+	return errors.Is(err, payments.ErrDeclined) ||
+		errors.Is(err, payments.ErrUnknownReference) ||
+		errors.Is(err, payments.ErrInvalidAmount) ||
+		errors.Is(err, payments.ErrUnavailable)
+}
+
+func recordAttempt(tx *sql.Tx, paymentID string, refundID *string, op string, amountMinor int64, res payments.Result, callErr error) error {
+	var reference, message *string
+	if callErr == nil {
+		reference = &res.Reference
+	} else {
+		s := callErr.Error()
+		message = &s
+	}
+
+	_, err := tx.Exec(`
+	INSERT INTO payment_attempts (payment_id, refund_id, operation, amount_minor, succeeded, provider_reference, error)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, paymentID, refundID, op, amountMinor, callErr == nil, reference, message)
+	return err
+}
+
+// updatePayment applies set, whose placeholders start at $2, to the payment
+// and reads it back into p.
+func updatePayment(tx *sql.Tx, p *Payment, set string, args ...any) error {
+	return scanPayment(tx.QueryRow(`UPDATE payments SET `+set+` WHERE id = $1 RETURNING `+paymentColumns,
+		append([]any{p.ID}, args...)...), p)
+}
+
+func lockPayment(tx *sql.Tx, restaurantID, id string) (*Payment, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	p := &Payment{}
+	err = scanPayment(tx.QueryRow(`SELECT `+paymentColumns+` FROM payments
+	WHERE id = $1 AND restaurant_id = $2
+	FOR UPDATE
+	`, id, restaurantID), p)
+	if err == sql.ErrNoRows {
+		return nil, notFound("payment")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// gatewayKey builds the idempotency key sent to the provider from the
+// client's key rather than from row ids, which are new on every attempt: a
+// request retried after its transaction was lost reaches the provider with
+// the same key and cannot move money twice.
+func gatewayKey(parts ...string) string {
+	return strings.Join(parts, ":")
+}
+
+// authorize asks the provider to hold the amount and tip of a pending
+// payment. A declined payment fails; one the provider could not answer for
+// stays pending.
+func (pg *PostgresPaymentStore) authorize(tx *sql.Tx, p *Payment, token string) error {
+	amount := p.AmountMinor + p.TipMinor
+	res, callErr := pg.provider.Authorize(payments.AuthorizeRequest{
+		IdempotencyKey: gatewayKey(p.RestaurantID, p.IdempotencyKey),
+		Method:         p.Method,
+		Token:          token,
+		AmountMinor:    amount,
+		Currency:       p.Currency,
+	})
+
+	err := recordAttempt(tx, p.ID, nil, payments.OpAuthorize, amount, res, callErr)
+	if err != nil {
+		return err
+	}
+
+	if callErr != nil {
+		callErr = providerError(callErr)
+		status := PaymentStatusFailed
+		if errors.Is(callErr, payments.ErrUnavailable) {
+			status = PaymentStatusPending
+		}
+		err = updatePayment(tx, p, `status = $2, failure_reason = $3`, status, callErr.Error())
+		if err != nil {
+			return err
+		}
+		return callErr
+	}
+
+	return updatePayment(tx, p, `
+		status = 'authorized', provider_reference = $2, failure_reason = NULL, authorized_at = CURRENT_TIMESTAMP
+		`, res.Reference)
+}
+
+// capture takes the amount and tip of an authorized payment. When it fails
+// the authorization is left as it was, to be captured again or voided.
+func (pg *PostgresPaymentStore) capture(tx *sql.Tx, p *Payment) error {
+	amount := p.AmountMinor + p.TipMinor
+	key := gatewayKey(p.RestaurantID, p.IdempotencyKey, payments.OpCapture)
+	res, callErr := pg.provider.Capture(*p.ProviderReference, key, amount)
+
+	err := recordAttempt(tx, p.ID, nil, payments.OpCapture, amount, res, callErr)
+	if err != nil {
+		return err
+	}
+
+	if callErr != nil {
+		callErr = providerError(callErr)
+		err = updatePayment(tx, p, `failure_reason = $2`, callErr.Error())
+		if err != nil {
+			return err
+		}
+		return callErr
+	}
+
+	return updatePayment(tx, p, `status = 'captured', failure_reason = NULL, captured_at = CURRENT_TIMESTAMP`)
+}
+
+// Take records a payment against the final bill of an order and, for card
+// and e-wallet, authorizes it and by default captures it. The bool reports
+// whether the payment is new: a known idempotency key returns the payment
+// already made with it, after retrying the provider if it was left pending.
+// Provider failures are returned together with the payment they left.
+func (pg *PostgresPaymentStore) Take(restaurantID, orderID string, params TakePaymentParams) (*Payment, bool, error) {
+	_, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, false, ErrInvalidID
+	}
+	if params.ShareID != nil {
+		_, err = uuid.Parse(*params.ShareID)
+		if err != nil {
+			return nil, false, ErrInvalidID
+		}
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var billID, currency string
+	var total int64
+	err = tx.QueryRow(`
+	SELECT id, currency, total_minor FROM bills
+	WHERE order_id = $1 AND restaurant_id = $2
+	FOR UPDATE
+	`, orderID, restaurantID).Scan(&billID, &currency, &total)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrBillNotFinalized
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	p := &Payment{}
+	created := false
+	err = scanPayment(tx.QueryRow(`SELECT `+paymentColumns+` FROM payments
+	WHERE restaurant_id = $1 AND idempotency_key = $2
+	`, restaurantID, params.IdempotencyKey), p)
+
+	switch {
+	case err == nil:
+		sameShare := (p.ShareID == nil) == (params.ShareID == nil) &&
+			(p.ShareID == nil || *p.ShareID == *params.ShareID)
+		if p.BillID != billID || !sameShare || p.Method != params.Method ||
+			p.AmountMinor != params.AmountMinor || p.TipMinor != params.TipMinor {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+
+	case err == sql.ErrNoRows:
+		var committed int64
+		err = tx.QueryRow(`
+		SELECT COALESCE(SUM(amount_minor), 0) FROM payments
+		WHERE bill_id = $1 AND status IN ('pending', 'authorized', 'captured')
+		`, billID).Scan(&committed)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if params.AmountMinor > total-committed {
+			return nil, false, ErrPaymentExceedsBalance
+		}
+
+		if params.ShareID != nil {
+			var shareTotal int64
+			err = tx.QueryRow(`
+			SELECT s.total_minor - COALESCE((SELECT SUM(p.amount_minor) FROM payments p
+				WHERE p.share_id = s.id AND p.status IN ('pending', 'authorized', 'captured')), 0)
+			FROM bill_shares s
+			WHERE s.id = $1 AND s.bill_id = $2
+			`, *params.ShareID, billID).Scan(&shareTotal)
+			if err == sql.ErrNoRows {
+				return nil, false, ErrShareNotFound
+			}
+			if err != nil {
+				return nil, false, err
+			}
+
+			if params.AmountMinor > shareTotal {
+				return nil, false, ErrPaymentExceedsBalance
+			}
+		}
+
+		// cash is in the drawer as soon as it is recorded
+		status := PaymentStatusPending
+		if params.Method == payments.MethodCash {
+			status = PaymentStatusCaptured
+		}
+
+		err = scanPayment(tx.QueryRow(`
+		INSERT INTO payments (restaurant_id, bill_id, share_id, idempotency_key, method, status, currency,
+				amount_minor, tip_minor, created_by, captured_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+				CASE WHEN $6 = 'captured' THEN CURRENT_TIMESTAMP END)
+		RETURNING `+paymentColumns,
+			restaurantID,
+			billID,
+			params.ShareID,
+			params.IdempotencyKey,
+			params.Method,
+			status,
+			currency,
+			params.AmountMinor,
+			params.TipMinor,
+			params.CreatedBy), p)
+		if err != nil {
+			return nil, false, err
+		}
+		created = true
+
+	default:
+		return nil, false, err
+	}
+
+	if p.Status == PaymentStatusPending {
+		err = pg.authorize(tx, p, params.Token)
+	}
+	if err == nil && p.Status == PaymentStatusAuthorized && params.Capture {
+		err = pg.capture(tx, p)
+	}
+	if err != nil && !isProviderError(err) {
+		return nil, false, err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return nil, false, commitErr
+	}
+
+	return p, created, err
+}
+
+// ListForOrder returns the payments of the final bill of an order, oldest
+// first, with what is left to pay on the bill and on each share.
+func (pg *PostgresPaymentStore) ListForOrder(restaurantID, orderID string) (*BillPayments, error) {
+	_, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	bp := &BillPayments{Shares: []ShareBalance{}, Payments: []Payment{}}
+	err = pg.db.QueryRow(`
+	SELECT id, currency, total_minor FROM bills
+	WHERE order_id = $1 AND restaurant_id = $2
+	`, orderID, restaurantID).Scan(&bp.BillID, &bp.Currency, &bp.TotalMinor)
+	if err == sql.ErrNoRows {
+		return nil, ErrBillNotFinalized
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.db.Query(`
+	SELECT id, label, total_minor FROM bill_shares
+	WHERE bill_id = $1
+	ORDER BY position
+	`, bp.BillID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shareIndex := map[string]int{}
+	for rows.Next() {
+		var s ShareBalance
+		if err := rows.Scan(&s.ShareID, &s.Label, &s.TotalMinor); err != nil {
+			return nil, err
+		}
+		shareIndex[s.ShareID] = len(bp.Shares)
+		bp.Shares = append(bp.Shares, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = pg.db.Query(`SELECT `+paymentColumns+` FROM payments
+	WHERE bill_id = $1
+	ORDER BY created_at, id
+	`, bp.BillID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Payment
+		if err := scanPayment(rows, &p); err != nil {
+			return nil, err
+		}
+		bp.Payments = append(bp.Payments, p)
+
+		var share *ShareBalance
+		if p.ShareID != nil {
+			if n, ok := shareIndex[*p.ShareID]; ok {
+				share = &bp.Shares[n]
+			}
+		}
+
+		switch p.Status {
+		case PaymentStatusCaptured:
+			bp.PaidMinor += p.AmountMinor
+			bp.TipMinor += p.TipMinor
+			if share != nil {
+				share.PaidMinor += p.AmountMinor
+			}
+		case PaymentStatusPending, PaymentStatusAuthorized:
+			bp.PendingMinor += p.AmountMinor
+			if share != nil {
+				share.PendingMinor += p.AmountMinor
+			}
+		}
+		bp.RefundedMinor += p.RefundedMinor
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bp.BalanceMinor = bp.TotalMinor - bp.PaidMinor - bp.PendingMinor
+	for n := range bp.Shares {
+		s := &bp.Shares[n]
+		s.BalanceMinor = s.TotalMinor - s.PaidMinor - s.PendingMinor
+	}
+
+	return bp, nil
+}
+
+// GetById returns a payment with its refunds and every provider call made
+// for it.
+func (pg *PostgresPaymentStore) GetById(restaurantID, id string) (*Payment, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	p := &Payment{}
+	err = scanPayment(pg.db.QueryRow(`SELECT `+paymentColumns+` FROM payments
+	WHERE id = $1 AND restaurant_id = $2
+	`, id, restaurantID), p)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("payment")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.db.Query(`SELECT `+refundColumns+` FROM payment_refunds
+	WHERE payment_id = $1
+	ORDER BY created_at, id
+	`, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p.Refunds = []PaymentRefund{}
+	for rows.Next() {
+		var r PaymentRefund
+		if err := scanRefund(rows, &r); err != nil {
+			return nil, err
+		}
+		p.Refunds = append(p.Refunds, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = pg.db.Query(`
+	SELECT id, refund_id, operation, amount_minor, succeeded, provider_reference, error, created_at
+	FROM payment_attempts
+	WHERE payment_id = $1
+	ORDER BY created_at, id
+	`, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p.Attempts = []PaymentAttempt{}
+	for rows.Next() {
+		var a PaymentAttempt
+		err := rows.Scan(&a.ID, &a.RefundID, &a.Operation, &a.AmountMinor, &a.Succeeded,
+			&a.ProviderReference, &a.Error, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		p.Attempts = append(p.Attempts, a)
+	}
+
+	return p, rows.Err()
+}
+
+// Capture takes an authorized payment, first replacing its tip when tipMinor
+// is given.
+func (pg *PostgresPaymentStore) Capture(restaurantID, id string, tipMinor *int64) (*Payment, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := lockPayment(tx, restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Status != PaymentStatusAuthorized {
+		return nil, ErrPaymentNotCapturable
+	}
+
+	if tipMinor != nil {
+		err = updatePayment(tx, p, `tip_minor = $2`, *tipMinor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = pg.capture(tx, p)
+	if err != nil && !isProviderError(err) {
+		return nil, err
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return nil, commitErr
+	}
+
+	return p, err
+}
+
+// Void cancels a payment before money has moved: a pending payment, an
+// authorization that was not captured, or cash recorded by mistake and not
+// refunded. Captured card and e-wallet payments are refunded instead.
+func (pg *PostgresPaymentStore) Void(restaurantID, id string) (*Payment, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := lockPayment(tx, restaurantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.Status == PaymentStatusPending,
+		p.Status == PaymentStatusCaptured && p.Method == payments.MethodCash && p.RefundedMinor == 0:
+
+	case p.Status == PaymentStatusAuthorized:
+		res, callErr := pg.provider.Void(*p.ProviderReference)
+		err = recordAttempt(tx, p.ID, nil, payments.OpVoid, 0, res, callErr)
+		if err != nil {
+			return nil, err
+		}
+
+		if callErr != nil {
+			callErr = providerError(callErr)
+			err = updatePayment(tx, p, `failure_reason = $2`, callErr.Error())
+			if err != nil {
+				return nil, err
+			}
+			if err := tx.Commit(); err != nil {
+				return nil, err
+			}
+			return p, callErr
+		}
+
+	default:
+		return nil, ErrPaymentNotVoidable
+	}
+
+	err = updatePayment(tx, p, `status = 'voided', failure_reason = NULL, voided_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, tx.Commit()
+}
+
+// Refund gives back part or all of a captured payment, tip included. The
+// bool reports whether the refund is new: a known idempotency key returns
+// the refund already made with it, after retrying the provider if it was
+// left pending.
+func (pg *PostgresPaymentStore) Refund(restaurantID, id string, params RefundPaymentParams) (*PaymentRefund, bool, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	p, err := lockPayment(tx, restaurantID, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r := &PaymentRefund{}
+	created := false
+	err = scanRefund(tx.QueryRow(`SELECT `+refundColumns+` FROM payment_refunds
+	WHERE payment_id = $1 AND idempotency_key = $2
+	`, p.ID, params.IdempotencyKey), r)
+
+	switch {
+	case err == nil:
+		if r.AmountMinor != params.AmountMinor {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		if r.Status != RefundStatusPending {
+			return r, false, nil
+		}
+
+	case err == sql.ErrNoRows:
+		if p.Status != PaymentStatusCaptured {
+			return nil, false, ErrPaymentNotRefundable
+		}
+
+		var committed int64
+		err = tx.QueryRow(`
+		SELECT COALESCE(SUM(amount_minor), 0) FROM payment_refunds
+		WHERE payment_id = $1 AND status IN ('pending', 'succeeded')
+		`, p.ID).Scan(&committed)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if params.AmountMinor > p.AmountMinor+p.TipMinor-committed {
+			return nil, false, ErrRefundExceedsPayment
+		}
+
+		err = scanRefund(tx.QueryRow(`
+		INSERT INTO payment_refunds (payment_id, idempotency_key, amount_minor, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+refundColumns,
+			p.ID,
+			params.IdempotencyKey,
+			params.AmountMinor,
+			params.Reason,
+			params.CreatedBy), r)
+		if err != nil {
+			return nil, false, err
+		}
+		created = true
+
+	default:
+		return nil, false, err
+	}
+
+	var reference *string
+	var callErr error
+	if p.Method != payments.MethodCash {
+		var res payments.Result
+		key := gatewayKey(p.RestaurantID, p.ID, r.IdempotencyKey)
+		res, callErr = pg.provider.Refund(*p.ProviderReference, key, r.AmountMinor)
+		err = recordAttempt(tx, p.ID, &r.ID, payments.OpRefund, r.AmountMinor, res, callErr)
+		if err != nil {
+			return nil, false, err
+		}
+		reference = &res.Reference
+	}
+
+	if callErr != nil {
+		callErr = providerError(callErr)
+		status := RefundStatusFailed
+		if errors.Is(callErr, payments.ErrUnavailable) {
+			status = RefundStatusPending
+		}
+		err = scanRefund(tx.QueryRow(`
+		UPDATE payment_refunds SET status = $1, failure_reason = $2 WHERE id = $3
+		RETURNING `+refundColumns, status, callErr.Error(), r.ID), r)
+	} else {
+		err = scanRefund(tx.QueryRow(`
+		UPDATE payment_refunds SET status = 'succeeded', provider_reference = $1, failure_reason = NULL
+		WHERE id = $2
+		RETURNING `+refundColumns, reference, r.ID), r)
+		if err == nil {
+			err = updatePayment(tx, p, `refunded_minor = refunded_minor + $2`, r.AmountMinor)
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return r, created, callErr
+}
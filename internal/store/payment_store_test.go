@@ -0,0 +1,583 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"htrr-apis/internal/payments"
+	"htrr-apis/migrations"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL and migrates
+// it. Tests that need Postgres are skipped when it is not set.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrateOnce.Do(func() {
+		migrateErr = MigrateFs(db, migrations.FS, ".")
+	})
+	if migrateErr != nil {
+		t.Fatalf("migrate: %v", migrateErr)
+	}
+
+	return db
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+type paymentFixture struct {
+	db           *sql.DB
+	provider     *payments.FakeProvider
+	store        *PostgresPaymentStore
+	restaurantID string
+	orderID      string
+	userID       string
+}
+
+// newPaymentFixture seeds a restaurant of its own with an order whose bill
+// was finalized at totalMinor, and a payment store on a fresh FakeProvider.
+func newPaymentFixture(t *testing.T, totalMinor int64) *paymentFixture {
+	t.Helper()
+
+	db := testDB(t)
+	f := &paymentFixture{
+		db:       db,
+		provider: payments.NewFakeProvider(),
+	}
+	f.store = NewPostgresPaymentStore(db, f.provider)
+
+	err := db.QueryRow(`INSERT INTO restaurants (name) VALUES ('Payment test') RETURNING id`).Scan(&f.restaurantID)
+	if err != nil {
+		t.Fatalf("seed restaurant: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM restaurants WHERE id = $1`, f.restaurantID) })
+
+	err = db.QueryRow(`INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id`,
+		uuid.NewString()+"@example.com").Scan(&f.userID)
+	if err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, f.userID) })
+
+	err = db.QueryRow(`INSERT INTO orders (restaurant_id, currency) VALUES ($1, 'USD') RETURNING id`,
+		f.restaurantID).Scan(&f.orderID)
+	if err != nil {
+		t.Fatalf("seed order: %v", err)
+	}
+
+	_, err = db.Exec(`
+	INSERT INTO bills (restaurant_id, order_id, currency, subtotal_minor, discount_minor, service_charge_minor,
+			tax_minor, rounding_minor, total_minor, split_mode, details)
+	VALUES ($1, $2, 'USD', $3, 0, 0, 0, 0, $3, 'none', '{}')
+	`, f.restaurantID, f.orderID, totalMinor)
+	if err != nil {
+		t.Fatalf("seed bill: %v", err)
+	}
+
+	return f
+}
+
+func (f *paymentFixture) card(key string, amountMinor int64) TakePaymentParams {
+	return TakePaymentParams{
+		IdempotencyKey: key,
+		Method:         payments.MethodCard,
+		Token:          "tok_visa",
+		AmountMinor:    amountMinor,
+		Capture:        true,
+		CreatedBy:      f.userID,
+	}
+}
+
+func (f *paymentFixture) refund(key string, amountMinor int64) RefundPaymentParams {
+	return RefundPaymentParams{
+		IdempotencyKey: key,
+		AmountMinor:    amountMinor,
+		CreatedBy:      f.userID,
+	}
+}
+
+// attempts counts the provider calls recorded for a payment by operation.
+func (f *paymentFixture) attempts(t *testing.T, paymentID string) map[string]int {
+	t.Helper()
+
+	rows, err := f.db.Query(`SELECT operation, COUNT(*) FROM payment_attempts WHERE payment_id = $1 GROUP BY operation`, paymentID)
+	if err != nil {
+		t.Fatalf("attempts: %v", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var op string
+		var n int
+		if err := rows.Scan(&op, &n); err != nil {
+			t.Fatalf("attempts: %v", err)
+		}
+		counts[op] = n
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("attempts: %v", err)
+	}
+	return counts
+}
+
+// heldReference returns the authorization the provider holds for the
+// client's idempotency key, authorizing amountMinor if it holds none.
+func (f *paymentFixture) heldReference(t *testing.T, key string, amountMinor int64) string {
+	t.Helper()
+
+	res, err := f.provider.Authorize(payments.AuthorizeRequest{
+		IdempotencyKey: gatewayKey(f.restaurantID, key),
+		Method:         payments.MethodCard,
+		Token:          "tok_visa",
+		AmountMinor:    amountMinor,
+		Currency:       "USD",
+	})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return res.Reference
+}
+
+func TestTakePayment(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     func(f *paymentFixture) TakePaymentParams
+		fail       error
+		wantErr    error
+		wantStatus string
+	}{
+		{
+			name:       "card captured",
+			params:     func(f *paymentFixture) TakePaymentParams { return f.card("k1", 4000) },
+			wantStatus: PaymentStatusCaptured,
+		},
+		{
+			name: "card authorized only",
+			params: func(f *paymentFixture) TakePaymentParams {
+				p := f.card("k1", 4000)
+				p.Capture = false
+				return p
+			},
+			wantStatus: PaymentStatusAuthorized,
+		},
+		{
+			name: "cash",
+			params: func(f *paymentFixture) TakePaymentParams {
+				p := f.card("k1", 4000)
+				p.Method = payments.MethodCash
+				p.Token = ""
+				return p
+			},
+			wantStatus: PaymentStatusCaptured,
+		},
+		{
+			name: "declined",
+			params: func(f *paymentFixture) TakePaymentParams {
+				p := f.card("k1", 4000)
+				p.Token = payments.DeclineToken
+				return p
+			},
+			wantErr:    payments.ErrDeclined,
+			wantStatus: PaymentStatusFailed,
+		},
+		{
+			name:       "provider unavailable",
+			params:     func(f *paymentFixture) TakePaymentParams { return f.card("k1", 4000) },
+			fail:       payments.ErrUnavailable,
+			wantErr:    payments.ErrUnavailable,
+			wantStatus: PaymentStatusPending,
+		},
+		{
+			name:    "more than the bill",
+			params:  func(f *paymentFixture) TakePaymentParams { return f.card("k1", 10001) },
+			wantErr: ErrPaymentExceedsBalance,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 10000)
+			if tt.fail != nil {
+				f.provider.FailNext(payments.OpAuthorize, tt.fail)
+			}
+
+			p, created, err := f.store.Take(f.restaurantID, f.orderID, tt.params(f))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+
+			if !created {
+				t.Errorf("created = false, want true")
+			}
+			if p.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", p.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestTakePaymentRetryAfterUnavailable(t *testing.T) {
+	f := newPaymentFixture(t, 10000)
+	f.provider.FailNext(payments.OpAuthorize, payments.ErrUnavailable)
+
+	first, _, err := f.store.Take(f.restaurantID, f.orderID, f.card("k1", 4000))
+	if !errors.Is(err, payments.ErrUnavailable) {
+		t.Fatalf("first call: err = %v, want %v", err, payments.ErrUnavailable)
+	}
+
+	retried, created, err := f.store.Take(f.restaurantID, f.orderID, f.card("k1", 4000))
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if created || retried.ID != first.ID {
+		t.Fatalf("retry made a new payment")
+	}
+	if retried.Status != PaymentStatusCaptured {
+		t.Errorf("status = %s, want %s", retried.Status, PaymentStatusCaptured)
+	}
+
+	again, _, err := f.store.Take(f.restaurantID, f.orderID, f.card("k1", 4000))
+	if err != nil {
+		t.Fatalf("repeat: %v", err)
+	}
+	if again.ID != first.ID || again.Status != PaymentStatusCaptured {
+		t.Errorf("repeat returned %s %s, want %s captured", again.ID, again.Status, first.ID)
+	}
+
+	if got := f.heldReference(t, "k1", 4000); got != *retried.ProviderReference {
+		t.Errorf("provider holds %s for the key, want the one authorization %s", got, *retried.ProviderReference)
+	}
+	want := map[string]int{payments.OpAuthorize: 2, payments.OpCapture: 1}
+	if got := f.attempts(t, first.ID); got[payments.OpAuthorize] != want[payments.OpAuthorize] || got[payments.OpCapture] != want[payments.OpCapture] {
+		t.Errorf("attempts = %v, want %v", got, want)
+	}
+}
+
+// A client whose first request was authorized by the provider but whose
+// transaction was then lost retries with the same key; the provider must
+// see the same key and answer with the authorization it already holds.
+func TestTakePaymentRetryAfterLostCommit(t *testing.T) {
+	f := newPaymentFixture(t, 10000)
+	held := f.heldReference(t, "k1", 4000)
+
+	p, created, err := f.store.Take(f.restaurantID, f.orderID, f.card("k1", 4000))
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if !created {
+		t.Errorf("created = false, want true")
+	}
+	if p.ProviderReference == nil || *p.ProviderReference != held {
+		t.Errorf("reference = %v, want the held authorization %s", p.ProviderReference, held)
+	}
+}
+
+func TestTakePaymentKeyReused(t *testing.T) {
+	f := newPaymentFixture(t, 10000)
+
+	_, _, err := f.store.Take(f.restaurantID, f.orderID, f.card("k1", 4000))
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	_, _, err = f.store.Take(f.restaurantID, f.orderID, f.card("k1", 5000))
+	if !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("err = %v, want %v", err, ErrIdempotencyKeyReused)
+	}
+}
+
+func TestCapturePayment(t *testing.T) {
+	tests := []struct {
+		name       string
+		tip        *int64
+		fail       error
+		wantErr    error
+		wantStatus string
+		wantTip    int64
+	}{
+		{name: "without tip", wantStatus: PaymentStatusCaptured},
+		{name: "with tip", tip: ptr(int64(500)), wantStatus: PaymentStatusCaptured, wantTip: 500},
+		{name: "tip beyond what the provider allows", tip: ptr(int64(2000)), wantErr: payments.ErrInvalidAmount, wantStatus: PaymentStatusAuthorized, wantTip: 2000},
+		{name: "provider unavailable", fail: payments.ErrUnavailable, wantErr: payments.ErrUnavailable, wantStatus: PaymentStatusAuthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 10000)
+			params := f.card("k1", 4000)
+			params.Capture = false
+			taken, _, err := f.store.Take(f.restaurantID, f.orderID, params)
+			if err != nil {
+				t.Fatalf("Take: %v", err)
+			}
+
+			if tt.fail != nil {
+				f.provider.FailNext(payments.OpCapture, tt.fail)
+			}
+
+			p, err := f.store.Capture(f.restaurantID, taken.ID, tt.tip)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if p.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", p.Status, tt.wantStatus)
+			}
+			if p.TipMinor != tt.wantTip {
+				t.Errorf("tip = %d, want %d", p.TipMinor, tt.wantTip)
+			}
+		})
+	}
+}
+
+func TestCapturePaymentRetryAfterUnavailable(t *testing.T) {
+	f := newPaymentFixture(t, 10000)
+	params := f.card("k1", 4000)
+	params.Capture = false
+	taken, _, err := f.store.Take(f.restaurantID, f.orderID, params)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	f.provider.FailNext(payments.OpCapture, payments.ErrUnavailable)
+	_, err = f.store.Capture(f.restaurantID, taken.ID, nil)
+	if !errors.Is(err, payments.ErrUnavailable) {
+		t.Fatalf("first call: err = %v, want %v", err, payments.ErrUnavailable)
+	}
+
+	p, err := f.store.Capture(f.restaurantID, taken.ID, nil)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if p.Status != PaymentStatusCaptured {
+		t.Errorf("status = %s, want %s", p.Status, PaymentStatusCaptured)
+	}
+
+	_, err = f.store.Capture(f.restaurantID, taken.ID, nil)
+	if !errors.Is(err, ErrPaymentNotCapturable) {
+		t.Errorf("repeat: err = %v, want %v", err, ErrPaymentNotCapturable)
+	}
+}
+
+func TestVoidPayment(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  func(f *paymentFixture) TakePaymentParams
+		fail    error
+		wantErr error
+	}{
+		{
+			name: "authorized",
+			params: func(f *paymentFixture) TakePaymentParams {
+				p := f.card("k1", 4000)
+				p.Capture = false
+				return p
+			},
+		},
+		{
+			name: "cash",
+			params: func(f *paymentFixture) TakePaymentParams {
+				p := f.card("k1", 4000)
+				p.Method = payments.MethodCash
+				return p
+			},
+		},
+		{
+			name:    "captured card",
+			params:  func(f *paymentFixture) TakePaymentParams { return f.card("k1", 4000) },
+			wantErr: ErrPaymentNotVoidable,
+		},
+		{
+			name: "provider unavailable",
+			params: func(f *paymentFixture) TakePaymentParams {
+				p := f.card("k1", 4000)
+				p.Capture = false
+				return p
+			},
+			fail:    payments.ErrUnavailable,
+			wantErr: payments.ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 10000)
+			taken, _, err := f.store.Take(f.restaurantID, f.orderID, tt.params(f))
+			if err != nil {
+				t.Fatalf("Take: %v", err)
+			}
+
+			if tt.fail != nil {
+				f.provider.FailNext(payments.OpVoid, tt.fail)
+			}
+
+			_, err = f.store.Void(f.restaurantID, taken.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			p, err := f.store.GetById(f.restaurantID, taken.ID)
+			if err != nil {
+				t.Fatalf("GetById: %v", err)
+			}
+			wantStatus := PaymentStatusVoided
+			if tt.wantErr != nil {
+				wantStatus = taken.Status
+			}
+			if p.Status != wantStatus {
+				t.Errorf("status = %s, want %s", p.Status, wantStatus)
+			}
+		})
+	}
+}
+
+func TestRefundPayment(t *testing.T) {
+	type refund struct {
+		key     string
+		amount  int64
+		wantErr error
+	}
+
+	tests := []struct {
+		name         string
+		refunds      []refund
+		wantRefunded int64
+	}{
+		{
+			name:         "full",
+			refunds:      []refund{{key: "r1", amount: 4000}},
+			wantRefunded: 4000,
+		},
+		{
+			name:         "partial",
+			refunds:      []refund{{key: "r1", amount: 1500}, {key: "r2", amount: 2500}},
+			wantRefunded: 4000,
+		},
+		{
+			name:         "more than the payment",
+			refunds:      []refund{{key: "r1", amount: 4001, wantErr: ErrRefundExceedsPayment}},
+			wantRefunded: 0,
+		},
+		{
+			name:         "partial then more than is left",
+			refunds:      []refund{{key: "r1", amount: 3000}, {key: "r2", amount: 1001, wantErr: ErrRefundExceedsPayment}},
+			wantRefunded: 3000,
+		},
+		{
+			name:         "repeated key counts once",
+			refunds:      []refund{{key: "r1", amount: 3000}, {key: "r1", amount: 3000}, {key: "r2", amount: 1000}},
+			wantRefunded: 4000,
+		},
+		{
+			name:         "key reused for another amount",
+			refunds:      []refund{{key: "r1", amount: 3000}, {key: "r1", amount: 1000, wantErr: ErrIdempotencyKeyReused}},
+			wantRefunded: 3000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 10000)
+			taken, _, err := f.store.Take(f.restaurantID, f.orderID, f.card("k1", 4000))
+			if err != nil {
+				t.Fatalf("Take: %v", err)
+			}
+
+			for _, r := range tt.refunds {
+				_, _, err := f.store.Refund(f.restaurantID, taken.ID, f.refund(r.key, r.amount))
+				if !errors.Is(err, r.wantErr) {
+					t.Errorf("refund %s of %d: err = %v, want %v", r.key, r.amount, err, r.wantErr)
+				}
+			}
+
+			p, err := f.store.GetById(f.restaurantID, taken.ID)
+			if err != nil {
+				t.Fatalf("GetById: %v", err)
+			}
+			if p.RefundedMinor != tt.wantRefunded {
+				t.Errorf("refunded %d, want %d", p.RefundedMinor, tt.wantRefunded)
+			}
+		})
+	}
+}
+
+func TestRefundPaymentRetryAfterUnavailable(t *testing.T) {
+	f := newPaymentFixture(t, 10000)
+	taken, _, err := f.store.Take(f.restaurantID, f.orderID, f.card("k1", 4000))
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	f.provider.FailNext(payments.OpRefund, payments.ErrUnavailable)
+	first, _, err := f.store.Refund(f.restaurantID, taken.ID, f.refund("r1", 1000))
+	if !errors.Is(err, payments.ErrUnavailable) {
+		t.Fatalf("first call: err = %v, want %v", err, payments.ErrUnavailable)
+	}
+	if first.Status != RefundStatusPending {
+		t.Errorf("status = %s, want %s", first.Status, RefundStatusPending)
+	}
+
+	for i := 0; i < 2; i++ {
+		r, created, err := f.store.Refund(f.restaurantID, taken.ID, f.refund("r1", 1000))
+		if err != nil {
+			t.Fatalf("call %d: %v", i+2, err)
+		}
+		if created || r.ID != first.ID || r.Status != RefundStatusSucceeded {
+			t.Errorf("call %d returned %s %s, want %s succeeded", i+2, r.ID, r.Status, first.ID)
+		}
+	}
+
+	p, err := f.store.GetById(f.restaurantID, taken.ID)
+	if err != nil {
+		t.Fatalf("GetById: %v", err)
+	}
+	if p.RefundedMinor != 1000 {
+		t.Errorf("refunded %d, want 1000", p.RefundedMinor)
+	}
+
+	// the provider refunded once: it has 3000 left to give back
+	_, err = f.provider.Refund(*p.ProviderReference, "probe", 3001)
+	if !errors.Is(err, payments.ErrInvalidAmount) {
+		t.Errorf("provider refund beyond what is left: err = %v, want %v", err, payments.ErrInvalidAmount)
+	}
+}
+
+func TestRefundPaymentNotCaptured(t *testing.T) {
+	f := newPaymentFixture(t, 10000)
+	params := f.card("k1", 4000)
+	params.Capture = false
+	taken, _, err := f.store.Take(f.restaurantID, f.orderID, params)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	_, _, err = f.store.Refund(f.restaurantID, taken.ID, f.refund("r1", 1000))
+	if !errors.Is(err, ErrPaymentNotRefundable) {
+		t.Errorf("err = %v, want %v", err, ErrPaymentNotRefundable)
+	}
+}
@@ -1,8 +1,9 @@
 package store
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"htrr-apis/internal/utils"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,6 +20,12 @@ type Position struct {
 	UpdatedAt time.Time `json:"updated_at" `
 }
 
+type ListPositionParams struct {
+	Page     int
+	PageSize int
+	Title    string
+}
+
 func NewPosition(pgDb *sql.DB) *PostgresPosition {
 	return &PostgresPosition{
 		db: pgDb,
@@ -26,18 +33,23 @@ func NewPosition(pgDb *sql.DB) *PostgresPosition {
 }
 
 type PositionStore interface {
-	Create(*Position) error
-	Update(Position) error
-	GetById(string) (*Position, error)
+	Create(context.Context, *Position) error
+	List(context.Context, ListPositionParams) ([]Position, int, error)
+	Update(context.Context, *Position) error
+	GetById(context.Context, string) (*Position, error)
+	Delete(context.Context, string) error
 }
 
-func (pg *PostgresPosition) Create(pos *Position) error {
+func (pg *PostgresPosition) Create(ctx context.Context, pos *Position) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	q := `
 	INSERT INTO positions (title)
-	VALUES $1
+	VALUES ($1)
 	RETURNING id, created_at, updated_at
 	`
-	err := pg.db.QueryRow(q, pos.Title).Scan(
+	err := pg.db.QueryRowContext(ctx, q, pos.Title).Scan(
 		&pos.ID,
 		&pos.CreatedAt,
 		&pos.UpdatedAt)
@@ -48,10 +60,53 @@ func (pg *PostgresPosition) Create(pos *Position) error {
 	return nil
 }
 
-func (pg *PostgresPosition) GetById(id string) (*Position, error) {
+func (pg *PostgresPosition) List(ctx context.Context, params ListPositionParams) ([]Position, int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	q := `
+	SELECT id, title, created_at, updated_at,
+			COUNT(*) OVER()
+	FROM positions
+	WHERE ($1 = '' OR title ILIKE $1 ESCAPE '\')
+	ORDER BY title
+	LIMIT $2 OFFSET $3
+	`
+	limit, offset := utils.GetOffset(&params.Page, &params.PageSize)
+
+	rows, err := pg.db.QueryContext(ctx, q, containsPattern(params.Title), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var total int
+	list := []Position{}
+	for rows.Next() {
+		var pos Position
+		err := rows.Scan(
+			&pos.ID,
+			&pos.Title,
+			&pos.CreatedAt,
+			&pos.UpdatedAt,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, pos)
+	}
+
+	return list, total, rows.Err()
+}
+
+func (pg *PostgresPosition) GetById(ctx context.Context, id string) (*Position, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	_, err := uuid.Parse(id)
 	if err != nil {
-		return nil, errors.New("invalid id format")
+		return nil, ErrInvalidID
 	}
 	q := `
 	SELECT id, title, created_at, updated_at
@@ -59,14 +114,14 @@ func (pg *PostgresPosition) GetById(id string) (*Position, error) {
 	WHERE id = $1
 	`
 	pos := &Position{}
-	err = pg.db.QueryRow(q, id).Scan(
+	err = pg.db.QueryRowContext(ctx, q, id).Scan(
 		&pos.ID,
 		&pos.Title,
 		&pos.CreatedAt,
 		&pos.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, notFound("position")
 	}
 
 	if err != nil {
@@ -76,22 +131,51 @@ func (pg *PostgresPosition) GetById(id string) (*Position, error) {
 	return pos, nil
 }
 
-func (pg *PostgresPosition) Update(pos Position) error {
+func (pg *PostgresPosition) Update(ctx context.Context, pos *Position) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	_, err := uuid.Parse(pos.ID)
 	if err != nil {
-		return errors.New("Invalid id format")
+		return ErrInvalidID
 	}
 
 	q := `
 	UPDATE positions
-	SET title = $1,
+	SET title = $1
 	WHERE id = $2
+	RETURNING updated_at
 	`
-	_, err = pg.db.Exec(q, pos.Title, pos.ID)
+	err = pg.db.QueryRowContext(ctx, q, pos.Title, pos.ID).Scan(&pos.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (pg *PostgresPosition) Delete(ctx context.Context, id string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := pg.db.ExecContext(ctx, `DELETE FROM positions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
 
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return notFound("position")
+	}
+
 	return nil
 }
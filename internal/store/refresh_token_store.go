@@ -0,0 +1,225 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenRevoked  = errors.New("refresh token has been revoked")
+	ErrRefreshTokenExpired  = errors.New("refresh token has expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected")
+)
+
+type PostgresRefreshTokenStore struct {
+	db *sql.DB
+}
+
+func NewPostgresRefreshTokenStore(db *sql.DB) *PostgresRefreshTokenStore {
+	return &PostgresRefreshTokenStore{
+		db: db,
+	}
+}
+
+// RefreshToken is one link in a rotation chain. Every token issued from the
+// same login shares a FamilyID, so the family represents a device session.
+type RefreshToken struct {
+	ID         string
+	UserID     string
+	FamilyID   string
+	Hash       []byte
+	DeviceName string
+	ExpiresAt  time.Time
+	UsedAt     *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type RefreshTokenStore interface {
+	Create(*RefreshToken) error
+	Rotate(oldHash []byte, next *RefreshToken) error
+	RevokeFamilyByHash([]byte) error
+	ListSessions(userID string) ([]Session, error)
+	RevokeSession(userID, familyID string) error
+}
+
+// Create starts a new token family unless FamilyID is already set.
+func (pg *PostgresRefreshTokenStore) Create(token *RefreshToken) error {
+	if token.FamilyID == "" {
+		token.FamilyID = uuid.NewString()
+	}
+
+	q := `
+	INSERT INTO refresh_tokens (user_id, family_id, token_hash, device_name, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`
+	return pg.db.QueryRow(q,
+		token.UserID,
+		token.FamilyID,
+		token.Hash,
+		token.DeviceName,
+		token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// Rotate marks the token identified by oldHash as used and stores next in
+// the same family. Presenting a token that was already used means it has
+// been copied, so the whole family is revoked and ErrRefreshTokenReused is
+// returned.
+func (pg *PostgresRefreshTokenStore) Rotate(oldHash []byte, next *RefreshToken) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := RefreshToken{}
+	err = tx.QueryRow(`
+	SELECT id, user_id, family_id, device_name, expires_at, used_at, revoked_at
+	FROM refresh_tokens
+	WHERE token_hash = $1
+	FOR UPDATE
+	`, oldHash).Scan(
+		&current.ID,
+		&current.UserID,
+		&current.FamilyID,
+		&current.DeviceName,
+		&current.ExpiresAt,
+		&current.UsedAt,
+		&current.RevokedAt)
+
+	if err == sql.ErrNoRows {
+		return ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if current.RevokedAt != nil {
+		return ErrRefreshTokenRevoked
+	}
+
+	if current.UsedAt != nil {
+		_, err = tx.Exec(`
+		UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = $1 AND revoked_at IS NULL
+		`, current.FamilyID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return ErrRefreshTokenExpired
+	}
+
+	_, err = tx.Exec(`UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, current.ID)
+	if err != nil {
+		return err
+	}
+
+	next.UserID = current.UserID
+	next.FamilyID = current.FamilyID
+	next.DeviceName = current.DeviceName
+
+	err = tx.QueryRow(`
+	INSERT INTO refresh_tokens (user_id, family_id, token_hash, device_name, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`, next.UserID, next.FamilyID, next.Hash, next.DeviceName, next.ExpiresAt).
+		Scan(&next.ID, &next.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresRefreshTokenStore) RevokeFamilyByHash(hash []byte) error {
+	q := `
+	UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+	WHERE revoked_at IS NULL
+		AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+	`
+	_, err := pg.db.Exec(q, hash)
+	return err
+}
+
+func (pg *PostgresRefreshTokenStore) ListSessions(userID string) ([]Session, error) {
+	q := `
+	SELECT family_id, MAX(device_name), MIN(created_at), MAX(created_at), MAX(expires_at)
+	FROM refresh_tokens
+	WHERE user_id = $1
+	GROUP BY family_id
+	HAVING bool_or(revoked_at IS NULL AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP)
+	ORDER BY MAX(created_at) DESC
+	`
+	rows, err := pg.db.Query(q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var s Session
+		err := rows.Scan(
+			&s.ID,
+			&s.DeviceName,
+			&s.CreatedAt,
+			&s.LastUsedAt,
+			&s.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes every token of the family, as long as it belongs to
+// userID. It returns ErrNotFound when there was nothing to revoke.
+func (pg *PostgresRefreshTokenStore) RevokeSession(userID, familyID string) error {
+	_, err := uuid.Parse(familyID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+	`
+	result, err := pg.db.Exec(q, userID, familyID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("session")
+	}
+
+	return nil
+}
@@ -0,0 +1,314 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"htrr-apis/internal/validate"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrOutsideOpeningHours = invalid("booking_time", "booking falls outside the restaurant's opening hours")
+
+const dateLayout = "2006-01-02"
+
+// OpeningInterval is one period the restaurant is open on a weekday, as
+// "HH:MM" wall-clock times in the restaurant's timezone. DayOfWeek follows
+// time.Weekday. When ClosesAt is not after OpensAt the interval runs past
+// midnight into the following day.
+type OpeningInterval struct {
+	DayOfWeek int    `json:"day_of_week"`
+	OpensAt   string `json:"opens_at"`
+	ClosesAt  string `json:"closes_at"`
+}
+
+// Closure marks a local date on which no interval starts, e.g. a holiday.
+type Closure struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+}
+
+// Hours is the weekly schedule of a restaurant. A restaurant without any
+// weekly interval has no restriction besides its closures.
+type Hours struct {
+	Timezone string            `json:"timezone"`
+	Weekly   []OpeningInterval `json:"weekly"`
+	Closures []Closure         `json:"closures"`
+}
+
+func parseClock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a valid HH:MM time", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func (h *Hours) Validate() error {
+	v := validate.New()
+	_, err := time.LoadLocation(h.Timezone)
+	v.Check(err == nil && h.Timezone != "", "timezone", "timezone must be a valid IANA timezone")
+	for i, iv := range h.Weekly {
+		field := fmt.Sprintf("weekly[%d]", i)
+		v.Check(iv.DayOfWeek >= 0 && iv.DayOfWeek <= 6, field+".day_of_week",
+			"day_of_week must be between 0 (Sunday) and 6 (Saturday)")
+		if _, _, err := parseClock(iv.OpensAt); err != nil {
+			v.Add(field+".opens_at", "opens_at: "+err.Error())
+		}
+		if _, _, err := parseClock(iv.ClosesAt); err != nil {
+			v.Add(field+".closes_at", "closes_at: "+err.Error())
+		}
+		v.Check(iv.OpensAt != iv.ClosesAt, field+".closes_at", "opens_at and closes_at must differ")
+	}
+	seen := map[string]bool{}
+	for i, c := range h.Closures {
+		field := fmt.Sprintf("closures[%d].date", i)
+		if _, err := time.Parse(dateLayout, c.Date); err != nil {
+			v.Add(field, fmt.Sprintf("closure date %q must be YYYY-MM-DD", c.Date))
+		}
+		v.Check(!seen[c.Date], field, fmt.Sprintf("closure date %s is listed twice", c.Date))
+		seen[c.Date] = true
+	}
+	return v.Err()
+}
+
+func (h *Hours) location() *time.Location {
+	loc, err := time.LoadLocation(h.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (h *Hours) closedOn(day time.Time) bool {
+	date := day.Format(dateLayout)
+	for _, c := range h.Closures {
+		if c.Date == date {
+			return true
+		}
+	}
+	return false
+}
+
+// openUntil returns the end of the opening interval containing t, if any.
+// Intervals that started yesterday are considered so overnight spans count.
+func (h *Hours) openUntil(t time.Time, loc *time.Location) (time.Time, bool) {
+	local := t.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	if len(h.Weekly) == 0 {
+		if h.closedOn(today) {
+			return time.Time{}, false
+		}
+		return today.AddDate(0, 0, 1), true
+	}
+
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		if h.closedOn(day) {
+			continue
+		}
+		for _, iv := range h.Weekly {
+			if time.Weekday(iv.DayOfWeek) != day.Weekday() {
+				continue
+			}
+			oh, om, err := parseClock(iv.OpensAt)
+			if err != nil {
+				continue
+			}
+			ch, cm, err := parseClock(iv.ClosesAt)
+			if err != nil {
+				continue
+			}
+
+			y, m, d := day.Date()
+			opens := time.Date(y, m, d, oh, om, 0, 0, loc)
+			closes := time.Date(y, m, d, ch, cm, 0, 0, loc)
+			if !closes.After(opens) {
+				closes = time.Date(y, m, d+1, ch, cm, 0, 0, loc)
+			}
+
+			if !t.Before(opens) && t.Before(closes) {
+				return closes, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// IsOpen reports whether the restaurant is open at t.
+func (h *Hours) IsOpen(t time.Time) bool {
+	_, ok := h.openUntil(t, h.location())
+	return ok
+}
+
+// Covers reports whether the restaurant stays open for the whole of
+// [start, end). Back-to-back intervals, such as 12:00-15:00 followed by
+// 15:00-22:00, are treated as one.
+func (h *Hours) Covers(start, end time.Time) bool {
+	loc := h.location()
+	cur := start
+	for i := 0; i < 16 && cur.Before(end); i++ {
+		until, ok := h.openUntil(cur, loc)
+		if !ok {
+			return false
+		}
+		cur = until
+	}
+	return !cur.Before(end)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx, so helpers can read
+// inside or outside a transaction.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// loadHours reads the schedule of a restaurant that is known to exist.
+func loadHours(ctx context.Context, q queryer, restaurantID string) (*Hours, error) {
+	h := &Hours{Weekly: []OpeningInterval{}, Closures: []Closure{}}
+
+	err := q.QueryRowContext(ctx, `SELECT timezone FROM restaurants WHERE id = $1`, restaurantID).Scan(&h.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+	SELECT day_of_week, to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI')
+	FROM restaurant_opening_hours
+	WHERE restaurant_id = $1
+	ORDER BY day_of_week, opens_at
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var iv OpeningInterval
+		if err := rows.Scan(&iv.DayOfWeek, &iv.OpensAt, &iv.ClosesAt); err != nil {
+			return nil, err
+		}
+		h.Weekly = append(h.Weekly, iv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, `
+	SELECT to_char(closed_on, 'YYYY-MM-DD'), reason
+	FROM restaurant_closures
+	WHERE restaurant_id = $1
+	ORDER BY closed_on
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Closure
+		if err := rows.Scan(&c.Date, &c.Reason); err != nil {
+			return nil, err
+		}
+		h.Closures = append(h.Closures, c)
+	}
+
+	return h, rows.Err()
+}
+
+func (pg *PostgresRestaurantStore) GetHours(ctx context.Context, restaurantID string, scope Scope) (*Hours, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var exists bool
+	err = pg.db.QueryRowContext(ctx, `
+	SELECT EXISTS (SELECT 1 FROM restaurants WHERE id = $1 AND ($2::uuid[] IS NULL OR id = ANY($2)))
+	`, restaurantID, scope.Arg()).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, notFound("restaurant")
+	}
+
+	return loadHours(ctx, pg.db, restaurantID)
+}
+
+// ReplaceHours overwrites the timezone, weekly intervals and closures of a
+// restaurant in one transaction.
+func (pg *PostgresRestaurantStore) ReplaceHours(ctx context.Context, restaurantID string, h *Hours, scope Scope) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE restaurants SET timezone = $1
+	WHERE id = $2 AND ($3::uuid[] IS NULL OR id = ANY($3))
+	`, h.Timezone, restaurantID, scope.Arg())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("restaurant")
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM restaurant_opening_hours WHERE restaurant_id = $1`, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	for _, iv := range h.Weekly {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO restaurant_opening_hours (restaurant_id, day_of_week, opens_at, closes_at)
+		VALUES ($1, $2, $3, $4)
+		`, restaurantID, iv.DayOfWeek, iv.OpensAt, iv.ClosesAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM restaurant_closures WHERE restaurant_id = $1`, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range h.Closures {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO restaurant_closures (restaurant_id, closed_on, reason)
+		VALUES ($1, $2, $3)
+		`, restaurantID, c.Date, c.Reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -1,8 +1,8 @@
 package store
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"htrr-apis/internal/utils"
 	"time"
 
@@ -26,6 +26,8 @@ type Restaurant struct {
 	Address   string    `json:"address"`
 	Phone     string    `json:"phone"`
 	IsActive  bool      `json:"is_active"`
+	Timezone  string    `json:"timezone"`
+	OpenNow   *bool     `json:"open_now,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -34,6 +36,7 @@ type SearchRestaurantParams struct {
 	Page     int
 	PageSize int
 	Name     string
+	Scope    Scope
 }
 
 type BulkDeleteResult struct {
@@ -44,27 +47,34 @@ type BulkDeleteResult struct {
 }
 
 type RestaurantStore interface {
-	Create(*Restaurant) error
-	Search(SearchRestaurantParams) ([]Restaurant, int, error)
-	Update(*Restaurant) error
-	GetRestaurantById(string) (*Restaurant, error)
-	Delete(string) error
-	BulkDeleteAtomic([]string) (int, error)
-	BulkDeletePartial([]string) (*BulkDeleteResult, error)
-	BulkDeleteBestEffort([]string) (int, error)
+	Create(context.Context, *Restaurant) error
+	Search(context.Context, SearchRestaurantParams) ([]Restaurant, int, error)
+	Update(context.Context, *Restaurant, Scope) error
+	GetRestaurantById(context.Context, string, Scope) (*Restaurant, error)
+	Delete(context.Context, string, Scope) error
+	BulkDeleteAtomic(context.Context, []string, Scope) (int, error)
+	BulkDeletePartial(context.Context, []string, Scope) (*BulkDeleteResult, error)
+	BulkDeleteBestEffort(context.Context, []string, Scope) (int, error)
+	ListIDsForEmployee(ctx context.Context, userID string) ([]string, error)
+	GetHours(ctx context.Context, restaurantID string, scope Scope) (*Hours, error)
+	ReplaceHours(ctx context.Context, restaurantID string, hours *Hours, scope Scope) error
 }
 
-func (pg *PostgresRestaurantStore) Create(restaurant *Restaurant) error {
+func (pg *PostgresRestaurantStore) Create(ctx context.Context, restaurant *Restaurant) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	q := `
-	INSERT INTO restaurants (name, address, phone, is_active)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO restaurants (name, address, phone, is_active, timezone)
+	VALUES ($1, $2, $3, $4, $5)
 	RETURNING id, name, created_at
 	`
-	err := pg.db.QueryRow(q,
+	err := pg.db.QueryRowContext(ctx, q,
 		restaurant.Name,
 		restaurant.Address,
 		restaurant.Phone,
-		restaurant.IsActive).
+		restaurant.IsActive,
+		restaurant.Timezone).
 		Scan(&restaurant.ID,
 			&restaurant.Name,
 			&restaurant.CreatedAt)
@@ -76,18 +86,22 @@ func (pg *PostgresRestaurantStore) Create(restaurant *Restaurant) error {
 	return nil
 }
 
-func (pg *PostgresRestaurantStore) Search(params SearchRestaurantParams) ([]Restaurant, int, error) {
+func (pg *PostgresRestaurantStore) Search(ctx context.Context, params SearchRestaurantParams) ([]Restaurant, int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	q := `
-	SELECT id, name, address, phone, is_active, created_at, updated_at,
+	SELECT id, name, address, phone, is_active, timezone, created_at, updated_at,
 			COUNT(*) OVER()
 	FROM restaurants
-	WHERE ($1 = '' OR name ILIKE '%' || $1 || '%')
+	WHERE ($1 = '' OR name ILIKE $1 ESCAPE '\')
+		AND ($4::uuid[] IS NULL OR id = ANY($4))
 	ORDER BY name
 	LIMIT $2 OFFSET $3
 	`
 	limit, offset := utils.GetOffset(&params.Page, &params.PageSize)
 
-	row, err := pg.db.Query(q, params.Name, limit, offset)
+	row, err := pg.db.QueryContext(ctx, q, containsPattern(params.Name), limit, offset, params.Scope.Arg())
 	if err != nil {
 		return nil, 0, err
 	}
@@ -103,6 +117,7 @@ func (pg *PostgresRestaurantStore) Search(params SearchRestaurantParams) ([]Rest
 			&rtr.Address,
 			&rtr.Phone,
 			&rtr.IsActive,
+			&rtr.Timezone,
 			&rtr.CreatedAt,
 			&rtr.UpdatedAt,
 			&total,
@@ -116,57 +131,75 @@ func (pg *PostgresRestaurantStore) Search(params SearchRestaurantParams) ([]Rest
 	return list, total, nil
 }
 
-func (pg *PostgresRestaurantStore) Update(restaurant *Restaurant) error {
+func (pg *PostgresRestaurantStore) Update(ctx context.Context, restaurant *Restaurant, scope Scope) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	_, err := uuid.Parse(restaurant.ID)
 	if err != nil {
-		return errors.New("Invalid id format")
+		return ErrInvalidID
 	}
 
 	q := `
 	UPDATE restaurants
-	SET name = $1, address = $2, phone = $3, is_active = $4
-	WHERE id = $5
+	SET name = $1, address = $2, phone = $3, is_active = $4, timezone = $5
+	WHERE id = $6 AND ($7::uuid[] IS NULL OR id = ANY($7))
 	`
 
-	_, err = pg.db.Exec(q,
+	result, err := pg.db.ExecContext(ctx, q,
 		restaurant.Name,
 		restaurant.Address,
 		restaurant.Phone,
 		restaurant.IsActive,
-		restaurant.ID)
+		restaurant.Timezone,
+		restaurant.ID,
+		scope.Arg())
+
+	if err != nil {
+		return err
+	}
 
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return notFound("restaurant")
+	}
+
 	return nil
 }
 
-func (pg *PostgresRestaurantStore) GetRestaurantById(id string) (*Restaurant, error) {
+func (pg *PostgresRestaurantStore) GetRestaurantById(ctx context.Context, id string, scope Scope) (*Restaurant, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	q := `
-	SELECT id, name, address, phone, is_active, created_at, updated_at
+	SELECT id, name, address, phone, is_active, timezone, created_at, updated_at
 	FROM restaurants
-	WHERE id = $1
+	WHERE id = $1 AND ($2::uuid[] IS NULL OR id = ANY($2))
 	`
 
 	_, err := uuid.Parse(id)
 	if err != nil {
-		return nil, errors.New("Invalid id format")
+		return nil, ErrInvalidID
 	}
 
 	restaurant := &Restaurant{}
-	err = pg.db.QueryRow(q, id).Scan(
+	err = pg.db.QueryRowContext(ctx, q, id, scope.Arg()).Scan(
 		&restaurant.ID,
 		&restaurant.Name,
 		&restaurant.Address,
 		&restaurant.Phone,
 		&restaurant.IsActive,
+		&restaurant.Timezone,
 		&restaurant.CreatedAt,
 		&restaurant.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, notFound("restaurant")
 	}
 
 	if err != nil {
@@ -176,17 +209,20 @@ func (pg *PostgresRestaurantStore) GetRestaurantById(id string) (*Restaurant, er
 	return restaurant, nil
 }
 
-func (pg *PostgresRestaurantStore) Delete(id string) error {
+func (pg *PostgresRestaurantStore) Delete(ctx context.Context, id string, scope Scope) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	q := `
-	DELETE FROM restaurants WHERE id = $1
+	DELETE FROM restaurants WHERE id = $1 AND ($2::uuid[] IS NULL OR id = ANY($2))
 	`
 
 	_, err := uuid.Parse(id)
 	if err != nil {
-		return errors.New("Invalid id format")
+		return ErrInvalidID
 	}
 
-	result, err := pg.db.Exec(q, id)
+	result, err := pg.db.ExecContext(ctx, q, id, scope.Arg())
 	if err != nil {
 		return err
 	}
@@ -197,31 +233,34 @@ func (pg *PostgresRestaurantStore) Delete(id string) error {
 	}
 
 	if rowEffected == 0 {
-		return sql.ErrNoRows
+		return notFound("restaurant")
 	}
 
 	return nil
 }
 
-func (pg *PostgresRestaurantStore) BulkDeleteAtomic(ids []string) (int, error) {
+func (pg *PostgresRestaurantStore) BulkDeleteAtomic(ctx context.Context, ids []string, scope Scope) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// Validate all IDs upfront
 	for _, id := range ids {
 		_, err := uuid.Parse(id)
 		if err != nil {
-			return 0, errors.New("Invalid id format")
+			return 0, ErrInvalidID
 		}
 	}
 
 	// Start transaction
-	tx, err := pg.db.Begin()
+	tx, err := pg.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 	defer tx.Rollback()
 
 	// Delete with ANY clause for all IDs
-	q := `DELETE FROM restaurants WHERE id = ANY($1)`
-	result, err := tx.Exec(q, pq.Array(ids))
+	q := `DELETE FROM restaurants WHERE id = ANY($1) AND ($2::uuid[] IS NULL OR id = ANY($2))`
+	result, err := tx.ExecContext(ctx, q, pq.Array(ids), scope.Arg())
 	if err != nil {
 		return 0, err
 	}
@@ -231,9 +270,9 @@ func (pg *PostgresRestaurantStore) BulkDeleteAtomic(ids []string) (int, error) {
 		return 0, err
 	}
 
-	// If no rows were deleted, return error
-	if rowsAffected == 0 {
-		return 0, sql.ErrNoRows
+	// All or nothing: any missing or out of scope id rolls the delete back
+	if rowsAffected == 0 || int(rowsAffected) < len(uniqueIDs(ids)) {
+		return 0, notFound("one or more restaurants")
 	}
 
 	// Commit transaction
@@ -244,7 +283,10 @@ func (pg *PostgresRestaurantStore) BulkDeleteAtomic(ids []string) (int, error) {
 	return int(rowsAffected), nil
 }
 
-func (pg *PostgresRestaurantStore) BulkDeletePartial(ids []string) (*BulkDeleteResult, error) {
+func (pg *PostgresRestaurantStore) BulkDeletePartial(ctx context.Context, ids []string, scope Scope) (*BulkDeleteResult, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	result := &BulkDeleteResult{
 		DeletedIDs: []string{},
 		FailedIDs:  []string{},
@@ -268,15 +310,15 @@ func (pg *PostgresRestaurantStore) BulkDeletePartial(ids []string) (*BulkDeleteR
 	}
 
 	// Start transaction
-	tx, err := pg.db.Begin()
+	tx, err := pg.db.BeginTx(ctx, nil)
 	if err != nil {
 		return result, err
 	}
 	defer tx.Rollback()
 
 	// First, query which valid IDs exist in the database
-	existingQuery := `SELECT id FROM restaurants WHERE id = ANY($1)`
-	rows, err := tx.Query(existingQuery, pq.Array(validIDs))
+	existingQuery := `SELECT id FROM restaurants WHERE id = ANY($1) AND ($2::uuid[] IS NULL OR id = ANY($2))`
+	rows, err := tx.QueryContext(ctx, existingQuery, pq.Array(validIDs), scope.Arg())
 	if err != nil {
 		return result, err
 	}
@@ -302,8 +344,8 @@ func (pg *PostgresRestaurantStore) BulkDeletePartial(ids []string) (*BulkDeleteR
 
 	// Delete the valid IDs that exist
 	if len(existingSet) > 0 {
-		deleteQuery := `DELETE FROM restaurants WHERE id = ANY($1)`
-		deleteResult, err := tx.Exec(deleteQuery, pq.Array(validIDs))
+		deleteQuery := `DELETE FROM restaurants WHERE id = ANY($1) AND ($2::uuid[] IS NULL OR id = ANY($2))`
+		deleteResult, err := tx.ExecContext(ctx, deleteQuery, pq.Array(validIDs), scope.Arg())
 		if err != nil {
 			return result, err
 		}
@@ -329,7 +371,10 @@ func (pg *PostgresRestaurantStore) BulkDeletePartial(ids []string) (*BulkDeleteR
 	return result, nil
 }
 
-func (pg *PostgresRestaurantStore) BulkDeleteBestEffort(ids []string) (int, error) {
+func (pg *PostgresRestaurantStore) BulkDeleteBestEffort(ctx context.Context, ids []string, scope Scope) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// Filter to only valid UUID IDs
 	validIDs := []string{}
 	for _, id := range ids {
@@ -347,8 +392,8 @@ func (pg *PostgresRestaurantStore) BulkDeleteBestEffort(ids []string) (int, erro
 	}
 
 	// Delete valid IDs
-	q := `DELETE FROM restaurants WHERE id = ANY($1)`
-	result, err := pg.db.Exec(q, pq.Array(validIDs))
+	q := `DELETE FROM restaurants WHERE id = ANY($1) AND ($2::uuid[] IS NULL OR id = ANY($2))`
+	result, err := pg.db.ExecContext(ctx, q, pq.Array(validIDs), scope.Arg())
 	if err != nil {
 		return 0, err
 	}
@@ -360,3 +405,39 @@ func (pg *PostgresRestaurantStore) BulkDeleteBestEffort(ids []string) (int, erro
 
 	return int(rowsAffected), nil
 }
+
+// ListIDsForEmployee returns the restaurants the user is employed at.
+func (pg *PostgresRestaurantStore) ListIDsForEmployee(ctx context.Context, userID string) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	q := `
+	SELECT restaurant_id
+	FROM employees
+	WHERE user_id = $1 AND restaurant_id IS NOT NULL
+	`
+	rows, err := pg.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func uniqueIDs(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,41 @@
+package store
+
+import "github.com/lib/pq"
+
+// Scope is the set of restaurants a caller may see. Store methods apply it
+// inside their WHERE clause, so rows outside the scope behave as missing.
+// The zero value sees no restaurant at all; only Unrestricted, which is
+// reserved for owners and public lookups, sees every one.
+type Scope struct {
+	Unrestricted  bool
+	RestaurantIDs []string
+}
+
+// UnscopedAccess grants access to every restaurant.
+var UnscopedAccess = Scope{Unrestricted: true}
+
+// Arg is meant for a `($n::uuid[] IS NULL OR restaurant_id = ANY($n))`
+// predicate: nil matches every row and is only returned for Unrestricted
+// scopes, an array matches only its members. An empty scope becomes an
+// empty array rather than NULL so it matches nothing.
+func (s Scope) Arg() any {
+	if s.Unrestricted {
+		return nil
+	}
+	if s.RestaurantIDs == nil {
+		return pq.Array([]string{})
+	}
+	return pq.Array(s.RestaurantIDs)
+}
+
+func (s Scope) Allows(restaurantID string) bool {
+	if s.Unrestricted {
+		return true
+	}
+	for _, id := range s.RestaurantIDs {
+		if id == restaurantID {
+			return true
+		}
+	}
+	return false
+}
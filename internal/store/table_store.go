@@ -0,0 +1,375 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	TableStatusAvailable    = "available"
+	TableStatusReserved     = "reserved"
+	TableStatusOccupied     = "occupied"
+	TableStatusCleaning     = "cleaning"
+	TableStatusOutOfService = "out_of_service"
+)
+
+var (
+	ErrDuplicateTableNumber   = conflict("table number already exists in this restaurant")
+	ErrIllegalTableTransition = conflict("table cannot move to that status from its current status")
+	ErrTableOutOfService      = invalid("table_id", "table is out of service")
+)
+
+// tableTransitions lists the statuses a table may move to from each status.
+var tableTransitions = map[string][]string{
+	TableStatusAvailable:    {TableStatusReserved, TableStatusOccupied, TableStatusOutOfService},
+	TableStatusReserved:     {TableStatusAvailable, TableStatusOccupied, TableStatusOutOfService},
+	TableStatusOccupied:     {TableStatusCleaning, TableStatusAvailable},
+	TableStatusCleaning:     {TableStatusAvailable, TableStatusOutOfService},
+	TableStatusOutOfService: {TableStatusAvailable},
+}
+
+func IsValidTableStatus(status string) bool {
+	_, ok := tableTransitions[status]
+	return ok
+}
+
+// CanTransitionTable reports whether a table may move from one status to
+// another.
+func CanTransitionTable(from, to string) bool {
+	for _, next := range tableTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// tableStatusesInto returns the statuses from which a table may move to the
+// given status, for use in a `status = ANY(...)` guard.
+func tableStatusesInto(to string) []string {
+	var from []string
+	for status, next := range tableTransitions {
+		for _, n := range next {
+			if n == to {
+				from = append(from, status)
+			}
+		}
+	}
+	return from
+}
+
+// moveTable changes the status of a table if the transition is allowed, and
+// reports whether it did.
+func moveTable(tx *sql.Tx, tableID, to string) (bool, error) {
+	result, err := tx.Exec(`
+	UPDATE tables SET status = $1, status_changed_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND status = ANY($3)
+	`, to, tableID, pq.Array(tableStatusesInto(to)))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+type PostgresTableStore struct {
+	db *sql.DB
+}
+
+func NewPostgresTableStore(db *sql.DB) *PostgresTableStore {
+	return &PostgresTableStore{
+		db: db,
+	}
+}
+
+type Table struct {
+	ID              string    `json:"id"`
+	RestaurantID    string    `json:"restaurant_id"`
+	TableNumber     string    `json:"table_number"`
+	Status          string    `json:"status"`
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	Capacity        int       `json:"capacity"`
+	MinPartySize    int       `json:"min_party_size"`
+	MaxPartySize    int       `json:"max_party_size"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// FloorTable is a table as the host sees it on the floor view.
+type FloorTable struct {
+	Table
+	CurrentBooking *Booking   `json:"current_booking"`
+	SeatedAt       *time.Time `json:"seated_at"`
+}
+
+// Fits reports whether a party of the given size may be seated at the table.
+func (t *Table) Fits(partySize int) bool {
+	return partySize >= t.MinPartySize && partySize <= t.MaxPartySize
+}
+
+type TableStore interface {
+	Create(*Table) error
+	ListByRestaurant(restaurantID string, partySize int) ([]Table, error)
+	GetById(restaurantID, id string) (*Table, error)
+	Update(*Table) error
+	Delete(restaurantID, id string) error
+	SetStatus(restaurantID, id, status string) (*Table, error)
+	Floor(restaurantID string) ([]FloorTable, error)
+}
+
+const tableSelect = `
+	SELECT id, restaurant_id, table_number, status, status_changed_at, capacity,
+			min_party_size, max_party_size, created_at, updated_at
+	FROM tables
+	`
+
+func scanTable(row interface{ Scan(...any) error }, t *Table) error {
+	return row.Scan(
+		&t.ID,
+		&t.RestaurantID,
+		&t.TableNumber,
+		&t.Status,
+		&t.StatusChangedAt,
+		&t.Capacity,
+		&t.MinPartySize,
+		&t.MaxPartySize,
+		&t.CreatedAt,
+		&t.UpdatedAt)
+}
+
+func mapTableError(err error) error {
+	if pgCode(err) == pgUniqueViolation {
+		return ErrDuplicateTableNumber
+	}
+	return err
+}
+
+func (pg *PostgresTableStore) Create(table *Table) error {
+	_, err := uuid.Parse(table.RestaurantID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	INSERT INTO tables (restaurant_id, table_number, capacity, min_party_size, max_party_size)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, status, status_changed_at, created_at, updated_at
+	`
+	err = pg.db.QueryRow(q,
+		table.RestaurantID,
+		table.TableNumber,
+		table.Capacity,
+		table.MinPartySize,
+		table.MaxPartySize).
+		Scan(
+			&table.ID,
+			&table.Status,
+			&table.StatusChangedAt,
+			&table.CreatedAt,
+			&table.UpdatedAt)
+	if err != nil {
+		return mapTableError(err)
+	}
+
+	return nil
+}
+
+// ListByRestaurant returns the tables of a restaurant. A positive partySize
+// keeps only the tables that suit a party of that size.
+func (pg *PostgresTableStore) ListByRestaurant(restaurantID string, partySize int) ([]Table, error) {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	q := tableSelect + `
+	WHERE restaurant_id = $1
+		AND ($2 <= 0 OR $2 BETWEEN min_party_size AND max_party_size)
+	ORDER BY table_number
+	`
+	rows, err := pg.db.Query(q, restaurantID, partySize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Table{}
+	for rows.Next() {
+		var t Table
+		if err := scanTable(rows, &t); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+
+	return list, rows.Err()
+}
+
+func (pg *PostgresTableStore) GetById(restaurantID, id string) (*Table, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	q := tableSelect + `
+	WHERE id = $1 AND restaurant_id = $2
+	`
+	t := &Table{}
+	err = scanTable(pg.db.QueryRow(q, id, restaurantID), t)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("table")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (pg *PostgresTableStore) Update(table *Table) error {
+	_, err := uuid.Parse(table.ID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE tables
+	SET table_number = $1, capacity = $2, min_party_size = $3, max_party_size = $4
+	WHERE id = $5 AND restaurant_id = $6
+	RETURNING updated_at
+	`
+	err = pg.db.QueryRow(q,
+		table.TableNumber,
+		table.Capacity,
+		table.MinPartySize,
+		table.MaxPartySize,
+		table.ID,
+		table.RestaurantID).
+		Scan(&table.UpdatedAt)
+	if err != nil {
+		return mapTableError(err)
+	}
+
+	return nil
+}
+
+func (pg *PostgresTableStore) Delete(restaurantID, id string) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := pg.db.Exec(`DELETE FROM tables WHERE id = $1 AND restaurant_id = $2`, id, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("table")
+	}
+
+	return nil
+}
+
+// SetStatus moves a table to another status when tableTransitions allows it.
+// It returns ErrNotFound when the table does not exist.
+func (pg *PostgresTableStore) SetStatus(restaurantID, id, status string) (*Table, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t := &Table{}
+	err = scanTable(tx.QueryRow(tableSelect+`WHERE id = $1 AND restaurant_id = $2 FOR UPDATE`, id, restaurantID), t)
+	if err == sql.ErrNoRows {
+		return nil, notFound("table")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !CanTransitionTable(t.Status, status) {
+		return nil, ErrIllegalTableTransition
+	}
+
+	_, err = moveTable(tx, id, status)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanTable(tx.QueryRow(tableSelect+`WHERE id = $1`, id), t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
+
+// Floor lists every table of a restaurant with the booking currently on it:
+// the seated party, or else a pending or confirmed booking whose slot covers
+// the present moment.
+func (pg *PostgresTableStore) Floor(restaurantID string) ([]FloorTable, error) {
+	tables, err := pg.ListByRestaurant(restaurantID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	q := bookingSelect + `
+	WHERE t.restaurant_id = $1
+		AND (b.status = 'seated'
+			OR (b.status IN ('pending', 'confirmed')
+				AND CURRENT_TIMESTAMP <@ tstzrange(b.booking_time, b.ends_at, '[)')))
+	ORDER BY b.status = 'seated' DESC, b.booking_time
+	`
+	rows, err := pg.db.Query(q, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	current := map[string]*Booking{}
+	for rows.Next() {
+		b := &Booking{}
+		if err := scanBooking(rows, b); err != nil {
+			return nil, err
+		}
+		if _, ok := current[b.TableID]; !ok {
+			current[b.TableID] = b
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	floor := make([]FloorTable, 0, len(tables))
+	for _, t := range tables {
+		ft := FloorTable{Table: t, CurrentBooking: current[t.ID]}
+		if b := ft.CurrentBooking; b != nil && b.Status == BookingStatusSeated {
+			ft.SeatedAt = b.StatusChangedAt
+		}
+		floor = append(floor, ft)
+	}
+
+	return floor, nil
+}
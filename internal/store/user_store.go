@@ -1,11 +1,21 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const bcryptCost = 12
+
+var (
+	ErrDuplicateEmail    = conflict("email already registered")
+	ErrDuplicateUsername = conflict("username already taken")
 )
 
 type PostgresUserStore struct {
@@ -21,26 +31,64 @@ func NewPostgresUserStore(db *sql.DB) *PostgresUserStore {
 type User struct {
 	ID           string    `json:"id"`
 	Email        string    `json:"email"`
+	Username     string    `json:"username"`
+	Phone        string    `json:"phone"`
+	Bio          string    `json:"bio"`
 	Role         string    `json:"role"`
-	PasswordHash string    `json:"password_hash"`
+	PasswordHash string    `json:"-"`
 	IsActive     bool      `json:"is_active"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// SetPassword hashes the plain text password and stores the hash on the user.
+func (u *User) SetPassword(plainText string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plainText), bcryptCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// PasswordMatches reports whether the plain text password matches the stored hash.
+func (u *User) PasswordMatches(plainText string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(plainText))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 type UserStore interface {
-	Create(*User) error
-	GetById(string) (*User, error)
+	Create(context.Context, *User) error
+	GetById(context.Context, string) (*User, error)
+	GetByEmail(context.Context, string) (*User, error)
+	UpdateRole(ctx context.Context, id, role string) error
+	HasRole(ctx context.Context, role string) (bool, error)
 }
 
-func (pg *PostgresUserStore) Create(user *User) error {
+func (pg *PostgresUserStore) Create(ctx context.Context, user *User) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	q := `
-	INSERT INTO users (email, role, password_hash, is_active)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO users (email, username, phone, bio, role, password_hash, is_active)
+	VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)
 	RETURNING id, email, created_at, updated_at
 	`
 	err := pg.db.
-		QueryRow(q, user.Email, user.Role, user.PasswordHash, user.IsActive).
+		QueryRowContext(ctx, q,
+			user.Email,
+			user.Username,
+			user.Phone,
+			user.Bio,
+			user.Role,
+			user.PasswordHash,
+			user.IsActive).
 		Scan(
 			&user.ID,
 			&user.Email,
@@ -48,26 +96,42 @@ func (pg *PostgresUserStore) Create(user *User) error {
 			&user.UpdatedAt)
 
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			switch pgErr.ConstraintName {
+			case "users_email_key":
+				return ErrDuplicateEmail
+			case "users_username_key":
+				return ErrDuplicateUsername
+			}
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (pg *PostgresUserStore) GetById(id string) (*User, error) {
+func (pg *PostgresUserStore) GetById(ctx context.Context, id string) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	_, err := uuid.Parse(id)
 	if err != nil {
-		return nil, errors.New("invalid id format")
+		return nil, ErrInvalidID
 	}
 	q := `
-	SELECT id, email, role, password_hash, is_active, created_at, updated_at
+	SELECT id, email, COALESCE(username, ''), phone, bio, role, password_hash,
+			is_active, created_at, updated_at
 	FROM users
 	WHERE id = $1
 	`
 	usr := &User{}
-	err = pg.db.QueryRow(q, id).Scan(
+	err = pg.db.QueryRowContext(ctx, q, id).Scan(
 		&usr.ID,
 		&usr.Email,
+		&usr.Username,
+		&usr.Phone,
+		&usr.Bio,
 		&usr.Role,
 		&usr.PasswordHash,
 		&usr.IsActive,
@@ -75,7 +139,7 @@ func (pg *PostgresUserStore) GetById(id string) (*User, error) {
 		&usr.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, notFound("user")
 	}
 
 	if err != nil {
@@ -84,3 +148,81 @@ func (pg *PostgresUserStore) GetById(id string) (*User, error) {
 
 	return usr, nil
 }
+
+func (pg *PostgresUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	q := `
+	SELECT id, email, COALESCE(username, ''), phone, bio, role, password_hash,
+			is_active, created_at, updated_at
+	FROM users
+	WHERE email = $1
+	`
+	usr := &User{}
+	err := pg.db.QueryRowContext(ctx, q, email).Scan(
+		&usr.ID,
+		&usr.Email,
+		&usr.Username,
+		&usr.Phone,
+		&usr.Bio,
+		&usr.Role,
+		&usr.PasswordHash,
+		&usr.IsActive,
+		&usr.CreatedAt,
+		&usr.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("user")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return usr, nil
+}
+
+func (pg *PostgresUserStore) UpdateRole(ctx context.Context, id, role string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	q := `
+	UPDATE users
+	SET role = $1
+	WHERE id = $2
+	`
+	result, err := pg.db.ExecContext(ctx, q, role, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("user")
+	}
+
+	return nil
+}
+
+// HasRole reports whether any user holds the role.
+func (pg *PostgresUserStore) HasRole(ctx context.Context, role string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := pg.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE role = $1)`, role).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
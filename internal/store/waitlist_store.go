@@ -0,0 +1,477 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	WaitlistStatusWaiting = "waiting"
+	WaitlistStatusSeated  = "seated"
+	WaitlistStatusRemoved = "removed"
+)
+
+const (
+	// defaultTurnTime is assumed until the restaurant has completed bookings
+	// to average over.
+	defaultTurnTime = 90 * time.Minute
+	// cleaningTime is how long a table in cleaning is expected to stay so.
+	cleaningTime = 5 * time.Minute
+)
+
+type PostgresWaitlistStore struct {
+	db *sql.DB
+}
+
+func NewPostgresWaitlistStore(db *sql.DB) *PostgresWaitlistStore {
+	return &PostgresWaitlistStore{
+		db: db,
+	}
+}
+
+type WaitlistEntry struct {
+	ID                   string     `json:"id"`
+	RestaurantID         string     `json:"restaurant_id"`
+	CustomerID           *string    `json:"customer_id"`
+	CustomerName         string     `json:"customer_name"`
+	Phone                string     `json:"phone"`
+	PartySize            int        `json:"party_size"`
+	Position             int        `json:"position"`
+	Status               string     `json:"status"`
+	BookingID            *string    `json:"booking_id"`
+	EstimatedWaitMinutes *int       `json:"estimated_wait_minutes"`
+	SeatedAt             *time.Time `json:"seated_at"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+// SeatWaitlistParams describes the booking created when a waiting party is
+// seated.
+type SeatWaitlistParams struct {
+	RestaurantID    string
+	EntryID         string
+	TableID         string
+	DurationMinutes int
+	ChangedBy       string
+}
+
+type WaitlistStore interface {
+	Create(*WaitlistEntry) error
+	List(restaurantID string) ([]WaitlistEntry, error)
+	GetById(restaurantID, id string) (*WaitlistEntry, error)
+	Move(restaurantID, id string, position int) error
+	Remove(restaurantID, id string) error
+	Seat(context.Context, SeatWaitlistParams) (*WaitlistEntry, *Booking, *Table, error)
+}
+
+const waitlistSelect = `
+	SELECT id, restaurant_id, customer_id, customer_name, phone, party_size, position, status,
+			booking_id, seated_at, created_at, updated_at
+	FROM waitlist_entries
+	`
+
+func scanWaitlistEntry(row interface{ Scan(...any) error }, e *WaitlistEntry) error {
+	return row.Scan(
+		&e.ID,
+		&e.RestaurantID,
+		&e.CustomerID,
+		&e.CustomerName,
+		&e.Phone,
+		&e.PartySize,
+		&e.Position,
+		&e.Status,
+		&e.BookingID,
+		&e.SeatedAt,
+		&e.CreatedAt,
+		&e.UpdatedAt)
+}
+
+// lockQueue serialises changes to the waitlist of one restaurant.
+func lockQueue(tx *sql.Tx, restaurantID string) error {
+	var id string
+	err := tx.QueryRow(`SELECT id FROM restaurants WHERE id = $1 FOR UPDATE`, restaurantID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return notFound("restaurant")
+	}
+	return err
+}
+
+// Create appends a party to the end of the queue. It returns ErrNotFound
+// when the restaurant does not exist.
+func (pg *PostgresWaitlistStore) Create(e *WaitlistEntry) error {
+	_, err := uuid.Parse(e.RestaurantID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockQueue(tx, e.RestaurantID)
+	if err != nil {
+		return err
+	}
+
+	e.CustomerID, err = resolveCustomer(tx, e.RestaurantID, e.CustomerName, e.Phone, "")
+	if err != nil {
+		return err
+	}
+
+	q := `
+	INSERT INTO waitlist_entries (restaurant_id, customer_id, customer_name, phone, party_size, position)
+	SELECT $1, $2, $3, $4, $5, COALESCE(MAX(position), 0) + 1
+	FROM waitlist_entries
+	WHERE restaurant_id = $1 AND status = 'waiting'
+	RETURNING id
+	`
+	err = tx.QueryRow(q, e.RestaurantID, e.CustomerID, e.CustomerName, e.Phone, e.PartySize).Scan(&e.ID)
+	if err != nil {
+		return err
+	}
+
+	err = scanWaitlistEntry(tx.QueryRow(waitlistSelect+`WHERE id = $1`, e.ID), e)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// List returns the waiting parties in queue order, numbered from 1, with an
+// estimated wait for each.
+func (pg *PostgresWaitlistStore) List(restaurantID string) ([]WaitlistEntry, error) {
+	_, err := uuid.Parse(restaurantID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	rows, err := pg.db.Query(waitlistSelect+`
+	WHERE restaurant_id = $1 AND status = 'waiting'
+	ORDER BY position, created_at
+	`, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []WaitlistEntry{}
+	for rows.Next() {
+		var e WaitlistEntry
+		if err := scanWaitlistEntry(rows, &e); err != nil {
+			return nil, err
+		}
+		e.Position = len(list) + 1
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	floor, err := (&PostgresTableStore{db: pg.db}).Floor(restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	turnTime, err := pg.averageTurnTime(restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	EstimateWaits(list, floor, turnTime, time.Now())
+
+	return list, nil
+}
+
+// averageTurnTime is how long seated parties stayed on average over the last
+// 30 days, measured from the seated to the completed status change.
+func (pg *PostgresWaitlistStore) averageTurnTime(restaurantID string) (time.Duration, error) {
+	q := `
+	SELECT COALESCE(EXTRACT(EPOCH FROM AVG(c.changed_at - s.changed_at)), 0)
+	FROM booking_status_history c
+	JOIN booking_status_history s ON s.booking_id = c.booking_id AND s.to_status = 'seated'
+	JOIN bookings b ON b.id = c.booking_id
+	JOIN tables t ON t.id = b.table_id
+	WHERE c.to_status = 'completed'
+		AND t.restaurant_id = $1
+		AND c.changed_at > CURRENT_TIMESTAMP - INTERVAL '30 days'
+	`
+	var seconds float64
+	err := pg.db.QueryRow(q, restaurantID).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	if seconds <= 0 {
+		return defaultTurnTime, nil
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// EstimateWaits fills EstimatedWaitMinutes for entries given in queue order.
+// Each table is expected to free up after its current party has stayed for
+// turnTime; the queue is then played forward, giving every party the suitable
+// table that frees up first. Later reservations are not taken into account.
+// Entries no table can seat get no estimate.
+func EstimateWaits(entries []WaitlistEntry, floor []FloorTable, turnTime time.Duration, now time.Time) {
+	freeAt := make([]time.Time, len(floor))
+	for i, t := range floor {
+		at := now
+		switch t.Status {
+		case TableStatusOccupied:
+			at = t.StatusChangedAt.Add(turnTime)
+		case TableStatusCleaning:
+			at = t.StatusChangedAt.Add(cleaningTime)
+		default:
+			if b := t.CurrentBooking; b != nil {
+				at = b.EndsAt
+			}
+		}
+		if at.Before(now) {
+			at = now
+		}
+		freeAt[i] = at
+	}
+
+	for i := range entries {
+		entries[i].EstimatedWaitMinutes = nil
+
+		best := -1
+		for j, t := range floor {
+			if t.Status == TableStatusOutOfService || !t.Fits(entries[i].PartySize) {
+				continue
+			}
+			if best == -1 || freeAt[j].Before(freeAt[best]) {
+				best = j
+			}
+		}
+		if best == -1 {
+			continue
+		}
+
+		wait := int(freeAt[best].Sub(now).Round(time.Minute) / time.Minute)
+		entries[i].EstimatedWaitMinutes = &wait
+		freeAt[best] = freeAt[best].Add(turnTime)
+	}
+}
+
+func (pg *PostgresWaitlistStore) GetById(restaurantID, id string) (*WaitlistEntry, error) {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	e := &WaitlistEntry{}
+	err = scanWaitlistEntry(pg.db.QueryRow(waitlistSelect+`WHERE id = $1 AND restaurant_id = $2`, id, restaurantID), e)
+
+	if err == sql.ErrNoRows {
+		return nil, notFound("waitlist entry")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Move puts a waiting party at the given 1-based position, shifting the
+// others. Positions past the end move the party to the back.
+func (pg *PostgresWaitlistStore) Move(restaurantID, id string, position int) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockQueue(tx, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+	SELECT id FROM waitlist_entries
+	WHERE restaurant_id = $1 AND status = 'waiting'
+	ORDER BY position, created_at
+	`, restaurantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var queue []string
+	found := false
+	for rows.Next() {
+		var entryID string
+		if err := rows.Scan(&entryID); err != nil {
+			return err
+		}
+		if entryID == id {
+			found = true
+			continue
+		}
+		queue = append(queue, entryID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !found {
+		return notFound("waitlist entry")
+	}
+
+	idx := min(max(position-1, 0), len(queue))
+	queue = append(queue[:idx], append([]string{id}, queue[idx:]...)...)
+
+	_, err = tx.Exec(`
+	UPDATE waitlist_entries w
+	SET position = v.pos
+	FROM unnest($1::uuid[]) WITH ORDINALITY AS v(id, pos)
+	WHERE w.id = v.id
+	`, pq.Array(queue))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresWaitlistStore) Remove(restaurantID, id string) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result, err := pg.db.Exec(`
+	UPDATE waitlist_entries SET status = 'removed'
+	WHERE id = $1 AND restaurant_id = $2 AND status = 'waiting'
+	`, id, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("waitlist entry")
+	}
+
+	return nil
+}
+
+// Seat turns a waiting party into a booking that starts now and is already
+// seated, and marks the table occupied, all in one transaction. The usual
+// booking rules apply: the party must fit the table, the restaurant must be
+// open, and the table must be free for the whole duration.
+func (pg *PostgresWaitlistStore) Seat(ctx context.Context, params SeatWaitlistParams) (*WaitlistEntry, *Booking, *Table, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := uuid.Parse(params.EntryID)
+	if err != nil {
+		return nil, nil, nil, ErrInvalidID
+	}
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer tx.Rollback()
+
+	e := &WaitlistEntry{}
+	err = scanWaitlistEntry(tx.QueryRow(waitlistSelect+`
+	WHERE id = $1 AND restaurant_id = $2 AND status = 'waiting'
+	FOR UPDATE
+	`, params.EntryID, params.RestaurantID), e)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil, notFound("waitlist entry")
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	scope := Scope{RestaurantIDs: []string{params.RestaurantID}}
+	t, err := lockTable(ctx, tx, params.TableID, e.PartySize, scope)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	b := &Booking{
+		TableID:         t.ID,
+		CustomerID:      e.CustomerID,
+		CustomerName:    e.CustomerName,
+		BookingTime:     time.Now().Truncate(time.Second),
+		PartySize:       e.PartySize,
+		DurationMinutes: params.DurationMinutes,
+		Status:          BookingStatusSeated,
+		StatusChangedBy: &params.ChangedBy,
+	}
+
+	err = checkOpeningHours(ctx, tx, t.RestaurantID, b)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = tx.QueryRow(`
+	INSERT INTO bookings (table_id, customer_id, customer_name, booking_time, party_size,
+		duration_minutes, status, status_changed_at, status_changed_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, $8)
+	RETURNING id
+	`, b.TableID, b.CustomerID, b.CustomerName, b.BookingTime, b.PartySize,
+		b.DurationMinutes, b.Status, b.StatusChangedBy).
+		Scan(&b.ID)
+	if err != nil {
+		return nil, nil, nil, mapBookingError(err)
+	}
+
+	err = recordStatusChange(ctx, tx, b.ID, nil, b.Status, b.StatusChangedBy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	moved, err := moveTable(tx, t.ID, TableStatusOccupied)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !moved {
+		return nil, nil, nil, ErrIllegalTableTransition
+	}
+
+	err = scanWaitlistEntry(tx.QueryRow(`
+	UPDATE waitlist_entries
+	SET status = 'seated', booking_id = $1, seated_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	RETURNING id, restaurant_id, customer_id, customer_name, phone, party_size, position, status,
+			booking_id, seated_at, created_at, updated_at
+	`, b.ID, e.ID), e)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = scanBooking(tx.QueryRow(bookingSelect+`WHERE b.id = $1`, b.ID), b)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = scanTable(tx.QueryRow(tableSelect+`WHERE id = $1`, t.ID), t)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return e, b, t, tx.Commit()
+}
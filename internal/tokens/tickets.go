@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// StreamTicket lets a browser EventSource, which cannot send an
+// Authorization header, open an event stream. It is passed in the query
+// string, so it is short-lived, single-use and bound to one restaurant.
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type streamGrant struct {
+	userID       string
+	restaurantID string
+	expiresAt    time.Time
+}
+
+// TicketStore keeps issued stream tickets in memory, which is enough since
+// the event bus they give access to is in-process too. Only ticket hashes
+// are kept.
+type TicketStore struct {
+	ttl    time.Duration
+	mu     sync.Mutex
+	grants map[string]streamGrant
+}
+
+func NewTicketStore(ttl time.Duration) *TicketStore {
+	return &TicketStore{
+		ttl:    ttl,
+		grants: make(map[string]streamGrant),
+	}
+}
+
+// Issue creates a ticket for the user to stream the restaurant's events.
+func (s *TicketStore) Issue(userID, restaurantID string) (*StreamTicket, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ticket := &StreamTicket{
+		Ticket:    base64.RawURLEncoding.EncodeToString(randomBytes),
+		ExpiresAt: now.Add(s.ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, g := range s.grants {
+		if !now.Before(g.expiresAt) {
+			delete(s.grants, hash)
+		}
+	}
+	s.grants[string(HashRefresh(ticket.Ticket))] = streamGrant{
+		userID:       userID,
+		restaurantID: restaurantID,
+		expiresAt:    ticket.ExpiresAt,
+	}
+
+	return ticket, nil
+}
+
+// Redeem consumes the ticket and returns the user it was issued to. It
+// returns ErrInvalidToken for unknown or already used tickets and tickets
+// issued for another restaurant, and ErrExpiredToken for expired ones.
+func (s *TicketStore) Redeem(ticket, restaurantID string) (string, error) {
+	hash := string(HashRefresh(ticket))
+
+	s.mu.Lock()
+	g, ok := s.grants[hash]
+	delete(s.grants, hash)
+	s.mu.Unlock()
+
+	switch {
+	case !ok || g.restaurantID != restaurantID:
+		return "", ErrInvalidToken
+	case !time.Now().Before(g.expiresAt):
+		return "", ErrExpiredToken
+	}
+	return g.userID, nil
+}
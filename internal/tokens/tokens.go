@@ -0,0 +1,136 @@
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// jwtHeader is fixed because HS256 is the only algorithm we issue and accept.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type Claims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type AccessToken struct {
+	Token     string    `json:"access_token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RefreshToken is an opaque random token. Only its Hash is ever persisted.
+type RefreshToken struct {
+	Plaintext string    `json:"refresh_token"`
+	Hash      []byte    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Manager signs and verifies HS256 access tokens with a shared secret and
+// issues opaque refresh tokens.
+type Manager struct {
+	secret     []byte
+	ttl        time.Duration
+	refreshTTL time.Duration
+}
+
+func NewManager(secret string, ttl, refreshTTL time.Duration) (*Manager, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("tokens: secret must be at least 32 bytes")
+	}
+	return &Manager{
+		secret:     []byte(secret),
+		ttl:        ttl,
+		refreshTTL: refreshTTL,
+	}, nil
+}
+
+func (m *Manager) Generate(userID, role string) (*AccessToken, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	payload, err := json.Marshal(Claims{
+		Subject:   userID,
+		Role:      role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return &AccessToken{
+		Token:     unsigned + "." + m.sign(unsigned),
+		TokenType: "Bearer",
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (m *Manager) Parse(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	expected := m.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+func (m *Manager) GenerateRefresh() (*RefreshToken, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+
+	plaintext := base64.RawURLEncoding.EncodeToString(randomBytes)
+	return &RefreshToken{
+		Plaintext: plaintext,
+		Hash:      HashRefresh(plaintext),
+		ExpiresAt: time.Now().Add(m.refreshTTL),
+	}, nil
+}
+
+func HashRefresh(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+func (m *Manager) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Machine-readable problem codes. Clients should branch on the code rather
+// than on the status or the human-readable detail.
+//
+// A request the server cannot make sense of answers 400: a body that is not
+// valid JSON (invalid_body), a malformed id in the path (invalid_id), or a
+// missing header or bad query parameter (invalid_request). A body that
+// decodes but breaks a rule answers 422 validation_failed, listing the
+// fields at fault, whether the handler or the store caught it.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeInvalidID        = "invalid_id"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePaymentDeclined  = "payment_declined"
+	CodeUnavailable      = "service_unavailable"
+	CodeInternal         = "internal_error"
+)
+
+// problemTypeBase prefixes the code to form the problem type URI. It is a
+// relative reference, resolved against the API's own address.
+const problemTypeBase = "/problems/"
+
+// FieldError is one field of a request that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object. Extensions are written as
+// extra top-level members, for instance the payment a declined charge
+// created.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Code       string
+	Errors     []FieldError
+	Extensions map[string]any
+}
+
+// NewProblem returns a problem with the given status, code and detail; its
+// type comes from the code and its title from the status.
+func NewProblem(status int, code, detail string) Problem {
+	return Problem{
+		Type:   problemTypeBase + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+7)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	m["code"] = p.Code
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	if len(p.Errors) > 0 {
+		m["errors"] = p.Errors
+	}
+	return json.Marshal(m)
+}
+
+// WriteProblem answers the request with p as application/problem+json. The
+// instance defaults to the request path.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) error {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	body, err := json.MarshalIndent(p, "", " ")
+	if err != nil {
+		return err
+	}
+
+	body = append(body, '\n')
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+	return nil
+}
+
+// WriteError answers the request with a problem that has no field errors or
+// extensions, which covers most failures.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, detail string) error {
+	return WriteProblem(w, r, NewProblem(status, code, detail))
+}
@@ -53,3 +53,11 @@ func GetIdUrlParams(r *http.Request) (string, error) {
 	}
 	return paramId, nil
 }
+
+func GetUrlParams(key string, r *http.Request) (string, error) {
+	param := chi.URLParam(r, key)
+	if param == "" {
+		return "", errors.New("invalid " + key + " params")
+	}
+	return param, nil
+}
@@ -0,0 +1,138 @@
+// Package validate checks request bodies field by field. A Validator
+// collects every failing field instead of stopping at the first one, so a
+// client can fix a whole form in one round trip:
+//
+//	v := validate.New()
+//	v.Required("email", req.Email)
+//	v.Email("email", req.Email)
+//	v.Length("username", req.Username, 3, 50)
+//	return v.Err()
+//
+// Each field reports at most one error, the first rule it failed, so a
+// missing value is not also reported as badly formatted. Format rules
+// (Email, Phone, UUID, OneOf) skip empty strings; pair them with Required
+// when the field is mandatory.
+package validate
+
+import (
+	"fmt"
+	"htrr-apis/internal/utils"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+	// e164Regex is a leading plus, a country code that does not start
+	// with 0 and at most 15 digits in total.
+	e164Regex = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+)
+
+// Errors lists the fields that failed validation, in the order they were
+// checked.
+type Errors []utils.FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator collects field errors. The zero value is not usable; call New.
+type Validator struct {
+	errors Errors
+	failed map[string]bool
+}
+
+func New() *Validator {
+	return &Validator{failed: make(map[string]bool)}
+}
+
+// Add records msg against field unless the field already failed.
+func (v *Validator) Add(field, msg string) {
+	if v.failed[field] {
+		return
+	}
+	v.failed[field] = true
+	v.errors = append(v.errors, utils.FieldError{Field: field, Message: msg})
+}
+
+// Check records msg against field when ok is false. It covers rules with no
+// helper of their own.
+func (v *Validator) Check(ok bool, field, msg string) {
+	if !ok {
+		v.Add(field, msg)
+	}
+}
+
+// Valid reports whether no rule has failed so far.
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Err returns the collected errors as Errors, or nil when every rule passed.
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return v.errors
+}
+
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, field+" is required")
+}
+
+// Length checks that value has between min and max characters. A max of 0
+// means no upper bound.
+func (v *Validator) Length(field, value string, min, max int) {
+	n := utf8.RuneCountInString(value)
+	switch {
+	case n < min:
+		v.Add(field, fmt.Sprintf("%s must be at least %d characters long", field, min))
+	case max > 0 && n > max:
+		v.Add(field, fmt.Sprintf("%s must not be more than %d characters", field, max))
+	}
+}
+
+func (v *Validator) Email(field, value string) {
+	v.Check(value == "" || emailRegex.MatchString(value), field, field+" is not valid")
+}
+
+// Phone checks that value is an E.164 number such as +14155552671.
+func (v *Validator) Phone(field, value string) {
+	v.Check(value == "" || e164Regex.MatchString(value), field,
+		field+" must be an E.164 phone number, e.g. +14155552671")
+}
+
+func (v *Validator) UUID(field, value string) {
+	if value == "" {
+		return
+	}
+	_, err := uuid.Parse(value)
+	v.Check(err == nil, field, field+" must be a valid UUID")
+}
+
+// OneOf checks that value is one of allowed.
+func (v *Validator) OneOf(field, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Add(field, fmt.Sprintf("%s must be one of %s", field, strings.Join(allowed, ", ")))
+}
+
+// Range checks that min <= value <= max.
+func (v *Validator) Range(field string, value, min, max int) {
+	v.Check(value >= min && value <= max, field,
+		fmt.Sprintf("%s must be between %d and %d", field, min, max))
+}
@@ -0,0 +1,383 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"htrr-apis/internal/utils"
+	"slices"
+	"testing"
+)
+
+// check runs rule on a new Validator and returns the message it recorded
+// for field, or "" when the rule passed.
+func check(t *testing.T, field string, rule func(v *Validator)) string {
+	t.Helper()
+
+	v := New()
+	rule(v)
+
+	err := v.Err()
+	if err == nil {
+		if !v.Valid() {
+			t.Fatalf("Err() is nil but Valid() is false")
+		}
+		return ""
+	}
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Err() = %T, want Errors", err)
+	}
+	if len(errs) != 1 || errs[0].Field != field {
+		t.Fatalf("errors = %+v, want one for %s", errs, field)
+	}
+	return errs[0].Message
+}
+
+func TestRequired(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "a"},
+		{value: " a "},
+		{value: "", want: "name is required"},
+		{value: "   ", want: "name is required"},
+		{value: "\t\n", want: "name is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.value), func(t *testing.T) {
+			got := check(t, "name", func(v *Validator) { v.Required("name", tt.value) })
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		min, max int
+		want     string
+	}{
+		{name: "within", value: "abcd", min: 3, max: 5},
+		{name: "at min", value: "abc", min: 3, max: 5},
+		{name: "at max", value: "abcde", min: 3, max: 5},
+		{name: "below min", value: "ab", min: 3, max: 5, want: "username must be at least 3 characters long"},
+		{name: "above max", value: "abcdef", min: 3, max: 5, want: "username must not be more than 5 characters"},
+		{name: "empty with no min", value: "", min: 0, max: 5},
+		{name: "no upper bound", value: string(make([]byte, 10000)), min: 3, max: 0},
+		{name: "counts characters not bytes", value: "ééééé", min: 3, max: 5},
+		{name: "multibyte above max", value: "éééééé", min: 3, max: 5, want: "username must not be more than 5 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := check(t, "username", func(v *Validator) { v.Length("username", tt.value, tt.min, tt.max) })
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmail(t *testing.T) {
+	const invalid = "email is not valid"
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: ""},
+		{value: "a@example.com"},
+		{value: "first.last+tag@mail.example.co.uk"},
+		{value: "user_name%x@sub-domain.example.io"},
+		{value: "plain", want: invalid},
+		{value: "@example.com", want: invalid},
+		{value: "a@", want: invalid},
+		{value: "a@example", want: invalid},
+		{value: "a@example.c", want: invalid},
+		{value: "a b@example.com", want: invalid},
+		{value: "a@@example.com", want: invalid},
+		{value: " a@example.com", want: invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := check(t, "email", func(v *Validator) { v.Email("email", tt.value) })
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhone(t *testing.T) {
+	const invalid = "phone must be an E.164 phone number, e.g. +14155552671"
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: ""},
+		{value: "+14155552671"},
+		{value: "+442071838750"},
+		{value: "+12"},
+		{value: "+123456789012345"},
+		{value: "+1234567890123456", want: invalid},
+		{value: "+1", want: invalid},
+		{value: "14155552671", want: invalid},
+		{value: "+04155552671", want: invalid},
+		{value: "+1 415 555 2671", want: invalid},
+		{value: "+1-415-555-2671", want: invalid},
+		{value: "+1415555267a", want: invalid},
+		{value: "++14155552671", want: invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := check(t, "phone", func(v *Validator) { v.Phone("phone", tt.value) })
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUUID(t *testing.T) {
+	const invalid = "id must be a valid UUID"
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: ""},
+		{value: "6f1c1e9a-4b8e-4c1e-9d1a-2b3c4d5e6f70"},
+		{value: "6F1C1E9A-4B8E-4C1E-9D1A-2B3C4D5E6F70"},
+		{value: "6f1c1e9a-4b8e-4c1e-9d1a-2b3c4d5e6f7", want: invalid},
+		{value: "6f1c1e9a-4b8e-4c1e-9d1a-2b3c4d5e6f7g", want: invalid},
+		{value: "not-a-uuid", want: invalid},
+		{value: "1", want: invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := check(t, "id", func(v *Validator) { v.UUID("id", tt.value) })
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUUIDList checks a list the way handlers do, one field per element,
+// so every bad element is reported by its index.
+func TestUUIDList(t *testing.T) {
+	const id = "6f1c1e9a-4b8e-4c1e-9d1a-2b3c4d5e6f70"
+
+	tests := []struct {
+		name string
+		ids  []string
+		want Errors
+	}{
+		{name: "all valid", ids: []string{id, id}},
+		{
+			name: "empty list",
+			ids:  []string{},
+			want: Errors{{Field: "ids", Message: "ids is required"}},
+		},
+		{
+			name: "bad elements",
+			ids:  []string{id, "x", "", id, "y"},
+			want: Errors{
+				{Field: "ids[1]", Message: "ids[1] must be a valid UUID"},
+				{Field: "ids[2]", Message: "ids[2] is required"},
+				{Field: "ids[4]", Message: "ids[4] must be a valid UUID"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.Check(len(tt.ids) > 0, "ids", "ids is required")
+			for i, id := range tt.ids {
+				field := fmt.Sprintf("ids[%d]", i)
+				v.Required(field, id)
+				v.UUID(field, id)
+			}
+
+			var got Errors
+			if err := v.Err(); err != nil {
+				got = err.(Errors)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("errors = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOneOf(t *testing.T) {
+	const invalid = "strategy must be one of atomic, partial, best_effort"
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: ""},
+		{value: "atomic"},
+		{value: "best_effort"},
+		{value: "Atomic", want: invalid},
+		{value: "atomic ", want: invalid},
+		{value: "other", want: invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := check(t, "strategy", func(v *Validator) {
+				v.OneOf("strategy", tt.value, "atomic", "partial", "best_effort")
+			})
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	const invalid = "party_size must be between 1 and 20"
+
+	tests := []struct {
+		value int
+		want  string
+	}{
+		{value: 1},
+		{value: 10},
+		{value: 20},
+		{value: 0, want: invalid},
+		{value: 21, want: invalid},
+		{value: -1, want: invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.value), func(t *testing.T) {
+			got := check(t, "party_size", func(v *Validator) { v.Range("party_size", tt.value, 1, 20) })
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules func(v *Validator)
+		want  Errors
+	}{
+		{
+			name: "valid",
+			rules: func(v *Validator) {
+				v.Required("email", "a@example.com")
+				v.Email("email", "a@example.com")
+				v.Length("username", "abc", 3, 50)
+			},
+		},
+		{
+			name: "every failing field in the order checked",
+			rules: func(v *Validator) {
+				v.Length("username", "ab", 3, 50)
+				v.Email("email", "nope")
+				v.Phone("phone", "123")
+			},
+			want: Errors{
+				{Field: "username", Message: "username must be at least 3 characters long"},
+				{Field: "email", Message: "email is not valid"},
+				{Field: "phone", Message: "phone must be an E.164 phone number, e.g. +14155552671"},
+			},
+		},
+		{
+			name: "first failure per field",
+			rules: func(v *Validator) {
+				v.Required("username", "")
+				v.Length("username", "", 3, 50)
+				v.Email("email", "nope")
+				v.Length("email", "nope", 5, 0)
+			},
+			want: Errors{
+				{Field: "username", Message: "username is required"},
+				{Field: "email", Message: "email is not valid"},
+			},
+		},
+		{
+			name: "passing rule does not hide a later failure",
+			rules: func(v *Validator) {
+				v.Required("username", "ab")
+				v.Length("username", "ab", 3, 50)
+			},
+			want: Errors{
+				{Field: "username", Message: "username must be at least 3 characters long"},
+			},
+		},
+		{
+			name: "Check and Add",
+			rules: func(v *Validator) {
+				v.Check(true, "a", "a is wrong")
+				v.Check(false, "b", "b is wrong")
+				v.Add("c", "c is wrong")
+				v.Add("b", "b is wrong again")
+			},
+			want: Errors{
+				{Field: "b", Message: "b is wrong"},
+				{Field: "c", Message: "c is wrong"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			tt.rules(v)
+
+			if v.Valid() != (tt.want == nil) {
+				t.Errorf("Valid() = %v, want %v", v.Valid(), tt.want == nil)
+			}
+
+			err := v.Err()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Err() = %v, want nil", err)
+				}
+				return
+			}
+
+			var got Errors
+			if !errors.As(err, &got) {
+				t.Fatalf("Err() = %T, want Errors", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("errors = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorsError(t *testing.T) {
+	errs := Errors{
+		{Field: "username", Message: "username is required"},
+		{Field: "email", Message: "email is not valid"},
+	}
+
+	want := "username is required; email is not valid"
+	if got := errs.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	if got := (Errors{utils.FieldError{Field: "a", Message: "a is wrong"}}).Error(); got != "a is wrong" {
+		t.Errorf("Error() = %q, want %q", got, "a is wrong")
+	}
+}
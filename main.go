@@ -7,6 +7,7 @@ import (
 	"htrr-apis/internal/routes"
 	"net/http"
 	"time"
+	_ "time/tzdata" // restaurant timezones must resolve without system tzdata
 )
 
 func main() {
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE users SET role = 'STAFF' WHERE role NOT IN ('OWNER', 'MANAGER', 'STAFF', 'HOST');
+
+ALTER TABLE users
+    ADD CONSTRAINT chk_users_role CHECK (role IN ('OWNER', 'MANAGER', 'STAFF', 'HOST'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_role;
+-- +goose StatementEnd
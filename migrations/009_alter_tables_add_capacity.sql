@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tables
+    ADD COLUMN IF NOT EXISTS capacity INTEGER NOT NULL DEFAULT 2,
+    ADD COLUMN IF NOT EXISTS min_party_size INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS max_party_size INTEGER NOT NULL DEFAULT 2;
+
+ALTER TABLE tables
+    ADD CONSTRAINT chk_tables_party_size
+        CHECK (capacity > 0 AND min_party_size > 0 AND min_party_size <= max_party_size),
+    ADD CONSTRAINT uq_tables_restaurant_table_number UNIQUE (restaurant_id, table_number);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tables
+    DROP CONSTRAINT IF EXISTS uq_tables_restaurant_table_number,
+    DROP CONSTRAINT IF EXISTS chk_tables_party_size,
+    DROP COLUMN IF EXISTS max_party_size,
+    DROP COLUMN IF EXISTS min_party_size,
+    DROP COLUMN IF EXISTS capacity;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS party_size INTEGER NOT NULL DEFAULT 2,
+    ADD COLUMN IF NOT EXISTS duration_minutes INTEGER NOT NULL DEFAULT 90,
+    ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending';
+
+-- ends_at is derived so the exclusion constraint below can index the range
+CREATE OR REPLACE FUNCTION set_booking_ends_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.ends_at = NEW.booking_time + make_interval(mins => NEW.duration_minutes);
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER tr_bookings_ends_at
+    BEFORE INSERT OR UPDATE OF booking_time, duration_minutes ON bookings
+    FOR EACH ROW EXECUTE PROCEDURE set_booking_ends_at();
+
+UPDATE bookings SET ends_at = booking_time + make_interval(mins => duration_minutes);
+
+ALTER TABLE bookings
+    ALTER COLUMN ends_at SET NOT NULL,
+    ADD CONSTRAINT chk_bookings_party_size CHECK (party_size > 0),
+    ADD CONSTRAINT chk_bookings_duration CHECK (duration_minutes > 0),
+    ADD CONSTRAINT chk_bookings_status
+        CHECK (status IN ('pending', 'confirmed', 'seated', 'completed', 'cancelled', 'no_show')),
+    ADD CONSTRAINT ex_bookings_table_overlap EXCLUDE USING gist (
+        table_id WITH =,
+        tstzrange(booking_time, ends_at, '[)') WITH &&
+    ) WHERE (status IN ('pending', 'confirmed', 'seated'));
+
+CREATE INDEX IF NOT EXISTS idx_bookings_booking_time ON bookings(booking_time);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_bookings_booking_time;
+ALTER TABLE bookings
+    DROP CONSTRAINT IF EXISTS ex_bookings_table_overlap,
+    DROP CONSTRAINT IF EXISTS chk_bookings_status,
+    DROP CONSTRAINT IF EXISTS chk_bookings_duration,
+    DROP CONSTRAINT IF EXISTS chk_bookings_party_size;
+DROP TRIGGER IF EXISTS tr_bookings_ends_at ON bookings;
+DROP FUNCTION IF EXISTS set_booking_ends_at;
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS ends_at,
+    DROP COLUMN IF EXISTS duration_minutes,
+    DROP COLUMN IF EXISTS party_size;
+-- +goose StatementEnd
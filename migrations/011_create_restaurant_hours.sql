@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE restaurants
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- day_of_week follows Go's time.Weekday (0 = Sunday). An interval whose
+-- closes_at is not after opens_at runs past midnight into the next day.
+CREATE TABLE IF NOT EXISTS restaurant_opening_hours (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    day_of_week SMALLINT NOT NULL,
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_opening_hours_day CHECK (day_of_week BETWEEN 0 AND 6),
+    CONSTRAINT chk_opening_hours_span CHECK (opens_at <> closes_at)
+);
+CREATE INDEX IF NOT EXISTS idx_opening_hours_restaurant ON restaurant_opening_hours(restaurant_id);
+
+CREATE TABLE IF NOT EXISTS restaurant_closures (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    closed_on DATE NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_restaurant_closures_date UNIQUE (restaurant_id, closed_on)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS restaurant_closures CASCADE;
+DROP TABLE IF EXISTS restaurant_opening_hours CASCADE;
+ALTER TABLE restaurants DROP COLUMN IF EXISTS timezone;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE tables SET status = 'available'
+WHERE status IS NULL
+    OR status NOT IN ('available', 'reserved', 'occupied', 'cleaning', 'out_of_service');
+
+ALTER TABLE tables
+    ALTER COLUMN status SET NOT NULL,
+    ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD CONSTRAINT chk_tables_status
+        CHECK (status IN ('available', 'reserved', 'occupied', 'cleaning', 'out_of_service'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tables
+    DROP CONSTRAINT IF EXISTS chk_tables_status,
+    DROP COLUMN IF EXISTS status_changed_at,
+    ALTER COLUMN status DROP NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    customer_name VARCHAR(255) NOT NULL,
+    phone VARCHAR(20) NOT NULL DEFAULT '',
+    party_size INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting',
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    seated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_waitlist_party_size CHECK (party_size > 0),
+    CONSTRAINT chk_waitlist_status CHECK (status IN ('waiting', 'seated', 'removed'))
+);
+CREATE TRIGGER tr_waitlist_entries_update BEFORE UPDATE ON waitlist_entries FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_queue ON waitlist_entries(restaurant_id, position)
+    WHERE status = 'waiting';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS waitlist_entries CASCADE;
+-- +goose StatementEnd
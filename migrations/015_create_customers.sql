@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Customers belong to one restaurant, like employees and tables, so that the
+-- restaurant scope of staff also limits which guests they can see.
+CREATE TABLE IF NOT EXISTS customers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    full_name VARCHAR(255) NOT NULL,
+    phone VARCHAR(20),
+    email VARCHAR(255),
+    notes TEXT NOT NULL DEFAULT '',
+    allergies TEXT[] NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TRIGGER tr_customers_update BEFORE UPDATE ON customers FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- phone and email are stored normalised; either one identifies a guest
+CREATE UNIQUE INDEX IF NOT EXISTS uq_customers_restaurant_phone
+    ON customers(restaurant_id, phone) WHERE phone IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_customers_restaurant_email
+    ON customers(restaurant_id, email) WHERE email IS NOT NULL;
+
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS customer_id UUID REFERENCES customers(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_bookings_customer ON bookings(customer_id);
+
+ALTER TABLE waitlist_entries
+    ADD COLUMN IF NOT EXISTS customer_id UUID REFERENCES customers(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE waitlist_entries DROP COLUMN IF EXISTS customer_id;
+DROP INDEX IF EXISTS idx_bookings_customer;
+ALTER TABLE bookings DROP COLUMN IF EXISTS customer_id;
+DROP TABLE IF EXISTS customers CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,90 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every menu row carries restaurant_id, even below the menu itself, so that
+-- lookups and deletes can be limited to one restaurant without joining up the
+-- tree.
+CREATE TABLE IF NOT EXISTS menus (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TRIGGER tr_menus_update BEFORE UPDATE ON menus FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+CREATE INDEX IF NOT EXISTS idx_menus_restaurant ON menus(restaurant_id);
+
+CREATE TABLE IF NOT EXISTS menu_categories (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    menu_id UUID NOT NULL REFERENCES menus(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TRIGGER tr_menu_categories_update BEFORE UPDATE ON menu_categories FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+CREATE INDEX IF NOT EXISTS idx_menu_categories_restaurant ON menu_categories(restaurant_id);
+
+-- price_minor is in the smallest unit of currency, e.g. cents for USD
+CREATE TABLE IF NOT EXISTS menu_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES menu_categories(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    dietary_tags TEXT[] NOT NULL DEFAULT '{}',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_menu_items_price CHECK (price_minor >= 0)
+);
+CREATE TRIGGER tr_menu_items_update BEFORE UPDATE ON menu_items FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+CREATE INDEX IF NOT EXISTS idx_menu_items_restaurant ON menu_items(restaurant_id);
+
+CREATE TABLE IF NOT EXISTS modifier_groups (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    min_selections INTEGER NOT NULL DEFAULT 0,
+    max_selections INTEGER NOT NULL DEFAULT 1,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_modifier_groups_selections CHECK (min_selections >= 0 AND max_selections >= min_selections AND max_selections > 0)
+);
+CREATE TRIGGER tr_modifier_groups_update BEFORE UPDATE ON modifier_groups FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+CREATE INDEX IF NOT EXISTS idx_modifier_groups_restaurant ON modifier_groups(restaurant_id);
+
+-- price_minor of a modifier is added to the item price, in the item's currency
+CREATE TABLE IF NOT EXISTS modifiers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    group_id UUID NOT NULL REFERENCES modifier_groups(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    price_minor BIGINT NOT NULL DEFAULT 0,
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_modifiers_price CHECK (price_minor >= 0)
+);
+CREATE TRIGGER tr_modifiers_update BEFORE UPDATE ON modifiers FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+CREATE INDEX IF NOT EXISTS idx_modifiers_restaurant ON modifiers(restaurant_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS modifiers CASCADE;
+DROP TABLE IF EXISTS modifier_groups CASCADE;
+DROP TABLE IF EXISTS menu_items CASCADE;
+DROP TABLE IF EXISTS menu_categories CASCADE;
+DROP TABLE IF EXISTS menus CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An order is what one table eats and drinks during a sitting. currency is
+-- fixed by the first line added, so every line of an order shares it.
+CREATE TABLE IF NOT EXISTS orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    table_id UUID REFERENCES tables(id) ON DELETE SET NULL,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    currency CHAR(3),
+    notes TEXT NOT NULL DEFAULT '',
+    opened_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    opened_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_orders_status CHECK (status IN ('open', 'closed'))
+);
+CREATE TRIGGER tr_orders_update BEFORE UPDATE ON orders FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_orders_restaurant ON orders(restaurant_id, opened_at);
+-- a table runs at most one open order at a time
+CREATE UNIQUE INDEX IF NOT EXISTS uq_orders_open_table ON orders(table_id) WHERE status = 'open';
+
+-- Lines copy the name and price of the menu item when they are added, so
+-- later menu edits leave past orders and bills untouched. unit_price_minor
+-- already includes the chosen modifiers.
+CREATE TABLE IF NOT EXISTS order_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    menu_item_id UUID REFERENCES menu_items(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    unit_price_minor BIGINT NOT NULL,
+    quantity INTEGER NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    voided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_order_items_price CHECK (unit_price_minor >= 0),
+    CONSTRAINT chk_order_items_quantity CHECK (quantity > 0),
+    CONSTRAINT chk_order_items_status CHECK (status IN ('pending', 'sent', 'voided'))
+);
+CREATE TRIGGER tr_order_items_update BEFORE UPDATE ON order_items FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items(order_id);
+
+CREATE TABLE IF NOT EXISTS order_item_modifiers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    modifier_id UUID REFERENCES modifiers(id) ON DELETE SET NULL,
+    group_name VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    price_minor BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_modifiers_item ON order_item_modifiers(order_item_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS order_item_modifiers CASCADE;
+DROP TABLE IF EXISTS order_items CASCADE;
+DROP TABLE IF EXISTS orders CASCADE;
+-- +goose StatementEnd
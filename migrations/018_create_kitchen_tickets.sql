@@ -0,0 +1,68 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Stations are the screens of the kitchen, such as grill, bar and cold. An
+-- item goes to the station routed for it, else to the one routed for its
+-- category, else to the default station.
+CREATE TABLE IF NOT EXISTS kitchen_stations (
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (restaurant_id, code)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_kitchen_stations_default ON kitchen_stations(restaurant_id) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS kitchen_routes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL,
+    station_code VARCHAR(50) NOT NULL,
+    category_id UUID UNIQUE REFERENCES menu_categories(id) ON DELETE CASCADE,
+    menu_item_id UUID UNIQUE REFERENCES menu_items(id) ON DELETE CASCADE,
+    FOREIGN KEY (restaurant_id, station_code) REFERENCES kitchen_stations(restaurant_id, code) ON DELETE CASCADE,
+    CONSTRAINT chk_kitchen_routes_target CHECK ((category_id IS NULL) <> (menu_item_id IS NULL))
+);
+
+-- Tickets keep the station code as text so that changing the routing later
+-- leaves tickets already fired where they are.
+CREATE TABLE IF NOT EXISTS kitchen_tickets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    table_id UUID REFERENCES tables(id) ON DELETE SET NULL,
+    station VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    fired_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    ready_at TIMESTAMP WITH TIME ZONE,
+    served_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_kitchen_tickets_status CHECK (status IN ('queued', 'preparing', 'ready', 'served'))
+);
+CREATE INDEX IF NOT EXISTS idx_kitchen_tickets_queue ON kitchen_tickets(restaurant_id, station, fired_at);
+
+CREATE TABLE IF NOT EXISTS kitchen_ticket_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ticket_id UUID NOT NULL REFERENCES kitchen_tickets(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    modifiers TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    started_at TIMESTAMP WITH TIME ZONE,
+    ready_at TIMESTAMP WITH TIME ZONE,
+    served_at TIMESTAMP WITH TIME ZONE,
+    voided_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_kitchen_ticket_items_status CHECK (status IN ('queued', 'preparing', 'ready', 'served'))
+);
+CREATE INDEX IF NOT EXISTS idx_kitchen_ticket_items_ticket ON kitchen_ticket_items(ticket_id);
+CREATE INDEX IF NOT EXISTS idx_kitchen_ticket_items_order_item ON kitchen_ticket_items(order_item_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS kitchen_ticket_items CASCADE;
+DROP TABLE IF EXISTS kitchen_tickets CASCADE;
+DROP TABLE IF EXISTS kitchen_routes CASCADE;
+DROP TABLE IF EXISTS kitchen_stations CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,71 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rates are basis points: 825 is 8.25%. Prices on the menu exclude tax.
+CREATE TABLE IF NOT EXISTS billing_settings (
+    restaurant_id UUID PRIMARY KEY REFERENCES restaurants(id) ON DELETE CASCADE,
+    tax_rate_bp INTEGER NOT NULL DEFAULT 0,
+    service_charge_bp INTEGER NOT NULL DEFAULT 0,
+    service_charge_taxable BOOLEAN NOT NULL DEFAULT FALSE,
+    rounding_increment BIGINT NOT NULL DEFAULT 1,
+    rounding_mode VARCHAR(20) NOT NULL DEFAULT 'half_up',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_billing_settings_tax CHECK (tax_rate_bp BETWEEN 0 AND 10000),
+    CONSTRAINT chk_billing_settings_service CHECK (service_charge_bp BETWEEN 0 AND 10000),
+    CONSTRAINT chk_billing_settings_rounding CHECK (rounding_increment BETWEEN 1 AND 100),
+    CONSTRAINT chk_billing_settings_rounding_mode CHECK (rounding_mode IN ('half_up', 'up', 'down'))
+);
+CREATE TRIGGER tr_billing_settings_update BEFORE UPDATE ON billing_settings FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS category_tax_rates (
+    category_id UUID PRIMARY KEY REFERENCES menu_categories(id) ON DELETE CASCADE,
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    tax_rate_bp INTEGER NOT NULL,
+    CONSTRAINT chk_category_tax_rates_tax CHECK (tax_rate_bp BETWEEN 0 AND 10000)
+);
+
+-- the seat a line was ordered for, used to split the bill by seat
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS seat INTEGER;
+ALTER TABLE order_items
+    ADD CONSTRAINT chk_order_items_seat CHECK (seat IS NULL OR seat > 0);
+
+-- A finalized bill is stored as it was printed, so later changes to the
+-- billing settings do not alter it. There is at most one per order.
+CREATE TABLE IF NOT EXISTS bills (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    subtotal_minor BIGINT NOT NULL,
+    discount_minor BIGINT NOT NULL,
+    service_charge_minor BIGINT NOT NULL,
+    tax_minor BIGINT NOT NULL,
+    rounding_minor BIGINT NOT NULL,
+    total_minor BIGINT NOT NULL,
+    split_mode VARCHAR(20) NOT NULL,
+    details JSONB NOT NULL,
+    finalized_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    finalized_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_bills_restaurant ON bills(restaurant_id, finalized_at);
+
+CREATE TABLE IF NOT EXISTS bill_shares (
+    id UUID PRIMARY KEY,
+    bill_id UUID NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    total_minor BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_bill_shares_bill ON bill_shares(bill_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS bill_shares CASCADE;
+DROP TABLE IF EXISTS bills CASCADE;
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS chk_order_items_seat;
+ALTER TABLE order_items DROP COLUMN IF EXISTS seat;
+DROP TABLE IF EXISTS category_tax_rates CASCADE;
+DROP TABLE IF EXISTS billing_settings CASCADE;
+-- +goose StatementEnd
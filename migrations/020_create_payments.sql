@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A payment settles all or part of a finalized bill, optionally one share
+-- of it. Amounts are in minor units of the bill's currency; the tip is on
+-- top of amount_minor and does not count toward the bill.
+CREATE TABLE IF NOT EXISTS payments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    restaurant_id UUID NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+    bill_id UUID NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    share_id UUID REFERENCES bill_shares(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    method VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    currency CHAR(3) NOT NULL,
+    amount_minor BIGINT NOT NULL,
+    tip_minor BIGINT NOT NULL DEFAULT 0,
+    refunded_minor BIGINT NOT NULL DEFAULT 0,
+    provider_reference VARCHAR(255),
+    failure_reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    authorized_at TIMESTAMP WITH TIME ZONE,
+    captured_at TIMESTAMP WITH TIME ZONE,
+    voided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_payments_idempotency_key UNIQUE (restaurant_id, idempotency_key),
+    CONSTRAINT chk_payments_method CHECK (method IN ('cash', 'card', 'ewallet')),
+    CONSTRAINT chk_payments_status CHECK (status IN ('pending', 'authorized', 'captured', 'voided', 'failed')),
+    CONSTRAINT chk_payments_amounts CHECK (amount_minor > 0 AND tip_minor >= 0
+        AND refunded_minor BETWEEN 0 AND amount_minor + tip_minor)
+);
+CREATE INDEX IF NOT EXISTS idx_payments_bill ON payments(bill_id);
+CREATE TRIGGER tr_payments_update BEFORE UPDATE ON payments FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    amount_minor BIGINT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    provider_reference VARCHAR(255),
+    failure_reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_payment_refunds_idempotency_key UNIQUE (payment_id, idempotency_key),
+    CONSTRAINT chk_payment_refunds_status CHECK (status IN ('pending', 'succeeded', 'failed')),
+    CONSTRAINT chk_payment_refunds_amount CHECK (amount_minor > 0)
+);
+CREATE TRIGGER tr_payment_refunds_update BEFORE UPDATE ON payment_refunds FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- every call made to the payment provider, successful or not
+CREATE TABLE IF NOT EXISTS payment_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    refund_id UUID REFERENCES payment_refunds(id) ON DELETE CASCADE,
+    operation VARCHAR(20) NOT NULL,
+    amount_minor BIGINT NOT NULL DEFAULT 0,
+    succeeded BOOLEAN NOT NULL,
+    provider_reference VARCHAR(255),
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_payment_attempts_operation CHECK (operation IN ('authorize', 'capture', 'void', 'refund'))
+);
+CREATE INDEX IF NOT EXISTS idx_payment_attempts_payment ON payment_attempts(payment_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS payment_attempts CASCADE;
+DROP TABLE IF EXISTS payment_refunds CASCADE;
+DROP TABLE IF EXISTS payments CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Lines keep the menu category they were ordered under, which picks their
+-- tax rate, so moving or deleting the menu item later does not change the
+-- tax on the order. There is no foreign key for the same reason.
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS category_id UUID;
+
+UPDATE order_items i
+SET category_id = mi.category_id
+FROM menu_items mi
+WHERE mi.id = i.menu_item_id AND i.category_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_items DROP COLUMN IF EXISTS category_id;
+-- +goose StatementEnd